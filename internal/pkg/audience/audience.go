@@ -0,0 +1,186 @@
+// Package audience implements a query language for selecting users by attributes and group membership.
+//
+// Expressions combine "field:value" predicates with AND, OR, NOT and parentheses,
+// where adjacent predicates are implicitly joined with AND. Values are matched
+// case-insensitively and may contain "*" wildcards or be double-quoted to include spaces.
+//
+//	is:member tz:America/*
+//	title:*Manager*
+//	group:oncall AND NOT (group:managers OR is:guest)
+package audience
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+)
+
+// Expr is a parsed audience expression.
+type Expr interface {
+	// Match reports whether user matches expression, using index for group membership.
+	Match(user *slack.Destination, index *Index) bool
+	String() string
+}
+
+// Index provides group membership lookups for a set of destinations.
+type Index struct {
+	users  []*slack.Destination
+	groups map[string]map[string]bool
+}
+
+// NewIndex builds an index from users and user groups in destinations.
+// Groups are keyed by lowercase ID, name and handle.
+func NewIndex(destinations []*slack.Destination) *Index {
+	index := &Index{
+		groups: map[string]map[string]bool{},
+	}
+	for _, dest := range destinations {
+		switch dest.Type {
+		case "user":
+			index.users = append(index.users, dest)
+		case "usergroup":
+			members := map[string]bool{}
+			for _, child := range dest.Children {
+				members[child.ID] = true
+			}
+			for _, key := range []string{dest.ID, dest.Name, dest.DisplayName} {
+				if key != "" {
+					index.groups[strings.ToLower(key)] = members
+				}
+			}
+		}
+	}
+	return index
+}
+
+// Resolve returns users in index matching expression, in directory order.
+func (index *Index) Resolve(expr Expr) []*slack.Destination {
+	matches := []*slack.Destination{}
+	for _, user := range index.users {
+		if expr.Match(user, index) {
+			matches = append(matches, user)
+		}
+	}
+	return matches
+}
+
+// NeedsProfiles reports whether expression matches pronouns or custom profile fields,
+// which destinations only have after [slack.Session.WithProfiles].
+func NeedsProfiles(expr Expr) bool {
+	switch e := expr.(type) {
+	case *andExpr:
+		return NeedsProfiles(e.left) || NeedsProfiles(e.right)
+	case *orExpr:
+		return NeedsProfiles(e.left) || NeedsProfiles(e.right)
+	case *notExpr:
+		return NeedsProfiles(e.expr)
+	case *predicateExpr:
+		return e.field == "pronouns" || strings.HasPrefix(e.field, customFieldPrefix)
+	}
+	return false
+}
+
+// Destinations retrieves session's destinations for resolving expression,
+// with profiles loaded only if expression needs them.
+func Destinations(session slack.Session, expr Expr) ([]*slack.Destination, error) {
+	destinations, err := session.GetDestinations()
+	if err != nil || !NeedsProfiles(expr) {
+		return destinations, err
+	}
+	return session.WithProfiles(destinations)
+}
+
+type andExpr struct {
+	left, right Expr
+}
+
+func (e *andExpr) Match(user *slack.Destination, index *Index) bool {
+	return e.left.Match(user, index) && e.right.Match(user, index)
+}
+
+func (e *andExpr) String() string {
+	return fmt.Sprintf("(%s AND %s)", e.left, e.right)
+}
+
+type orExpr struct {
+	left, right Expr
+}
+
+func (e *orExpr) Match(user *slack.Destination, index *Index) bool {
+	return e.left.Match(user, index) || e.right.Match(user, index)
+}
+
+func (e *orExpr) String() string {
+	return fmt.Sprintf("(%s OR %s)", e.left, e.right)
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e *notExpr) Match(user *slack.Destination, index *Index) bool {
+	return !e.expr.Match(user, index)
+}
+
+func (e *notExpr) String() string {
+	return fmt.Sprintf("NOT %s", e.expr)
+}
+
+// predicateExpr matches a single "field:value" pair.
+type predicateExpr struct {
+	field, value string
+}
+
+func (e *predicateExpr) Match(user *slack.Destination, index *Index) bool {
+	switch e.field {
+	case "everyone":
+		return true
+	case "id":
+		return strings.EqualFold(user.ID, e.value)
+	case "is":
+		return user.HasFlag(e.value)
+	case "group":
+		return index.groups[strings.ToLower(e.value)][user.ID]
+	case "name":
+		return globMatch(e.value, user.Name) || globMatch(e.value, user.DisplayName)
+	}
+	return globMatch(e.value, user.Field(strings.TrimPrefix(e.field, customFieldPrefix)))
+}
+
+func (e *predicateExpr) String() string {
+	if e.field == "everyone" {
+		return e.field
+	}
+	if strings.ContainsAny(e.value, " ()") {
+		return fmt.Sprintf("%s:%q", e.field, e.value)
+	}
+	return e.field + ":" + e.value
+}
+
+// globMatch matches value against pattern case-insensitively, where "*" matches any sequence.
+// Unlike path.Match, "*" also matches "/" so that "America/*" covers nested zones.
+func globMatch(pattern, value string) bool {
+	pattern = strings.ToLower(pattern)
+	value = strings.ToLower(value)
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(value, part)
+		}
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return true
+}
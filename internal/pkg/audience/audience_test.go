@@ -0,0 +1,146 @@
+package audience
+
+import (
+	"testing"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDestinations() []*slack.Destination {
+	jane := &slack.Destination{
+		Type:     "user",
+		ID:       "U1",
+		Name:     "Jane Doe",
+		Title:    "Engineering Manager",
+		Timezone: "America/New_York",
+		Fields:   map[string]string{"department": "Platform"},
+	}
+	john := &slack.Destination{
+		Type:     "user",
+		ID:       "U2",
+		Name:     "John Smith",
+		Title:    "Engineer",
+		Timezone: "America/Argentina/Buenos_Aires",
+		IsGuest:  true,
+	}
+	yuki := &slack.Destination{
+		Type:     "user",
+		ID:       "U3",
+		Name:     "Yuki Tanaka",
+		Title:    "Product Manager",
+		Timezone: "Asia/Tokyo",
+		IsAdmin:  true,
+	}
+	return []*slack.Destination{
+		jane,
+		john,
+		yuki,
+		{
+			Type:        "usergroup",
+			ID:          "S1",
+			Name:        "On-call",
+			DisplayName: "oncall",
+			Children:    []*slack.Destination{jane, john},
+		},
+		{
+			Type:        "usergroup",
+			ID:          "S2",
+			Name:        "Managers",
+			DisplayName: "managers",
+			Children:    []*slack.Destination{jane, yuki},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	index := NewIndex(testDestinations())
+
+	for _, test := range []struct {
+		expr        string
+		expectedIDs []string
+	}{
+		{expr: "everyone", expectedIDs: []string{"U1", "U2", "U3"}},
+		{expr: "is:member tz:America/*", expectedIDs: []string{"U1"}},
+		{expr: "tz:america/*", expectedIDs: []string{"U1", "U2"}},
+		{expr: "title:*manager*", expectedIDs: []string{"U1", "U3"}},
+		{expr: "title:engineer", expectedIDs: []string{"U2"}},
+		{expr: "group:oncall AND NOT group:managers", expectedIDs: []string{"U2"}},
+		{expr: "group:S1 OR group:\"Managers\"", expectedIDs: []string{"U1", "U2", "U3"}},
+		{expr: "NOT (group:oncall OR is:admin)", expectedIDs: []string{}},
+		{expr: "name:\"jane doe\" or id:u3", expectedIDs: []string{"U1", "U3"}},
+		{expr: "field.department:platform", expectedIDs: []string{"U1"}},
+		{expr: "group:unknown", expectedIDs: []string{}},
+	} {
+		expr, err := Parse(test.expr)
+		if !assert.NoError(t, err, "expr: %s", test.expr) {
+			continue
+		}
+		actualIDs := []string{}
+		for _, user := range index.Resolve(expr) {
+			actualIDs = append(actualIDs, user.ID)
+		}
+		assert.Equal(t, test.expectedIDs, actualIDs, "expr: %s", test.expr)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	expr, err := Parse("group:a (is:guest or title:\"Head of Sales\") not tz:Asia/*")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "((group:a AND (is:guest OR title:\"Head of Sales\")) AND NOT tz:Asia/*)", expr.String())
+	}
+}
+
+func TestNeedsProfiles(t *testing.T) {
+	for query, expected := range map[string]bool{
+		"group:a title:*Manager*":                   false,
+		"group:a OR NOT pronouns:they/them":         true,
+		"is:member (tz:Asia/* OR field.team:infra)": true,
+	} {
+		expr, err := Parse(query)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, NeedsProfiles(expr), query)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		expr          string
+		errorContains string
+	}{
+		{expr: "", errorContains: "empty expression"},
+		{expr: "   ", errorContains: "empty expression"},
+		{expr: "manager", errorContains: "expected field:value"},
+		{expr: "colour:blue", errorContains: "unknown field"},
+		{expr: "title:", errorContains: "missing value"},
+		{expr: "title:\"head", errorContains: "unterminated quote"},
+		{expr: "(group:a", errorContains: "missing closing parenthesis"},
+		{expr: "group:a)", errorContains: "unexpected \")\""},
+		{expr: "group:a AND", errorContains: "unexpected end"},
+		{expr: "OR group:a", errorContains: "unexpected operator"},
+		{expr: "NOT", errorContains: "unexpected end"},
+	} {
+		_, err := Parse(test.expr)
+		assert.ErrorContains(t, err, test.errorContains, "expr: %s", test.expr)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{pattern: "abc", value: "ABC", expected: true},
+		{pattern: "abc", value: "abcd", expected: false},
+		{pattern: "a*", value: "a/b/c", expected: true},
+		{pattern: "*c", value: "abc", expected: true},
+		{pattern: "*b*", value: "abc", expected: true},
+		{pattern: "a*b*c", value: "axxbyyc", expected: true},
+		{pattern: "a*b*c", value: "axxcyyb", expected: false},
+		{pattern: "*", value: "", expected: true},
+	} {
+		assert.Equal(t, test.expected, globMatch(test.pattern, test.value), "pattern: %s, value: %s", test.pattern, test.value)
+	}
+}
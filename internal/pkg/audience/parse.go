@@ -0,0 +1,186 @@
+package audience
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// fields lists predicate fields accepted by the parser, besides custom profile fields.
+var fields = map[string]bool{
+	"id":       true,
+	"is":       true,
+	"group":    true,
+	"name":     true,
+	"type":     true,
+	"email":    true,
+	"title":    true,
+	"tz":       true,
+	"locale":   true,
+	"pronouns": true,
+}
+
+// customFieldPrefix qualifies custom profile fields, such as "field.department:Platform".
+const customFieldPrefix = "field."
+
+// Parse parses an audience expression.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary (["AND"] unary)*
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := p.peek()
+		if token == "" || token == ")" || strings.EqualFold(token, "OR") {
+			return left, nil
+		}
+		if strings.EqualFold(token, "AND") {
+			p.next()
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left, right}
+	}
+}
+
+// parseUnary parses: "NOT" unary | "(" or ")" | predicate
+func (p *parser) parseUnary() (Expr, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case strings.EqualFold(token, "NOT"):
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr}, nil
+	case token == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return expr, nil
+	case token == ")":
+		return nil, fmt.Errorf("unexpected %q", token)
+	case strings.EqualFold(token, "AND"), strings.EqualFold(token, "OR"):
+		return nil, fmt.Errorf("unexpected operator %q", token)
+	case strings.EqualFold(token, "everyone"):
+		return &predicateExpr{field: "everyone"}, nil
+	}
+	return parsePredicate(token)
+}
+
+// parsePredicate parses a "field:value" token with optionally quoted value.
+func parsePredicate(token string) (Expr, error) {
+	field, value, found := strings.Cut(token, ":")
+	if !found {
+		return nil, fmt.Errorf("expected field:value, got %q", token)
+	}
+	field = strings.ToLower(field)
+	if !fields[field] && !strings.HasPrefix(field, customFieldPrefix) {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+	if value == "" || field == customFieldPrefix {
+		return nil, fmt.Errorf("missing value in %q", token)
+	}
+	return &predicateExpr{field: field, value: value}, nil
+}
+
+// lex splits s into parentheses and words, keeping double-quoted sections intact.
+func lex(s string) ([]string, error) {
+	tokens := []string{}
+	var b strings.Builder
+	quoted := false
+	flush := func() {
+		if b.Len() > 0 {
+			tokens = append(tokens, b.String())
+			b.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case quoted:
+			b.WriteRune(r)
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	flush()
+	return tokens, nil
+}
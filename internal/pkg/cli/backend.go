@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/audience"
+	"github.com/gouline/blaster/internal/pkg/search"
+	"github.com/gouline/blaster/internal/pkg/slack"
+)
+
+// backend performs commands against either the server API or Slack directly.
+type backend interface {
+	Suggest(term string, limit int) ([]*suggestion, error)
+	Audience(expression string) ([]*suggestion, error)
+	Send(expression, message string, asUser bool) (*sendResult, error)
+}
+
+// apiBackend calls /api/v1 on a running server with a personal access token.
+type apiBackend struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newAPIBackend(baseURL, token string) *apiBackend {
+	return &apiBackend{
+		url:   strings.TrimSuffix(baseURL, "/"),
+		token: token,
+		// Sending to a large audience happens within a single request
+		client: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+func (b *apiBackend) Suggest(term string, limit int) ([]*suggestion, error) {
+	suggestions := []*suggestion{}
+	query := url.Values{"term": {term}, "limit": {strconv.Itoa(limit)}}
+	return suggestions, b.do(http.MethodGet, "/api/v1/suggest?"+query.Encode(), nil, &suggestions)
+}
+
+func (b *apiBackend) Audience(expression string) ([]*suggestion, error) {
+	var response struct {
+		Members []*suggestion `json:"members"`
+	}
+	query := url.Values{"expression": {expression}}
+	return response.Members, b.do(http.MethodGet, "/api/v1/audience?"+query.Encode(), nil, &response)
+}
+
+func (b *apiBackend) Send(expression, message string, asUser bool) (*sendResult, error) {
+	result := &sendResult{}
+	return result, b.do(http.MethodPost, "/api/v1/send", map[string]interface{}{
+		"audience": expression,
+		"message":  message,
+		"as_user":  asUser,
+	}, result)
+}
+
+// do sends request with JSON body, decoding JSON response into v or error envelope into [apiError].
+func (b *apiBackend) do(method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, b.url+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+b.token)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := b.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 {
+		e := &apiError{Status: response.StatusCode}
+		if err := json.NewDecoder(response.Body).Decode(e); err != nil || e.Message == "" {
+			e.Message = http.StatusText(response.StatusCode)
+		}
+		return e
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+// newSession creates Slack session for direct mode, replaced in tests.
+var newSession = func(token string) slack.Session {
+	return &slack.ClientSession{Token: token}
+}
+
+// directBackend uses Slack API with a bot token, bypassing the server.
+type directBackend struct {
+	session slack.Session
+}
+
+func newDirectBackend(token string) *directBackend {
+	return &directBackend{session: newSession(token)}
+}
+
+func (b *directBackend) Suggest(term string, limit int) ([]*suggestion, error) {
+	destinations, err := b.session.GetDestinations()
+	if err != nil {
+		return nil, err
+	}
+
+	type ranked struct {
+		dest  *slack.Destination
+		score search.Score
+	}
+	matches := []ranked{}
+	for _, dest := range destinations {
+		if score := search.Match(term, dest.Name+" "+dest.DisplayName); score != search.ScoreNone && !dest.Disabled {
+			matches = append(matches, ranked{dest, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	suggestions := []*suggestion{}
+	for _, match := range matches[:min(limit, len(matches))] {
+		suggestions = append(suggestions, newSuggestion(match.dest))
+	}
+	return suggestions, nil
+}
+
+func (b *directBackend) Audience(expression string) ([]*suggestion, error) {
+	users, err := b.resolve(expression)
+	if err != nil {
+		return nil, err
+	}
+	members := []*suggestion{}
+	for _, user := range users {
+		members = append(members, newSuggestion(user))
+	}
+	return members, nil
+}
+
+func (b *directBackend) Send(expression, message string, asUser bool) (*sendResult, error) {
+	users, err := b.resolve(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no recipients")
+	}
+
+	result := &sendResult{Sent: []string{}}
+	for _, user := range users {
+		if _, err := b.session.PostMessage(user.ID, slack.Message{Text: message, AsUser: asUser}); err != nil {
+			failure := &sendFailure{User: user.ID, Message: err.Error()}
+			if slackErr := slack.ParseError(err); slackErr != nil {
+				failure.SlackError = slackErr.Code
+			}
+			result.Failed = append(result.Failed, failure)
+			continue
+		}
+		result.Sent = append(result.Sent, user.ID)
+	}
+	return result, nil
+}
+
+// resolve finds users matching audience expression in the workspace directory.
+func (b *directBackend) resolve(expression string) ([]*slack.Destination, error) {
+	expr, err := audience.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audience: %w", err)
+	}
+	destinations, err := audience.Destinations(b.session, expr)
+	if err != nil {
+		return nil, err
+	}
+	return audience.NewIndex(destinations).Resolve(expr), nil
+}
+
+func newSuggestion(dest *slack.Destination) *suggestion {
+	label := dest.Name
+	if dest.DisplayName != "" {
+		label += " (" + dest.DisplayName + ")"
+	}
+	return &suggestion{
+		Type:  dest.Type,
+		Label: label,
+		Value: dest.ID,
+		Email: dest.Email,
+		Title: dest.Title,
+	}
+}
+
+// suggestion matches the server's suggestion response.
+type suggestion struct {
+	Type     string        `json:"type"`
+	Label    string        `json:"label"`
+	Value    string        `json:"value"`
+	Email    string        `json:"email,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Children []*suggestion `json:"children,omitempty"`
+}
+
+// sendResult matches the server's send response, with recipients that would be sent to on dry run.
+type sendResult struct {
+	Blast      string         `json:"blast,omitempty"`
+	DryRun     bool           `json:"dry_run,omitempty"`
+	Recipients []*suggestion  `json:"recipients,omitempty"`
+	Sent       []string       `json:"sent"`
+	Skipped    []*sendSkip    `json:"skipped,omitempty"`
+	Failed     []*sendFailure `json:"failed,omitempty"`
+}
+
+type sendSkip struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
+
+type sendFailure struct {
+	User       string `json:"user"`
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message"`
+	SlackError string `json:"slack_error,omitempty"`
+}
+
+// apiError is the server's error envelope.
+type apiError struct {
+	Status     int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	SlackError string `json:"slack_error"`
+	RequestID  string `json:"request_id"`
+}
+
+func (e *apiError) Error() string {
+	s := fmt.Sprintf("%s (HTTP %d", e.Message, e.Status)
+	if e.SlackError != "" {
+		s += ", " + e.SlackError
+	}
+	if e.RequestID != "" {
+		s += ", request " + e.RequestID
+	}
+	return s + ")"
+}
@@ -0,0 +1,334 @@
+// Package cli implements command-line subcommands for sending blasts from scripts.
+//
+// Commands either call a running server's /api/v1 with a personal access token,
+// or use a Slack bot token directly, in which case server-side exclusions and the delivery policy don't apply.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Exit codes returned by [Run].
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+const usage = `Usage: blaster <command> [flags]
+
+Commands:
+  serve      Start the web server (default)
+  send       Send a message to recipients
+  suggest    Search for recipients
+  help       Show this help
+
+Connection flags, shared by send and suggest:
+  --url        Server URL for API mode ($BLASTER_URL)
+  --token      API token created in the UI ($BLASTER_TOKEN)
+  --bot-token  Slack bot token for direct mode, bypassing the server ($SLACK_BOT_TOKEN)
+
+Run "blaster <command> -h" for command flags.
+`
+
+// Run executes command-line subcommand with arguments, returning exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+
+	var err error
+	switch args[0] {
+	case "send":
+		err = runSend(args[1:], stdin, stdout, stderr)
+	case "suggest":
+		err = runSuggest(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return ExitUsage
+	}
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "%s\n", err)
+		return ExitUsage
+	}
+	fmt.Fprintf(stderr, "error: %s\n", err)
+	return ExitError
+}
+
+// runSend handles "blaster send".
+func runSend(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags, conn := newFlagSet("send", stderr)
+	var to stringList
+	flags.Var(&to, "to", "Recipient as @group-handle, email or Slack ID, repeatable or comma-separated")
+	audience := flags.String("audience", "", "Audience expression, such as \"group:oncall AND is:member\"")
+	message := flags.String("message", "", "Message text")
+	messageFile := flags.String("message-file", "", "File with message text, - for stdin")
+	asUser := flags.Bool("as-user", false, "Send as the token's user instead of the bot, API mode only")
+	dryRun := flags.Bool("dry-run", false, "Resolve recipients without sending")
+	jsonOutput := flags.Bool("json", false, "Print result as JSON")
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	text, err := readMessage(*message, *messageFile, stdin)
+	if err != nil {
+		return err
+	}
+	expression := recipientsExpression(to, *audience)
+	if expression == "" {
+		return &usageError{"missing recipients, use --to or --audience"}
+	}
+
+	b, err := conn.backend(stderr)
+	if err != nil {
+		return err
+	}
+
+	var result *sendResult
+	if *dryRun {
+		members, err := b.Audience(expression)
+		if err != nil {
+			return err
+		}
+		result = &sendResult{DryRun: true, Recipients: members, Sent: []string{}}
+	} else {
+		result, err = b.Send(expression, text, *asUser)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *jsonOutput {
+		return writeJSON(stdout, result)
+	}
+	writeSendResult(stdout, result)
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("failed to send to %d recipients", len(result.Failed))
+	}
+	return nil
+}
+
+// runSuggest handles "blaster suggest".
+func runSuggest(args []string, stdout, stderr io.Writer) error {
+	flags, conn := newFlagSet("suggest", stderr)
+	limit := flags.Int("limit", 10, "Maximum number of suggestions")
+	jsonOutput := flags.Bool("json", false, "Print suggestions as JSON")
+	terms, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(terms) == 0 {
+		return &usageError{"missing search term"}
+	}
+
+	b, err := conn.backend(stderr)
+	if err != nil {
+		return err
+	}
+	suggestions, err := b.Suggest(strings.Join(terms, " "), *limit)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		return writeJSON(stdout, suggestions)
+	}
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, s := range suggestions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Value, s.Type, s.Label)
+	}
+	return w.Flush()
+}
+
+// connection holds flags choosing between API and direct mode.
+type connection struct {
+	url      string
+	token    string
+	botToken string
+
+	// flags tells connection flags set on the command line from ones defaulting to environment
+	flags *flag.FlagSet
+}
+
+// backend creates API or direct Slack backend, whichever mode is configured. Flags set on the command line
+// take precedence over environment, and configuring both modes the same way is a usage error.
+func (c *connection) backend(stderr io.Writer) (backend, error) {
+	explicit := map[string]bool{}
+	c.flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	apiMode, directMode := c.url != "" || c.token != "", c.botToken != ""
+	switch explicitAPI, explicitDirect := explicit["url"] || explicit["token"], explicit["bot-token"]; {
+	case explicitAPI && explicitDirect:
+		return nil, &usageError{"use either --url and --token for API mode, or --bot-token for direct mode"}
+	case explicitAPI:
+		directMode = false
+	case explicitDirect:
+		apiMode = false
+	case apiMode && directMode:
+		return nil, &usageError{"both API mode ($BLASTER_URL, $BLASTER_TOKEN) and direct mode ($SLACK_BOT_TOKEN) are configured, choose one with --url and --token, or --bot-token"}
+	}
+
+	if directMode {
+		fmt.Fprintln(stderr, "warning: sending directly with bot token, exclusions, opt-outs and the delivery policy aren't applied")
+		return newDirectBackend(c.botToken), nil
+	}
+	if c.url == "" || c.token == "" {
+		return nil, &usageError{"missing --url and --token for API mode, or --bot-token for direct mode"}
+	}
+	return newAPIBackend(c.url, c.token), nil
+}
+
+// newFlagSet creates flag set for command with shared connection flags defaulting to environment.
+func newFlagSet(name string, output io.Writer) (*flag.FlagSet, *connection) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	conn := &connection{flags: flags}
+	flags.StringVar(&conn.url, "url", os.Getenv("BLASTER_URL"), "Server URL for API mode")
+	flags.StringVar(&conn.token, "token", os.Getenv("BLASTER_TOKEN"), "API token for API mode")
+	flags.StringVar(&conn.botToken, "bot-token", os.Getenv("SLACK_BOT_TOKEN"), "Slack bot token for direct mode")
+	return flags, conn
+}
+
+// parseFlags parses flags interspersed with positional arguments, which it returns.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{err.Error()}
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// readMessage returns message from flag or file, where "-" reads stdin.
+func readMessage(message, file string, stdin io.Reader) (string, error) {
+	if message != "" && file != "" {
+		return "", &usageError{"use either --message or --message-file"}
+	}
+	if file != "" {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read message: %w", err)
+		}
+		message = string(data)
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", &usageError{"missing message, use --message or --message-file"}
+	}
+	return message, nil
+}
+
+// recipientsExpression converts recipients into an audience expression, combined with audience by OR.
+// Recipients starting with "@" are user group handles, others are emails or Slack IDs.
+func recipientsExpression(to []string, audience string) string {
+	terms := []string{}
+	for _, recipient := range to {
+		switch {
+		case strings.HasPrefix(recipient, "@"):
+			terms = append(terms, predicate("group", strings.TrimPrefix(recipient, "@")))
+		case strings.Contains(recipient, "@"):
+			terms = append(terms, predicate("email", recipient))
+		default:
+			terms = append(terms, predicate("id", recipient))
+		}
+	}
+	if audience = strings.TrimSpace(audience); audience != "" {
+		terms = append(terms, "("+audience+")")
+	}
+	return strings.Join(terms, " OR ")
+}
+
+func predicate(field, value string) string {
+	if strings.ContainsAny(value, " ()\"") {
+		return fmt.Sprintf("%s:%q", field, value)
+	}
+	return field + ":" + value
+}
+
+// writeSendResult prints result for humans.
+func writeSendResult(w io.Writer, result *sendResult) {
+	if result.DryRun {
+		fmt.Fprintf(w, "Would send to %d recipients:\n", len(result.Recipients))
+		for _, recipient := range result.Recipients {
+			fmt.Fprintf(w, "  %s  %s\n", recipient.Value, recipient.Label)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "Sent to %d recipients", len(result.Sent))
+	if result.Blast != "" {
+		fmt.Fprintf(w, " (blast %s)", result.Blast)
+	}
+	fmt.Fprintln(w)
+	for _, skip := range result.Skipped {
+		fmt.Fprintf(w, "  skipped %s: %s\n", skip.User, skip.Reason)
+	}
+	for _, failure := range result.Failed {
+		fmt.Fprintf(w, "  failed %s: %s\n", failure.User, failure.Message)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// stringList is a repeatable flag, also accepting comma-separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// usageError is an error in command-line arguments.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
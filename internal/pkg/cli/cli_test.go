@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gouline/blaster/internal/pkg/audience"
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// run executes command with clean environment, returning exit code and outputs.
+func run(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Setenv("BLASTER_URL", "")
+	t.Setenv("BLASTER_TOKEN", "")
+	t.Setenv("SLACK_BOT_TOKEN", "")
+
+	var stdout, stderr bytes.Buffer
+	code := Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRecipientsExpression(t *testing.T) {
+	for _, test := range []struct {
+		to       []string
+		audience string
+		expected string
+	}{
+		{to: []string{"@oncall"}, expected: "group:oncall"},
+		{to: []string{"U1", "jane@example.com"}, expected: "id:U1 OR email:jane@example.com"},
+		{to: []string{"@oncall"}, audience: "is:admin tz:Asia/*", expected: "group:oncall OR (is:admin tz:Asia/*)"},
+		{to: []string{"@on call"}, expected: `group:"on call"`},
+		{expected: ""},
+	} {
+		expression := recipientsExpression(test.to, test.audience)
+		assert.Equal(t, test.expected, expression)
+		if expression != "" {
+			_, err := audience.Parse(expression)
+			assert.NoError(t, err, expression)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "")
+	limit := flags.Int("limit", 10, "")
+
+	positional, err := parseFlags(flags, []string{"jane", "--json", "doe", "--limit", "3"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"jane", "doe"}, positional)
+		assert.True(t, *jsonOutput)
+		assert.Equal(t, 3, *limit)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		code     int
+		contains string
+	}{
+		{args: []string{}, code: ExitUsage, contains: "Usage"},
+		{args: []string{"unknown"}, code: ExitUsage, contains: `unknown command "unknown"`},
+		{args: []string{"suggest"}, code: ExitUsage, contains: "missing search term"},
+		{args: []string{"suggest", "jane"}, code: ExitUsage, contains: "--bot-token"},
+		{args: []string{"send", "--to", "U1"}, code: ExitUsage, contains: "missing message"},
+		{args: []string{"send", "--message", "Hi"}, code: ExitUsage, contains: "missing recipients"},
+		{args: []string{"send", "--message", "Hi", "--message-file", "x"}, code: ExitUsage, contains: "either"},
+		{args: []string{"send", "--unknown"}, code: ExitUsage, contains: "not defined"},
+	} {
+		code, _, stderr := run(t, "", test.args...)
+		assert.Equal(t, test.code, code, test.args)
+		assert.Contains(t, stderr, test.contains, test.args)
+	}
+
+	code, stdout, _ := run(t, "", "help")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "Usage")
+}
+
+func TestConnectionBackend(t *testing.T) {
+	for _, test := range []struct {
+		env           map[string]string
+		args          []string
+		direct        bool
+		errorContains string
+	}{
+		{args: []string{"--url", "https://blaster.example.com", "--token", "blaster_test"}},
+		{args: []string{"--bot-token", "xoxb-1"}, direct: true},
+		{env: map[string]string{"SLACK_BOT_TOKEN": "xoxb-1"}, args: []string{"--url", "https://blaster.example.com", "--token", "blaster_test"}},
+		{env: map[string]string{"BLASTER_URL": "https://blaster.example.com", "BLASTER_TOKEN": "blaster_test"}, args: []string{"--bot-token", "xoxb-1"}, direct: true},
+		{env: map[string]string{"BLASTER_URL": "https://blaster.example.com", "SLACK_BOT_TOKEN": "xoxb-1"}, args: []string{"--token", "blaster_test"}},
+		{env: map[string]string{"BLASTER_URL": "https://blaster.example.com", "BLASTER_TOKEN": "blaster_test", "SLACK_BOT_TOKEN": "xoxb-1"}, errorContains: "both API mode"},
+		{args: []string{"--url", "https://blaster.example.com", "--bot-token", "xoxb-1"}, errorContains: "use either"},
+		{env: map[string]string{"SLACK_BOT_TOKEN": "xoxb-1"}, args: []string{"--url", "https://blaster.example.com"}, errorContains: "missing --url and --token"},
+	} {
+		t.Setenv("BLASTER_URL", test.env["BLASTER_URL"])
+		t.Setenv("BLASTER_TOKEN", test.env["BLASTER_TOKEN"])
+		t.Setenv("SLACK_BOT_TOKEN", test.env["SLACK_BOT_TOKEN"])
+
+		var stderr bytes.Buffer
+		flags, conn := newFlagSet("test", &stderr)
+		_, err := parseFlags(flags, test.args)
+		assert.NoError(t, err, test.args)
+		b, err := conn.backend(&stderr)
+		if test.errorContains != "" {
+			var usageErr *usageError
+			assert.ErrorAs(t, err, &usageErr, test.args)
+			assert.ErrorContains(t, err, test.errorContains, test.args)
+			continue
+		}
+		if assert.NoError(t, err, test.args) {
+			_, direct := b.(*directBackend)
+			assert.Equal(t, test.direct, direct, test.args)
+			assert.Equal(t, test.direct, strings.Contains(stderr.String(), "warning"), test.args)
+		}
+	}
+}
+
+func TestRunAPI(t *testing.T) {
+	var requests []*http.Request
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/suggest":
+			w.Write([]byte(`[{"type":"usergroup","label":"On-call (oncall)","value":"S1"}]`))
+		case "/api/v1/audience":
+			w.Write([]byte(`{"expression":"group:oncall","count":1,"members":[{"type":"user","label":"Jane Doe","value":"U1"}]}`))
+		case "/api/v1/send":
+			w.Write([]byte(`{"blast":"b1","sent":["U1"],"skipped":[{"user":"U2","reason":"Opted out"}]}`))
+		}
+	}))
+	defer server.Close()
+	conn := []string{"--url", server.URL + "/", "--token", "blaster_test"}
+
+	code, stdout, stderr := run(t, "", append([]string{"suggest", "on", "call", "--limit", "5"}, conn...)...)
+	if assert.Equal(t, ExitOK, code, stderr) {
+		assert.Equal(t, "Bearer blaster_test", requests[0].Header.Get("Authorization"))
+		assert.Equal(t, "on call", requests[0].URL.Query().Get("term"))
+		assert.Equal(t, "5", requests[0].URL.Query().Get("limit"))
+		assert.Equal(t, "S1  usergroup  On-call (oncall)\n", stdout)
+	}
+
+	code, stdout, _ = run(t, "", append([]string{"send", "--to", "@oncall", "--message", "Hi", "--dry-run", "--json"}, conn...)...)
+	if assert.Equal(t, ExitOK, code) {
+		assert.Equal(t, "group:oncall", requests[1].URL.Query().Get("expression"))
+		var result sendResult
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &result))
+		assert.True(t, result.DryRun)
+		assert.Equal(t, "U1", result.Recipients[0].Value)
+	}
+
+	file := filepath.Join(t.TempDir(), "notes.md")
+	assert.NoError(t, os.WriteFile(file, []byte("Release *notes*\n"), 0o600))
+	code, stdout, _ = run(t, "", append([]string{"send", "--to", "@oncall,U3", "--message-file", file}, conn...)...)
+	if assert.Equal(t, ExitOK, code) {
+		assert.Equal(t, http.MethodPost, requests[2].Method)
+		assert.Equal(t, "group:oncall OR id:U3", bodies[2]["audience"])
+		assert.Equal(t, "Release *notes*", bodies[2]["message"])
+		assert.Equal(t, "Sent to 1 recipients (blast b1)\n  skipped U2: Opted out\n", stdout)
+	}
+}
+
+func TestRunAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"code":"forbidden","message":"Token is missing scope send","retryable":false,"request_id":"r1"}`))
+	}))
+	defer server.Close()
+
+	code, _, stderr := run(t, "Hi", "send", "--url", server.URL, "--token", "t", "--to", "U1", "--message-file", "-")
+	assert.Equal(t, ExitError, code)
+	assert.Equal(t, "error: Token is missing scope send (HTTP 403, request r1)\n", stderr)
+}
+
+type mockSession struct {
+	*slack.ClientSession
+	Destinations []*slack.Destination
+	Posted       map[string]string
+}
+
+func (s *mockSession) GetDestinations() ([]*slack.Destination, error) {
+	return s.Destinations, nil
+}
+
+func (s *mockSession) PostMessage(user string, message slack.Message) (*slack.PostedMessage, error) {
+	s.Posted[user] = message.Text
+	return &slack.PostedMessage{Channel: "D" + user, Timestamp: "1.000"}, nil
+}
+
+func TestRunDirect(t *testing.T) {
+	jane := &slack.Destination{Type: "user", ID: "U1", Name: "Jane Doe", Email: "jane@example.com"}
+	john := &slack.Destination{Type: "user", ID: "U2", Name: "John Smith"}
+	session := &mockSession{
+		ClientSession: &slack.ClientSession{},
+		Destinations: []*slack.Destination{jane, john, {
+			Type: "usergroup", ID: "S1", Name: "On-call", DisplayName: "oncall", Children: []*slack.Destination{jane},
+		}},
+		Posted: map[string]string{},
+	}
+	defer func(f func(string) slack.Session) { newSession = f }(newSession)
+	newSession = func(token string) slack.Session {
+		session.Token = token
+		return session
+	}
+
+	code, stdout, stderr := run(t, "", "suggest", "--bot-token", "xoxb-1", "--json", "jane")
+	if assert.Equal(t, ExitOK, code) {
+		assert.Equal(t, "xoxb-1", session.Token)
+		assert.Contains(t, stderr, "exclusions, opt-outs and the delivery policy aren't applied")
+		var suggestions []*suggestion
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &suggestions))
+		assert.Equal(t, []*suggestion{{Type: "user", Label: "Jane Doe", Value: "U1", Email: "jane@example.com"}}, suggestions)
+	}
+
+	code, stdout, _ = run(t, "", "send", "--bot-token", "xoxb-1", "--to", "@oncall", "--message", "Hi", "--dry-run")
+	if assert.Equal(t, ExitOK, code) {
+		assert.Equal(t, "Would send to 1 recipients:\n  U1  Jane Doe\n", stdout)
+		assert.Empty(t, session.Posted)
+	}
+
+	code, stdout, _ = run(t, "", "send", "--bot-token", "xoxb-1", "--to", "@oncall", "--to", "U2", "--message", "Hi")
+	if assert.Equal(t, ExitOK, code) {
+		assert.Equal(t, "Sent to 2 recipients\n", stdout)
+		assert.Equal(t, map[string]string{"U1": "Hi", "U2": "Hi"}, session.Posted)
+	}
+}
@@ -0,0 +1,160 @@
+// Package i18n provides message catalogues and locale negotiation for the UI.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Fallback is the language used for missing messages and unsupported locales.
+var Fallback = language.English
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Translator returns message for key in a particular language, formatted with args if any.
+type Translator func(key string, args ...interface{}) string
+
+// PluralTranslator returns message for key in a particular language in the plural form for count, formatted with it.
+type PluralTranslator func(key string, count int) string
+
+// pluralSuffixes name plural forms in catalogue keys, such as "blasts.votes.one", where "other" is the default.
+var pluralSuffixes = map[plural.Form]string{
+	plural.Zero: "zero",
+	plural.One:  "one",
+	plural.Two:  "two",
+	plural.Few:  "few",
+	plural.Many: "many",
+}
+
+// Catalogue holds messages for supported languages.
+type Catalogue struct {
+	tags     []language.Tag
+	matcher  language.Matcher
+	messages map[language.Tag]map[string]string
+}
+
+// New loads built-in catalogue.
+func New() (*Catalogue, error) {
+	fsys, err := fs.Sub(locales, "locales")
+	if err != nil {
+		return nil, err
+	}
+	return Load(fsys)
+}
+
+// Load reads catalogue from JSON files in fsys root, each named after its language, such as "de.json".
+func Load(fsys fs.FS) (*Catalogue, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalogue{
+		tags:     []language.Tag{},
+		messages: map[language.Tag]map[string]string{},
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(path.Base(file), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid language %s: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("invalid catalogue %s: %w", file, err)
+		}
+		c.tags = append(c.tags, tag)
+		c.messages[tag] = messages
+	}
+	if _, ok := c.messages[Fallback]; !ok {
+		return nil, fmt.Errorf("missing %s catalogue", Fallback)
+	}
+
+	// Matcher defaults to the first tag
+	slices.SortStableFunc(c.tags, func(a, b language.Tag) int {
+		switch {
+		case a == Fallback:
+			return -1
+		case b == Fallback:
+			return 1
+		}
+		return strings.Compare(a.String(), b.String())
+	})
+	c.matcher = language.NewMatcher(c.tags)
+
+	return c, nil
+}
+
+// Languages returns supported languages, starting with [Fallback].
+func (c *Catalogue) Languages() []language.Tag {
+	return slices.Clone(c.tags)
+}
+
+// Match negotiates supported language from preferences in order, each either a locale,
+// such as Slack's "ja-JP", or an Accept-Language header. Empty and invalid ones are ignored.
+func (c *Catalogue) Match(preferences ...string) language.Tag {
+	tags := []language.Tag{}
+	for _, preference := range preferences {
+		parsed, _, err := language.ParseAcceptLanguage(strings.ReplaceAll(preference, "_", "-"))
+		if err == nil {
+			tags = append(tags, parsed...)
+		}
+	}
+
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return Fallback
+	}
+	return c.tags[index]
+}
+
+// Translate returns message for key in language, falling back to [Fallback] and then key itself.
+func (c *Catalogue) Translate(tag language.Tag, key string, args ...interface{}) string {
+	message, ok := c.messages[tag][key]
+	if !ok {
+		if message, ok = c.messages[Fallback][key]; !ok {
+			message = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Translator returns [Translator] for language.
+func (c *Catalogue) Translator(tag language.Tag) Translator {
+	return func(key string, args ...interface{}) string {
+		return c.Translate(tag, key, args...)
+	}
+}
+
+// TranslatePlural returns message for key in language's plural form for count, formatted with count.
+// Forms are separate messages suffixed by their name, such as "key.one", falling back to "key.other".
+func (c *Catalogue) TranslatePlural(tag language.Tag, key string, count int) string {
+	if suffix, ok := pluralSuffixes[plural.Cardinal.MatchPlural(tag, count, 0, 0, 0, 0)]; ok {
+		if _, ok := c.messages[tag][key+"."+suffix]; ok {
+			return c.Translate(tag, key+"."+suffix, count)
+		}
+	}
+	return c.Translate(tag, key+".other", count)
+}
+
+// PluralTranslator returns [PluralTranslator] for language.
+func (c *Catalogue) PluralTranslator(tag language.Tag) PluralTranslator {
+	return func(key string, count int) string {
+		return c.TranslatePlural(tag, key, count)
+	}
+}
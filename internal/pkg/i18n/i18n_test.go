@@ -0,0 +1,101 @@
+package i18n
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []language.Tag{language.English, language.German, language.Japanese}, c.Languages())
+
+	// Every catalogue must translate every message
+	expected := slices.Sorted(maps.Keys(c.messages[Fallback]))
+	for tag, messages := range c.messages {
+		assert.Equal(t, expected, slices.Sorted(maps.Keys(messages)), "language: %s", tag)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, test := range []struct {
+		fsys          fstest.MapFS
+		errorContains string
+	}{
+		{
+			fsys:          fstest.MapFS{"de.json": {Data: []byte(`{}`)}},
+			errorContains: "missing en catalogue",
+		},
+		{
+			fsys:          fstest.MapFS{"en.json": {Data: []byte(`[]`)}},
+			errorContains: "invalid catalogue en.json",
+		},
+		{
+			fsys:          fstest.MapFS{"en.json": {Data: []byte(`{}`)}, "not-a-language!.json": {Data: []byte(`{}`)}},
+			errorContains: "invalid language",
+		},
+	} {
+		_, err := Load(test.fsys)
+		assert.ErrorContains(t, err, test.errorContains)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c, err := New()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, test := range []struct {
+		preferences []string
+		expected    language.Tag
+	}{
+		{preferences: nil, expected: language.English},
+		{preferences: []string{"", "fr-FR,fr;q=0.9"}, expected: language.English},
+		{preferences: []string{"ja-JP"}, expected: language.Japanese},
+		{preferences: []string{"de_DE"}, expected: language.German},
+		{preferences: []string{"", "de-AT,de;q=0.9,en;q=0.8"}, expected: language.German},
+		{preferences: []string{"en-US", "ja"}, expected: language.English},
+		{preferences: []string{"fr-FR", "ja,en;q=0.5"}, expected: language.Japanese},
+		{preferences: []string{";;;", "de"}, expected: language.German},
+	} {
+		assert.Equal(t, test.expected, c.Match(test.preferences...), "preferences: %v", test.preferences)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	c, err := Load(fstest.MapFS{
+		"en.json": {Data: []byte(`{"greeting": "Hello", "welcome": "Welcome to %s"}`)},
+		"de.json": {Data: []byte(`{"greeting": "Hallo"}`)},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "Hallo", c.Translate(language.German, "greeting"))
+	assert.Equal(t, "Welcome to Blaster", c.Translate(language.German, "welcome", "Blaster"))
+	assert.Equal(t, "missing", c.Translate(language.German, "missing"))
+	assert.Equal(t, "Hello", c.Translator(language.Japanese)("greeting"))
+}
+
+func TestTranslatePlural(t *testing.T) {
+	c, err := Load(fstest.MapFS{
+		"en.json": {Data: []byte(`{"votes.one": "%d vote", "votes.other": "%d votes"}`)},
+		"ja.json": {Data: []byte(`{"votes.one": "unused", "votes.other": "%d票"}`)},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "1 vote", c.TranslatePlural(language.English, "votes", 1))
+	assert.Equal(t, "0 votes", c.TranslatePlural(language.English, "votes", 0))
+	assert.Equal(t, "2 votes", c.PluralTranslator(language.English)("votes", 2))
+	assert.Equal(t, "1票", c.TranslatePlural(language.Japanese, "votes", 1))
+}
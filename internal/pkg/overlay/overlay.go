@@ -0,0 +1,125 @@
+// Package overlay combines filesystems so that files in upper layers override those in lower ones.
+package overlay
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// FS reads from the first layer containing a file and merges directory listings across layers.
+type FS struct {
+	layers []fs.FS
+}
+
+// New creates overlay with layers in order of precedence, skipping nil ones.
+func New(layers ...fs.FS) *FS {
+	o := &FS{}
+	for _, layer := range layers {
+		if layer != nil {
+			o.layers = append(o.layers, layer)
+		}
+	}
+	return o
+}
+
+// Open implements [fs.FS] interface.
+func (o *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range o.layers {
+		f, err := layer.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		info, err := f.Stat()
+		if err != nil || !info.IsDir() {
+			return f, err
+		}
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &dir{File: f, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat implements [fs.StatFS] interface.
+func (o *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range o.layers {
+		info, err := fs.Stat(layer, name)
+		if err == nil {
+			return info, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements [fs.ReadDirFS] interface, listing entries from all layers sorted by name.
+func (o *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	found := false
+	entries := map[string]fs.DirEntry{}
+	for _, layer := range o.layers {
+		layerEntries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range layerEntries {
+			if _, ok := entries[entry.Name()]; !ok {
+				entries[entry.Name()] = entry
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name() < merged[j].Name()
+	})
+	return merged, nil
+}
+
+// dir is a directory from the first layer containing it, listing entries merged across layers.
+type dir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+// ReadDir implements [fs.ReadDirFile] interface.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
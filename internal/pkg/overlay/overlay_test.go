@@ -0,0 +1,56 @@
+package overlay
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFS(t *testing.T) {
+	upper := fstest.MapFS{
+		"css/main.css":  {Data: []byte("upper")},
+		"css/extra.css": {Data: []byte("extra")},
+	}
+	lower := fstest.MapFS{
+		"css/main.css":    {Data: []byte("lower")},
+		"js/blaster.js":   {Data: []byte("script")},
+		"img/favicon.ico": {Data: []byte("icon")},
+	}
+	o := New(upper, nil, lower)
+
+	data, err := fs.ReadFile(o, "css/main.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "upper", string(data))
+
+	data, err = fs.ReadFile(o, "js/blaster.js")
+	assert.NoError(t, err)
+	assert.Equal(t, "script", string(data))
+
+	_, err = fs.ReadFile(o, "missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = o.Open("../escape")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+
+	info, err := fs.Stat(o, "css/extra.css")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(5), info.Size())
+	}
+
+	_, err = fs.ReadDir(o, "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	paths := []string{}
+	err = fs.WalkDir(o, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"css/extra.css", "css/main.css", "img/favicon.ico", "js/blaster.js"}, paths)
+
+	assert.NoError(t, fstest.TestFS(New(upper, lower), "css/main.css", "css/extra.css", "js/blaster.js"))
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Score ranks how well a query matches a text, higher is better.
+type Score int
+
+const (
+	ScoreNone Score = iota
+	ScoreFuzzy
+	ScoreSubstring
+	ScoreWordPrefix
+	ScorePrefix
+	ScoreExact
+)
+
+// foldReplacements covers letters that don't decompose into base and combining mark.
+var foldReplacements = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+}
+
+// Fold lowercases string, strips accents, removes punctuation and symbols, and collapses whitespace.
+func Fold(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r), unicode.IsPunct(r), unicode.IsSymbol(r):
+			continue
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		if replacement, ok := foldReplacements[r]; ok {
+			b.WriteString(replacement)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
+
+// Match scores query against text, both of which are folded first.
+// Every word in query must match a word in text for a non-zero score.
+func Match(query, text string) Score {
+	query, text = Fold(query), Fold(text)
+	if query == "" {
+		return ScoreNone
+	}
+
+	switch {
+	case text == query:
+		return ScoreExact
+	case strings.HasPrefix(text, query):
+		return ScorePrefix
+	case strings.Contains(" "+text, " "+query):
+		return ScoreWordPrefix
+	}
+
+	words := strings.Fields(text)
+	score := ScoreWordPrefix
+	for _, token := range strings.Fields(query) {
+		best := ScoreNone
+		for _, word := range words {
+			if s := matchWord(token, word); s > best {
+				best = s
+			}
+		}
+		if best == ScoreNone {
+			return ScoreNone
+		}
+		score = min(score, best)
+	}
+	return score
+}
+
+// matchWord scores a single query token against a single word.
+func matchWord(token, word string) Score {
+	switch {
+	case strings.HasPrefix(word, token):
+		return ScoreWordPrefix
+	case strings.Contains(word, token):
+		return ScoreSubstring
+	}
+
+	tokenRunes, wordRunes := []rune(token), []rune(word)
+	maxDistance := typoTolerance(len(tokenRunes))
+	if maxDistance == 0 {
+		return ScoreNone
+	}
+
+	// Compare against prefixes around the token length to tolerate partially typed words
+	for n := len(tokenRunes) - maxDistance; n <= len(tokenRunes)+maxDistance; n++ {
+		if n <= 0 || n > len(wordRunes) {
+			continue
+		}
+		if distance(tokenRunes, wordRunes[:n]) <= maxDistance {
+			return ScoreFuzzy
+		}
+	}
+	return ScoreNone
+}
+
+// typoTolerance returns maximum edit distance allowed for a token of given length.
+func typoTolerance(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// distance computes optimal string alignment distance, counting transpositions as one edit.
+func distance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+// MatchValue scores query against a single field value, such as an email address.
+// Unlike [Match], values aren't folded, so punctuation such as "@" in "jane@" is significant.
+func MatchValue(query, value string) Score {
+	query, value = strings.ToLower(query), strings.ToLower(value)
+	switch {
+	case query == "":
+		return ScoreNone
+	case value == query:
+		return ScoreExact
+	case strings.HasPrefix(value, query):
+		return ScorePrefix
+	case strings.Contains(value, query):
+		return ScoreSubstring
+	}
+	return ScoreNone
+}
+
+// Query is a parsed search string with free text and field-qualified filters.
+type Query struct {
+	Text    string
+	Filters []Filter
+}
+
+// Filter restricts search to values of a named field.
+type Filter struct {
+	Field string
+	Value string
+}
+
+// ParseQuery splits s into free text and "field:value" filters, where value may be double-quoted.
+// Tokens with a field name rejected by isField remain part of the free text.
+func ParseQuery(s string, isField func(string) bool) Query {
+	query := Query{}
+	text := []string{}
+	for _, token := range tokenize(s) {
+		field, value, found := strings.Cut(token, ":")
+		field = strings.ToLower(field)
+		if found && value != "" && isField(field) {
+			query.Filters = append(query.Filters, Filter{
+				Field: field,
+				Value: strings.Trim(value, `"`),
+			})
+			continue
+		}
+		text = append(text, token)
+	}
+	query.Text = strings.Join(text, " ")
+	return query
+}
+
+// tokenize splits s on whitespace outside of double quotes.
+func tokenize(s string) []string {
+	tokens := []string{}
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected string
+	}{
+		{
+			s:        "test s!t@r#i$n^g [1,2)",
+			expected: "test string 12",
+		},
+		{
+			s:        "тестовая с!т@р#о$к^а (9.0]",
+			expected: "тестовая строка 90",
+		},
+		{
+			s:        "测!试@字#符$串%5^6",
+			expected: "测试字符串56",
+		},
+		{
+			s:        "  José  Müller-Łukasz ",
+			expected: "jose mullerlukasz",
+		},
+		{
+			s:        "Straße Ørsted",
+			expected: "strasse orsted",
+		},
+	} {
+		assert.Equal(t, test.expected, Fold(test.s))
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		text     string
+		expected Score
+	}{
+		{query: "", text: "anything", expected: ScoreNone},
+		{query: "Mike", text: "mike", expected: ScoreExact},
+		{query: "mi", text: "Mike Gouline", expected: ScorePrefix},
+		{query: "gou", text: "Mike Gouline", expected: ScoreWordPrefix},
+		{query: "oul", text: "Mike Gouline", expected: ScoreSubstring},
+		{query: "gouline mike", text: "Mike Gouline", expected: ScoreWordPrefix},
+		{query: "goulnie", text: "Mike Gouline", expected: ScoreFuzzy},
+		{query: "jsoe", text: "José Müller", expected: ScoreFuzzy},
+		{query: "muller", text: "José Müller", expected: ScoreWordPrefix},
+		{query: "xyz", text: "Mike Gouline", expected: ScoreNone},
+		{query: "mike smith", text: "Mike Gouline", expected: ScoreNone},
+		{query: "kno", text: "mark", expected: ScoreNone},
+	} {
+		assert.Equal(t, test.expected, Match(test.query, test.text), "query: %s, text: %s", test.query, test.text)
+	}
+}
+
+func TestMatchValue(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		value    string
+		expected Score
+	}{
+		{query: "", value: "jane@example.com", expected: ScoreNone},
+		{query: "Jane@Example.com", value: "jane@example.com", expected: ScoreExact},
+		{query: "jane@", value: "jane@example.com", expected: ScorePrefix},
+		{query: "jane@", value: "mary.jane@example.com", expected: ScoreSubstring},
+		{query: "jane@", value: "jane.doe@example.com", expected: ScoreNone},
+	} {
+		assert.Equal(t, test.expected, MatchValue(test.query, test.value), "query: %s, value: %s", test.query, test.value)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	isField := func(field string) bool {
+		return field == "email" || field == "title"
+	}
+
+	for _, test := range []struct {
+		s        string
+		expected Query
+	}{
+		{
+			s:        "jane doe",
+			expected: Query{Text: "jane doe"},
+		},
+		{
+			s: "jane Email:jane@ title:\"staff engineer\"",
+			expected: Query{
+				Text: "jane",
+				Filters: []Filter{
+					{Field: "email", Value: "jane@"},
+					{Field: "title", Value: "staff engineer"},
+				},
+			},
+		},
+		{
+			s:        "meeting 10:30 title:",
+			expected: Query{Text: "meeting 10:30 title:"},
+		},
+	} {
+		assert.Equal(t, test.expected, ParseQuery(test.s, isField), "s: %s", test.s)
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ackBlockID identifies the actions block holding the Acknowledge button.
+const ackBlockID = "blast_ack"
+
+// ackActions creates Acknowledge button for blast.
+func ackActions(blastID string) *block {
+	return actionsBlock(ackBlockID, button(actionBlastAck, "Acknowledge", blastID, "primary"))
+}
+
+// handleActionBlastAck records recipient acknowledging blast, replacing the button with a confirmation.
+func (s *Server) handleActionBlastAck(callback slack.InteractionCallback, id string) {
+	user := callback.User.ID
+	_, err := s.blasts.Update(id, func(blast *blast) error {
+		if blast.WorkspaceID != callback.Team.ID || !slices.Contains(blast.Sent, user) {
+			return errNotFound()
+		}
+		if _, ok := blast.Acks[user]; ok {
+			return nil
+		}
+		if blast.Acks == nil {
+			blast.Acks = map[string]time.Time{}
+		}
+		blast.Acks[user] = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		s.config.Logger.Warn("failed to acknowledge blast", zap.String("blast", id), zap.String("user", user), zap.Error(err))
+		if err := slack.Respond(callback.ResponseURL, ephemeral("Failed to acknowledge, the announcement may have been deleted.")); err != nil {
+			s.config.Logger.Warn("failed to respond to interaction", zap.Error(err))
+		}
+		return
+	}
+
+	s.respond(callback, replaceBlock(callback, ackBlockID, sectionBlock(":white_check_mark: Acknowledged")))
+}
+
+// handleAPIBlastAcksGet handles GET /api/blasts/:id/acks, reporting which recipients acknowledged blast.
+func (s *Server) handleAPIBlastAcksGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	blast, err := s.ackBlast(session, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, newAckReport(blast))
+}
+
+// handleAPIBlastRemind handles POST /api/blasts/:id/remind, asking recipients yet to acknowledge blast
+// to do so in a reply to the original message. Only the sender can remind, since replies go to their
+// conversations, and the reminder is sent and recorded like any other blast.
+func (s *Server) handleAPIBlastRemind(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	target, err := s.ackBlast(session, c.Param("id"))
+	if err != nil {
+		return err
+	} else if target.CreatedBy != session.UserID() {
+		return errForbidden("Only the sender can remind recipients")
+	}
+
+	var request remindRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+
+	pending := newAckReport(target).Pending
+	if len(pending) == 0 {
+		return errBadRequest("no recipients pending acknowledgement")
+	}
+
+	response, err := s.sendBlast(c, session, &sendRequest{
+		Users:   pending,
+		Message: "*Reminder:* please acknowledge this message.\n" + quote(target.Message),
+		AsUser:  target.AsUser,
+		remind:  target,
+
+		OverrideReason: request.OverrideReason,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(response.Sent) > 0 || len(response.Waves) > 0 {
+		_, err = s.blasts.Update(target.ID, func(blast *blast) error {
+			now := time.Now().UTC()
+			blast.RemindedAt = &now
+			return nil
+		})
+		if err != nil {
+			return errInternal(err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// ackBlast gets workspace's blast by ID, which must have asked recipients to acknowledge it.
+func (s *Server) ackBlast(session slack.Session, id string) (*blast, error) {
+	blast, err := s.blasts.Get(id)
+	if err != nil {
+		return nil, errInternal(err)
+	} else if blast == nil || blast.WorkspaceID != session.WorkspaceID() {
+		return nil, errNotFound()
+	} else if !blast.Acknowledge {
+		return nil, errBadRequest("blast doesn't ask for acknowledgement")
+	}
+	return blast, nil
+}
+
+// newAckReport splits blast's recipients by whether they acknowledged it, earliest first.
+func newAckReport(blast *blast) *ackReport {
+	report := &ackReport{
+		Acknowledged: []*ack{},
+		Pending:      []string{},
+	}
+	for _, user := range blast.Sent {
+		if at, ok := blast.Acks[user]; ok {
+			report.Acknowledged = append(report.Acknowledged, &ack{User: user, At: at})
+		} else {
+			report.Pending = append(report.Pending, user)
+		}
+	}
+	sort.SliceStable(report.Acknowledged, func(i, j int) bool {
+		return report.Acknowledged[i].At.Before(report.Acknowledged[j].At)
+	})
+	return report
+}
+
+type ackReport struct {
+	Acknowledged []*ack   `json:"acknowledged"`
+	Pending      []string `json:"pending"`
+}
+
+type ack struct {
+	User string    `json:"user"`
+	At   time.Time `json:"at"`
+}
+
+type remindRequest struct {
+	// OverrideReason reminds recipients outside working hours now, despite workspace's policy
+	OverrideReason string `json:"override_reason,omitempty"`
+}
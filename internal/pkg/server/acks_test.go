@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextBlocks(t *testing.T) {
+	assert.Empty(t, textBlocks(""))
+
+	line := strings.Repeat("a", 99) + "\n"
+	blocks := textBlocks(strings.Repeat(line, 45))
+	if assert.Len(t, blocks, 2) {
+		assert.Equal(t, strings.Repeat(line, 30), blocks[0].Text.Text)
+		assert.Equal(t, strings.Repeat(line, 15), blocks[1].Text.Text)
+	}
+
+	blocks = textBlocks(strings.Repeat("é", 7000))
+	if assert.Len(t, blocks, 3) {
+		assert.Equal(t, strings.Repeat("é", sectionMaxLength), blocks[0].Text.Text)
+		assert.Equal(t, strings.Repeat("é", 1000), blocks[2].Text.Text)
+	}
+}
+
+func TestBlastAcks(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	mock := newMockSession("T1", "U9")
+	request := func(method, body, id string, handler echo.HandlerFunc) *requestTester {
+		return serveAPI(t, server, mock, method, body, handler, "id", id)
+	}
+
+	r := request(http.MethodPost, `{"users":["U1","U2","U3"],"message":"Fire drill at 3pm","acknowledge":true}`, "", server.handleAPISend)
+	var sent sendResponse
+	if !assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) || !assert.Len(t, sent.Sent, 3) {
+		return
+	}
+	var blocks []*block
+	if assert.NoError(t, json.Unmarshal([]byte(mock.PostedBlocks["U1"]), &blocks)) && assert.Len(t, blocks, 2) {
+		assert.True(t, strings.HasPrefix(blocks[0].Text.Text, "Fire drill at 3pm"))
+		assert.Equal(t, ackBlockID, blocks[1].BlockID)
+		assert.Equal(t, sent.Blast, blocks[1].Elements[0].Value)
+	}
+
+	responseURL, responses := newResponseURL()
+	defer responseURL.Close()
+
+	ack := func(team, user string) {
+		r := newInteractionTester(server, map[string]interface{}{
+			"type":         "block_actions",
+			"team":         map[string]string{"id": team},
+			"user":         map[string]string{"id": user},
+			"response_url": responseURL.URL,
+			"message": map[string]interface{}{
+				"text":   mock.Posted[user],
+				"blocks": json.RawMessage(mock.PostedBlocks[user]),
+			},
+			"actions": []map[string]string{{"block_id": ackBlockID, "action_id": actionBlastAck, "value": sent.Blast}},
+		})
+		assert.NoError(t, server.handleSlackInteraction(r.Context))
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+	}
+
+	ack("T1", "U2")
+	response := <-responses
+	assert.True(t, response.ReplaceOriginal)
+	if assert.Len(t, response.Blocks, 2) {
+		assert.True(t, strings.HasPrefix(response.Blocks[0].Text.Text, "Fire drill at 3pm"))
+		assert.Contains(t, response.Blocks[1].Text.Text, "Acknowledged")
+	}
+
+	// Other workspaces can't acknowledge
+	ack("T2", "U1")
+	response = <-responses
+	assert.False(t, response.ReplaceOriginal)
+	assert.Contains(t, response.Text, "Failed")
+
+	r = request(http.MethodGet, "", sent.Blast, server.handleAPIBlastAcksGet)
+	var report ackReport
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &report)) && assert.Len(t, report.Acknowledged, 1) {
+		assert.Equal(t, "U2", report.Acknowledged[0].User)
+		assert.ElementsMatch(t, []string{"U1", "U3"}, report.Pending)
+	}
+
+	assert.NoError(t, server.optOut("T1", "U3"))
+	postedU1 := mock.PostedBlocks["U1"]
+	r = request(http.MethodPost, "", sent.Blast, server.handleAPIBlastRemind)
+	var reminded sendResponse
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &reminded)) {
+		assert.Equal(t, []string{"U1"}, reminded.Sent)
+		if assert.Len(t, reminded.Skipped, 1) {
+			assert.Equal(t, "U3", reminded.Skipped[0].User)
+		}
+	}
+	assert.NotEqual(t, postedU1, mock.PostedBlocks["U1"])
+	assert.Contains(t, mock.PostedBlocks["U1"], sent.Blast)
+	blast, err := server.blasts.Get(sent.Blast)
+	if assert.NoError(t, err) {
+		assert.Equal(t, blast.Messages["U1"].Timestamp, mock.PostedThreads["U1"])
+		assert.NotNil(t, blast.RemindedAt)
+	}
+
+	// Reminder is recorded in history like any other blast
+	reminder, err := server.blasts.Get(reminded.Blast)
+	if assert.NoError(t, err) && assert.NotNil(t, reminder) {
+		assert.Equal(t, sent.Blast, reminder.ReminderOf)
+		assert.Equal(t, blastStatusCompleted, reminder.Status)
+		assert.Contains(t, reminder.Message, "> Fire drill at 3pm")
+	}
+
+	// Only the sender can remind, as reminders reply in their conversations
+	mock.User = "U1"
+	r = request(http.MethodPost, "", sent.Blast, server.handleAPIBlastRemind)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	mock.User = "U9"
+
+	r = request(http.MethodPost, `{"users":["U1"],"message":"No buttons"}`, "", server.handleAPISend)
+	var plain sendResponse
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &plain))
+	assert.Empty(t, mock.PostedBlocks["U1"])
+	r = request(http.MethodGet, "", plain.Blast, server.handleAPIBlastAcksGet)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+}
@@ -1,88 +1,445 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
-	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gouline/blaster/internal/pkg/audience"
+	"github.com/gouline/blaster/internal/pkg/search"
 	"github.com/gouline/blaster/internal/pkg/slack"
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 )
 
+const (
+	suggestDefaultLimit = 10
+	suggestMaxLimit     = 100
+
+	// suggestionTypeBoostScale separates match quality tiers so that type boosts only reorder within a tier.
+	suggestionTypeBoostScale = 10
+)
+
+// suggestionTypeBoost ranks destination types against each other when match quality is equal.
+var suggestionTypeBoost = map[string]int{
+	"list":      3,
+	"usergroup": 2,
+	"user":      1,
+}
+
 // handleAPISuggest handles /api/suggest.
 func (s *Server) handleAPISuggest(c echo.Context) error {
 	session := s.session(c)
 	if !session.IsAuthenticated() {
-		return c.NoContent(http.StatusUnauthorized)
+		return errUnauthorized()
+	}
+
+	limit, err := queryInt(c, "limit", suggestDefaultLimit)
+	if err != nil || limit < 1 || limit > suggestMaxLimit {
+		return errBadRequest(fmt.Sprintf("limit must be between 1 and %d", suggestMaxLimit))
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		return errBadRequest("offset must be non-negative")
 	}
 
 	destinations, err := session.GetDestinations()
 	if err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+		return errInternal(err)
 	}
 
-	suggestions := suggestDestinations(c.QueryParam("term"), destinations)
+	lists, err := s.workspaceLists(session)
+	if err != nil {
+		return errInternal(err)
+	}
+	destinations = append(listDestinations(lists, destinations), destinations...)
+
+	if c.QueryParam("include_disabled") != "true" {
+		destinations = slices.DeleteFunc(slices.Clone(destinations), func(dest *slack.Destination) bool {
+			return dest.Disabled
+		})
+	}
+
+	destinations, err = searchProfiles(session, c.QueryParam("term"), destinations)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	suggestions := suggestDestinations(c.QueryParam("term"), destinations, limit, offset)
 
 	return c.JSON(http.StatusOK, suggestions)
 }
 
+// handleAPIAudience handles /api/audience.
+func (s *Server) handleAPIAudience(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	expr, err := audience.Parse(c.QueryParam("expression"))
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	destinations, err := audience.Destinations(session, expr)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	members := []*suggestion{}
+	for _, user := range audience.NewIndex(destinations).Resolve(expr) {
+		members = append(members, &suggestion{
+			Type:  user.Type,
+			Label: suggestionLabel(user.Name, user.DisplayName),
+			Value: user.ID,
+			Email: user.Email,
+			Title: user.Title,
+		})
+	}
+
+	return c.JSON(http.StatusOK, &audienceResponse{
+		Expression: expr.String(),
+		Count:      len(members),
+		Members:    members,
+	})
+}
+
 // handleAPISend handles /api/send.
 func (s *Server) handleAPISend(c echo.Context) error {
 	session := s.session(c)
 	if !session.IsAuthenticated() {
-		return c.NoContent(http.StatusUnauthorized)
+		return errUnauthorized()
 	}
 
 	var request sendRequest
 	if err := c.Bind(&request); err != nil {
-		return c.String(http.StatusBadRequest, err.Error())
+		return err
 	}
 
-	if err := session.PostMessage(request.User, request.Message, request.AsUser); err != nil {
-		return c.String(http.StatusInternalServerError, err.Error())
+	response, err := s.sendBlast(c, session, &request)
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, struct{}{})
+	return c.JSON(http.StatusOK, response)
 }
 
-// suggestDestinations filters destionations into suggestions by search term.
-func suggestDestinations(term string, destinations []*slack.Destination) []*suggestion {
-	term = " " + sanitizeSearchTerm(term)
+// sendBlast sends request's message to its recipients, skipping excluded users and recording the blast in history.
+// Used by every way of sending, so that they apply the same rules. Errors are returned as [apiError].
+func (s *Server) sendBlast(c echo.Context, session slack.Session, request *sendRequest) (*sendResponse, error) {
+	if err := request.Poll.validate(); err != nil {
+		return nil, errBadRequest(err.Error())
+	}
+	if err := request.Schedule.validate(); err != nil {
+		return nil, errBadRequest(err.Error())
+	}
+	if request.ForwardReplies != "" && !channelIDReg.MatchString(request.ForwardReplies) {
+		return nil, errBadRequest("invalid channel ID to forward replies to")
+	}
+	if request.Approver != "" && (!userIDReg.MatchString(request.Approver) || request.Approver == session.UserID()) {
+		return nil, errBadRequest("approver must be the ID of another user")
+	}
 
-	suggestions := []*suggestion{}
-	for _, dest := range destinations {
-		searchable := " " + sanitizeSearchTerm(strings.ToLower(dest.Name)+" "+strings.ToLower(dest.DisplayName))
-
-		if strings.Contains(searchable, term) {
-			children := []*suggestion{}
-			for _, child := range dest.Children {
-				children = append(children, &suggestion{
-					Type:  sanitizeCSV(child.Type),
-					Label: sanitizeCSV(suggestionLabel(child.Name, child.DisplayName)),
-					Value: sanitizeCSV(child.ID),
-				})
+	ids, err := s.sendRecipients(session, request)
+	if err != nil {
+		return nil, errBadRequest(err.Error())
+	}
+	if len(ids) == 0 {
+		return nil, errBadRequest("no recipients")
+	}
+
+	resolution, err := s.resolveRecipients(session, ids)
+	if err != nil {
+		return nil, errInternal(err)
+	}
+
+	excluded, err := s.excludedUsers(session.WorkspaceID())
+	if err != nil {
+		return nil, errInternal(err)
+	}
+
+	variables := map[string]map[string]string{}
+	for _, recipient := range request.Recipients {
+		variables[recipient.User] = recipient.Variables
+	}
+
+	response := &sendResponse{
+		Sent:     []string{},
+		Skipped:  resolution.Skipped,
+		Warnings: resolution.Warnings,
+		messages: map[string]*slack.PostedMessage{},
+	}
+	recipients := []*resolvedUser{}
+	for _, user := range resolution.Users {
+		if reason, ok := excluded[user.ID]; ok {
+			response.Skipped = append(response.Skipped, &recipientSkip{User: user.ID, Reason: reason})
+			continue
+		}
+		recipients = append(recipients, user)
+	}
+
+	var waves []*blastWave
+	if request.Schedule != nil {
+		waves = request.Schedule.waves(recipients)
+		if len(waves) > 0 && waves[len(waves)-1].SendAt.Before(time.Now()) {
+			return nil, errBadRequest("scheduled time has passed for every recipient")
+		}
+		recipients = []*resolvedUser{}
+	}
+
+	recipients, waves, override, err := s.applyDeliveryPolicy(session.WorkspaceID(), request, recipients, waves, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	// Recipients who'd be sent to now wait for approval instead, in a wave that's due once approved
+	if request.Approver != "" && len(recipients) > 0 {
+		users := make([]string, len(recipients))
+		for i, user := range recipients {
+			users[i] = user.ID
+		}
+		waves = append(waves, &blastWave{SendAt: time.Now().UTC(), Users: users, Status: waveStatusPending})
+		sortWaves(waves)
+		recipients = []*resolvedUser{}
+	}
+
+	blast, err := s.createBlast(c, session, request, override)
+	if err != nil {
+		return nil, errInternal(err)
+	}
+	response.Blast = blast.ID
+
+	users := make([]string, len(recipients))
+	for i, user := range recipients {
+		users[i] = user.ID
+	}
+	firstErr := s.postBlast(c, session, blast, users, variables, response)
+
+	if len(waves) > 0 {
+		if err := s.scheduleBlast(c, blast.ID, request.Schedule, waves, variables, response); err != nil {
+			return nil, errInternal(err)
+		}
+		if blast.Approval != nil {
+			count := 0
+			for _, wave := range waves {
+				count += len(wave.Users)
 			}
+			s.requestApproval(c, session, blast, count)
+		}
+		return response, nil
+	}
 
-			suggestions = append(suggestions, &suggestion{
-				Type:     sanitizeCSV(dest.Type),
-				Label:    sanitizeCSV(suggestionLabel(dest.Name, dest.DisplayName)),
-				Value:    sanitizeCSV(dest.ID),
-				Children: children,
-			})
+	if _, err := s.completeBlast(blast.ID, response); err != nil {
+		return nil, errInternal(err)
+	}
+
+	if len(response.Sent) == 0 && len(response.Failed) > 0 {
+		return nil, errInternal(firstErr)
+	}
+
+	return response, nil
+}
 
-			if len(suggestions) == 10 {
-				break
+// postBlast posts blast's message to each user, recording outcomes in response, and returns the first error.
+func (s *Server) postBlast(c echo.Context, session slack.Session, blast *blast, users []string, variables map[string]map[string]string, response *sendResponse) error {
+	actions := []*block{}
+	if blast.Poll != nil {
+		actions = append(actions, pollActions(blast.ID, blast.Poll.Options))
+	}
+	if blast.Acknowledge {
+		actions = append(actions, ackActions(blast.ID))
+	} else if blast.ReminderOf != "" {
+		actions = append(actions, ackActions(blast.ReminderOf))
+	}
+
+	var firstErr error
+	for _, user := range users {
+		text := mergeVariables(blast.Message, variables[user]) + s.optOutFooter(c, session, user)
+		message := slack.Message{Text: text, AsUser: blast.AsUser, ThreadTimestamp: blast.Threads[user]}
+		if len(actions) > 0 {
+			message.Blocks = encodeBlocks(text, actions...)
+		}
+		posted, err := session.PostMessage(user, message)
+		if err != nil {
+			response.Failed = append(response.Failed, newSendFailure(user, err))
+			if firstErr == nil {
+				firstErr = err
 			}
+			continue
+		}
+		response.Sent = append(response.Sent, user)
+		response.messages[user] = posted
+		if message.ThreadTimestamp == "" {
+			if err := s.indexPost(blast, user, posted); err != nil {
+				s.config.Logger.Warn("failed to index posted blast", zap.String("blast", blast.ID), zap.String("user", user), zap.Error(err))
+			}
+		}
+	}
+	return firstErr
+}
+
+// sendRecipients combines explicit recipient IDs and audience expression from request into unique IDs to resolve.
+func (s *Server) sendRecipients(session slack.Session, request *sendRequest) ([]string, error) {
+	ids := append([]string{request.User}, request.Users...)
+	for _, recipient := range request.Recipients {
+		ids = append(ids, recipient.User)
+	}
+
+	if request.Audience != "" {
+		expr, err := audience.Parse(request.Audience)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audience: %w", err)
+		}
+		destinations, err := audience.Destinations(session, expr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve audience: %w", err)
+		}
+		for _, user := range audience.NewIndex(destinations).Resolve(expr) {
+			ids = append(ids, user.ID)
+		}
+	}
+
+	return uniqueStrings(ids), nil
+}
+
+// searchProfiles loads profiles of user destinations if term filters by pronouns or custom profile fields.
+func searchProfiles(session slack.Session, term string, destinations []*slack.Destination) ([]*slack.Destination, error) {
+	if !strings.Contains(term, ":") {
+		return destinations, nil
+	}
+	names, err := session.GetProfileFieldNames()
+	if err != nil {
+		return nil, err
+	}
+	profileFields := map[string]bool{"pronouns": true}
+	for _, name := range names {
+		profileFields[name] = true
+	}
+	if len(search.ParseQuery(term, func(field string) bool { return profileFields[field] }).Filters) == 0 {
+		return destinations, nil
+	}
+	return session.WithProfiles(destinations)
+}
+
+// suggestDestinations ranks destinations by search term and returns a page of suggestions.
+// Term may contain field-qualified filters, such as "email:jane@" or "title:engineer".
+// Empty term suggests destinations in directory order.
+func suggestDestinations(term string, destinations []*slack.Destination, limit, offset int) []*suggestion {
+	type ranked struct {
+		dest  *slack.Destination
+		score int
+	}
+
+	customFields := map[string]bool{}
+	for _, dest := range destinations {
+		for field := range dest.Fields {
+			customFields[field] = true
 		}
 	}
+	query := search.ParseQuery(term, func(field string) bool {
+		return destinationFields[field] || customFields[field]
+	})
+
+	matches := []ranked{}
+	for _, dest := range destinations {
+		if query.Text == "" && len(query.Filters) == 0 {
+			matches = append(matches, ranked{dest: dest})
+			continue
+		}
+		score := matchDestination(query, dest)
+		if score == search.ScoreNone {
+			continue
+		}
+		matches = append(matches, ranked{
+			dest:  dest,
+			score: int(score)*suggestionTypeBoostScale + suggestionTypeBoost[dest.Type],
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	suggestions := []*suggestion{}
+	for i := offset; i < len(matches) && len(suggestions) < limit; i++ {
+		dest := matches[i].dest
+
+		children := []*suggestion{}
+		for _, child := range dest.Children {
+			children = append(children, &suggestion{
+				Type:  sanitizeCSV(child.Type),
+				Label: sanitizeCSV(suggestionLabel(child.Name, child.DisplayName)),
+				Value: sanitizeCSV(child.ID),
+			})
+		}
+
+		suggestions = append(suggestions, &suggestion{
+			Type:     sanitizeCSV(dest.Type),
+			Label:    sanitizeCSV(suggestionLabel(dest.Name, dest.DisplayName)),
+			Value:    sanitizeCSV(dest.ID),
+			Email:    dest.Email,
+			Title:    dest.Title,
+			Disabled: dest.Disabled,
+			Children: children,
+		})
+	}
 
 	return suggestions
 }
 
-// sanitizeSearchTerm removes lowercases search term and removes skippable characters.
-func sanitizeSearchTerm(s string) string {
-	symbolReg := regexp.MustCompile("[!-/:-@[-`{-~]+")
-	return symbolReg.ReplaceAllString(strings.ToLower(s), "")
+// matchDestination scores destination against free text and every filter in query.
+// The weakest component determines the overall score.
+func matchDestination(query search.Query, dest *slack.Destination) search.Score {
+	score := search.ScoreExact
+	if query.Text != "" {
+		score = search.Match(query.Text, dest.Name+" "+dest.DisplayName)
+	} else if len(query.Filters) == 0 {
+		return search.ScoreNone
+	}
+
+	for _, filter := range query.Filters {
+		if score == search.ScoreNone {
+			break
+		}
+		var filterScore search.Score
+		switch filter.Field {
+		case "name":
+			filterScore = search.Match(filter.Value, dest.Name+" "+dest.DisplayName)
+		case "is":
+			if dest.HasFlag(filter.Value) {
+				filterScore = search.ScoreExact
+			}
+		default:
+			filterScore = search.MatchValue(filter.Value, dest.Field(filter.Field))
+		}
+		score = min(score, filterScore)
+	}
+	return score
+}
+
+// destinationFields lists built-in field qualifiers accepted in search terms.
+var destinationFields = map[string]bool{
+	"name":     true,
+	"type":     true,
+	"email":    true,
+	"title":    true,
+	"tz":       true,
+	"locale":   true,
+	"pronouns": true,
+	"is":       true,
+}
+
+// queryInt parses an optional integer query parameter, returning fallback if absent.
+func queryInt(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
 }
 
 // sanitizeCSV removes commas for comma-separated values.
@@ -100,14 +457,90 @@ func suggestionLabel(name, displayName string) string {
 }
 
 type sendRequest struct {
-	User    string `json:"user"`
-	Message string `json:"message"`
-	AsUser  bool   `json:"as_user"`
+	User       string       `json:"user"`
+	Users      []string     `json:"users"`
+	Recipients []*recipient `json:"recipients"`
+	Audience   string       `json:"audience"`
+	Message    string       `json:"message"`
+	AsUser     bool         `json:"as_user"`
+
+	// Acknowledge adds a button for recipients to acknowledge the message
+	Acknowledge bool `json:"acknowledge,omitempty"`
+
+	// Poll adds buttons for recipients to vote for one of the options
+	Poll *pollRequest `json:"poll,omitempty"`
+
+	// ForwardReplies is the ID of a channel to forward recipients' replies to, in a thread
+	ForwardReplies string `json:"forward_replies,omitempty"`
+
+	// Schedule sends the message later, at the same local time for each recipient
+	Schedule *scheduleRequest `json:"schedule,omitempty"`
+
+	// OverrideReason sends to recipients outside working hours now, despite workspace's policy in warn mode
+	OverrideReason string `json:"override_reason,omitempty"`
+
+	// Approver is the ID of another user who must approve the blast before it's sent
+	Approver string `json:"approver,omitempty"`
+
+	// remind is the blast whose recipients are reminded to acknowledge it, in replies to their messages
+	remind *blast
+}
+
+type sendResponse struct {
+	// Blast is the ID of the blast recorded in history
+	Blast string `json:"blast"`
+
+	Sent    []string         `json:"sent"`
+	Skipped []*recipientSkip `json:"skipped,omitempty"`
+	Failed  []*sendFailure   `json:"failed,omitempty"`
+
+	// Warnings lists user groups whose membership changed since they were suggested
+	Warnings []*membershipChange `json:"warnings,omitempty"`
+
+	// Waves lists when each timezone's recipients will be sent a scheduled or deferred blast
+	Waves []*blastWave `json:"waves,omitempty"`
+
+	// messages are the messages posted to each sent recipient, kept in history
+	messages map[string]*slack.PostedMessage
+}
+
+type recipientSkip struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
+
+// sendFailure describes a recipient that couldn't be messaged, with the same error details as [apiError].
+type sendFailure struct {
+	User       string `json:"user"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	SlackError string `json:"slack_error,omitempty"`
+	Retryable  bool   `json:"retryable"`
+}
+
+func newSendFailure(user string, err error) *sendFailure {
+	e := errInternal(err)
+	return &sendFailure{
+		User:       user,
+		Code:       e.Code,
+		Message:    e.Message,
+		SlackError: e.SlackError,
+		Retryable:  e.Retryable,
+	}
+}
+
+type audienceResponse struct {
+	Expression string        `json:"expression"`
+	Count      int           `json:"count"`
+	Members    []*suggestion `json:"members"`
 }
 
 type suggestion struct {
 	Type     string        `json:"type"`
 	Label    string        `json:"label"`
 	Value    string        `json:"value"`
+	Email    string        `json:"email,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Disabled bool          `json:"disabled,omitempty"`
 	Children []*suggestion `json:"children,omitempty"`
 }
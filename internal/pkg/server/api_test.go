@@ -1,13 +1,16 @@
 package server
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/gouline/blaster/internal/pkg/slack"
 	"github.com/labstack/echo/v4"
+	slackapi "github.com/slack-go/slack"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,84 +28,166 @@ func TestHandleAPIUnauthenticated(t *testing.T) {
 				return r.Server.handleAPISend(r.Context)
 			},
 		},
+		{
+			func(r *requestTester) error {
+				return r.Server.handleAPIAudience(r.Context)
+			},
+		},
 	} {
-		r := newRequestTester(http.MethodGet, "/", nil)
-		if assert.NoError(t, test.f(r)) {
+		r := newRequestTester(http.MethodGet, "/api", nil)
+		if assert.NoError(t, r.Handle(func(echo.Context) error { return test.f(r) })) {
 			assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+			assert.Equal(t, errorCodeUnauthorized, r.APIError().Code)
 		}
 	}
 }
 
 func TestHandleAPISuggest(t *testing.T) {
-	r := newRequestTester(http.MethodGet, "/", nil)
+	r := newRequestTester(http.MethodGet, "/api", nil)
 	r.Authenticate("1", "")
 
-	if assert.NoError(t, r.Server.handleAPISuggest(r.Context)) {
+	if assert.NoError(t, r.Handle(r.Server.handleAPISuggest)) {
 		assert.Equal(t, http.StatusOK, r.Response.Code)
 	}
 }
 
+func TestHandleAPISuggestDisabled(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected int
+	}{
+		{query: "term=on-call", expected: 0},
+		{query: "term=on-call&include_disabled=true", expected: 1},
+	} {
+		r := newRequestTester(http.MethodGet, "/api?"+test.query, nil)
+		r.Authenticate("1", "")
+		destinations := testDestinations()
+		destinations[3].Disabled = true
+		r.Session.Destinations = destinations
+
+		if assert.NoError(t, r.Handle(r.Server.handleAPISuggest)) && assert.Equal(t, http.StatusOK, r.Response.Code) {
+			var suggestions []*suggestion
+			assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &suggestions))
+			assert.Len(t, suggestions, test.expected, test.query)
+		}
+	}
+}
+
 func TestHandleAPISuggestError(t *testing.T) {
-	r := newRequestTester(http.MethodGet, "/", nil)
+	r := newRequestTester(http.MethodGet, "/api", nil)
 	r.Authenticate("1", "")
 	r.Session.GetDestinationsError = errors.New("simulated")
 
-	if assert.NoError(t, r.Server.handleAPISuggest(r.Context)) {
+	if assert.NoError(t, r.Handle(r.Server.handleAPISuggest)) {
 		assert.Equal(t, http.StatusInternalServerError, r.Response.Code)
-		assert.Contains(t, r.Response.Body.String(), "simulated")
+		assert.Equal(t, errorCodeInternal, r.APIError().Code)
+		assert.NotContains(t, r.Response.Body.String(), "simulated")
 	}
 }
 
 func TestHandleAPISend(t *testing.T) {
-	r := newRequestTester(http.MethodPost, "/", strings.NewReader("{\"user\":\"1\",\"message\":\"test\",\"as_user\":true}"))
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader("{\"user\":\"U1\",\"message\":\"test\",\"as_user\":true}"))
 	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	r.Authenticate("1", "")
 
-	if assert.NoError(t, r.Server.handleAPISend(r.Context)) {
+	if assert.NoError(t, r.Handle(r.Server.handleAPISend)) {
 		assert.Equal(t, http.StatusOK, r.Response.Code)
 	}
 }
 
 func TestHandleAPISendBindError(t *testing.T) {
-	r := newRequestTester(http.MethodPost, "/", strings.NewReader("{\"user:\"1\",\"message\":\"test\",\"as_user\":true}"))
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader("{\"user:\"1\",\"message\":\"test\",\"as_user\":true}"))
 	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	r.Authenticate("1", "")
 
-	if assert.NoError(t, r.Server.handleAPISend(r.Context)) {
+	if assert.NoError(t, r.Handle(r.Server.handleAPISend)) {
 		assert.Equal(t, http.StatusBadRequest, r.Response.Code)
 	}
 }
 
 func TestHandleAPISendError(t *testing.T) {
-	r := newRequestTester(http.MethodPost, "/", nil)
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader("{\"user\":\"U1\",\"message\":\"test\"}"))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	r.Authenticate("1", "")
-	r.Session.PostMessageError = errors.New("simulated")
+	r.Session.PostMessageError = slackapi.SlackErrorResponse{Err: "channel_not_found"}
 
-	if assert.NoError(t, r.Server.handleAPISend(r.Context)) {
-		assert.Equal(t, http.StatusInternalServerError, r.Response.Code)
-		assert.Contains(t, r.Response.Body.String(), "simulated")
+	if assert.NoError(t, r.Handle(r.Server.handleAPISend)) {
+		assert.Equal(t, http.StatusBadGateway, r.Response.Code)
+		assert.Equal(t, &apiError{
+			Code:       errorCodeSlack,
+			Message:    "Slack API request failed",
+			SlackError: "channel_not_found",
+		}, r.APIError())
 	}
 }
 
-func TestSanitizeSearchTerm(t *testing.T) {
+func TestHandleAPISendAudience(t *testing.T) {
 	for _, test := range []struct {
-		s        string
-		expected string
+		body         string
+		expectedCode int
+		expectedSent []string
 	}{
 		{
-			s:        "test s!t@r#i$n^g [1,2)",
-			expected: "test string 12",
+			body:         `{"users":["U1","U3","U1"],"audience":"group:oncall","message":"test"}`,
+			expectedCode: http.StatusOK,
+			expectedSent: []string{"U1", "U3", "U2"},
+		},
+		{
+			body:         `{"audience":"title:*manager*","message":"test"}`,
+			expectedCode: http.StatusOK,
+			expectedSent: []string{"U3"},
 		},
 		{
-			s:        "тестовая с!т@р#о$к^а (9.0]",
-			expected: "тестовая строка 90",
+			body:         `{"audience":"group:","message":"test"}`,
+			expectedCode: http.StatusBadRequest,
 		},
 		{
-			s:        "测!试@字#符$串%5^6",
-			expected: "测试字符串56",
+			body:         `{"audience":"group:nobody","message":"test"}`,
+			expectedCode: http.StatusBadRequest,
 		},
 	} {
-		assert.Equal(t, test.expected, sanitizeSearchTerm(test.s))
+		r := newRequestTester(http.MethodPost, "/api", strings.NewReader(test.body))
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.Authenticate("1", "")
+		r.Session.Destinations = testDestinations()
+
+		if assert.NoError(t, r.Handle(r.Server.handleAPISend)) {
+			assert.Equal(t, test.expectedCode, r.Response.Code, "body: %s", test.body)
+			if test.expectedCode == http.StatusOK {
+				var response sendResponse
+				if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &response)) {
+					assert.Equal(t, test.expectedSent, response.Sent, "body: %s", test.body)
+				}
+			}
+		}
+	}
+}
+
+func TestHandleAPIAudience(t *testing.T) {
+	for _, test := range []struct {
+		expression    string
+		expectedCode  int
+		expectedCount int
+	}{
+		{expression: "group:oncall OR title:*manager*", expectedCode: http.StatusOK, expectedCount: 3},
+		{expression: "group:oncall AND NOT title:designer", expectedCode: http.StatusOK, expectedCount: 1},
+		{expression: "is:guest", expectedCode: http.StatusOK, expectedCount: 0},
+		{expression: "", expectedCode: http.StatusBadRequest},
+		{expression: "(group:oncall", expectedCode: http.StatusBadRequest},
+	} {
+		r := newRequestTester(http.MethodGet, "/api?expression="+url.QueryEscape(test.expression), nil)
+		r.Authenticate("1", "")
+		r.Session.Destinations = testDestinations()
+
+		if assert.NoError(t, r.Handle(r.Server.handleAPIAudience)) {
+			assert.Equal(t, test.expectedCode, r.Response.Code, "expression: %s", test.expression)
+			if test.expectedCode == http.StatusOK {
+				var response audienceResponse
+				if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &response)) {
+					assert.Equal(t, test.expectedCount, response.Count, "expression: %s", test.expression)
+				}
+			}
+		}
 	}
 }
 
@@ -224,10 +309,30 @@ func TestSuggestDestinations(t *testing.T) {
 			expectedIDs:         []string{"ug"},
 			expectedChildrenIDs: []string{"jd"},
 		},
+		{
+			term:                "knofler",
+			expectedIDs:         []string{"mk"},
+			expectedChildrenIDs: []string{},
+		},
+		{
+			term:                "m",
+			expectedIDs:         []string{"mg", "mark", "mk"},
+			expectedChildrenIDs: []string{},
+		},
+		{
+			term:                "",
+			expectedIDs:         []string{"mg", "mark", "mk", "ug"},
+			expectedChildrenIDs: []string{"jd"},
+		},
+		{
+			term:                " ",
+			expectedIDs:         []string{"mg", "mark", "mk", "ug"},
+			expectedChildrenIDs: []string{"jd"},
+		},
 	} {
 		actualValues := []string{}
 		actualChildren := []string{}
-		for _, actual := range suggestDestinations(test.term, destinations) {
+		for _, actual := range suggestDestinations(test.term, destinations, 10, 0) {
 			actualValues = append(actualValues, actual.Value)
 			for _, child := range actual.Children {
 				actualChildren = append(actualChildren, child.Value)
@@ -237,3 +342,99 @@ func TestSuggestDestinations(t *testing.T) {
 		assert.Equal(t, test.expectedChildrenIDs, actualChildren, "term: %s:", test.term)
 	}
 }
+
+func TestSuggestDestinationsRanking(t *testing.T) {
+	destinations := []*slack.Destination{
+		{Type: "user", Name: "Dejan", ID: "substring"},
+		{Type: "user", Name: "Bob", DisplayName: "Jan", ID: "word"},
+		{Type: "user", Name: "Jan", ID: "exact"},
+		{Type: "usergroup", Name: "Jan", ID: "group"},
+		{Type: "user", Name: "Janet", ID: "prefix"},
+	}
+
+	actualValues := []string{}
+	for _, actual := range suggestDestinations("jan", destinations, 10, 0) {
+		actualValues = append(actualValues, actual.Value)
+	}
+	assert.Equal(t, []string{"group", "exact", "prefix", "word", "substring"}, actualValues)
+
+	actualValues = []string{}
+	for _, actual := range suggestDestinations("jan", destinations, 2, 1) {
+		actualValues = append(actualValues, actual.Value)
+	}
+	assert.Equal(t, []string{"exact", "prefix"}, actualValues)
+
+	actualValues = []string{}
+	for _, actual := range suggestDestinations("", destinations, 3, 0) {
+		actualValues = append(actualValues, actual.Value)
+	}
+	assert.Equal(t, []string{"substring", "word", "exact"}, actualValues)
+}
+
+func TestHandleAPISuggestPagingErrors(t *testing.T) {
+	for _, target := range []string{
+		"/api?term=a&limit=0",
+		"/api?term=a&limit=1000",
+		"/api?term=a&limit=x",
+		"/api?term=a&offset=-1",
+	} {
+		r := newRequestTester(http.MethodGet, target, nil)
+		r.Authenticate("1", "")
+
+		if assert.NoError(t, r.Handle(r.Server.handleAPISuggest)) {
+			assert.Equal(t, http.StatusBadRequest, r.Response.Code, "target: %s", target)
+		}
+	}
+}
+
+func TestSuggestDestinationsFields(t *testing.T) {
+	destinations := []*slack.Destination{
+		{
+			Type:     "user",
+			Name:     "Jane Doe",
+			ID:       "jd",
+			Email:    "jane@example.com",
+			Title:    "Staff Engineer",
+			Timezone: "Europe/Berlin",
+			Fields:   map[string]string{"department": "Platform"},
+		},
+		{
+			Type:     "user",
+			Name:     "Mary Jane",
+			ID:       "mj",
+			Email:    "mary.jane@example.com",
+			Title:    "Engineering Manager",
+			Timezone: "America/New_York",
+			IsGuest:  true,
+		},
+		{
+			Type:    "user",
+			Name:    "John Smith",
+			ID:      "js",
+			Email:   "john@example.com",
+			Title:   "Designer",
+			IsAdmin: true,
+		},
+	}
+
+	for _, test := range []struct {
+		term        string
+		expectedIDs []string
+	}{
+		{term: "email:jane@", expectedIDs: []string{"jd", "mj"}},
+		{term: "title:engineer", expectedIDs: []string{"mj", "jd"}},
+		{term: "title:\"engineering manager\"", expectedIDs: []string{"mj"}},
+		{term: "jane title:engineer", expectedIDs: []string{"mj", "jd"}},
+		{term: "tz:america/", expectedIDs: []string{"mj"}},
+		{term: "department:platform", expectedIDs: []string{"jd"}},
+		{term: "is:guest", expectedIDs: []string{"mj"}},
+		{term: "is:admin", expectedIDs: []string{"js"}},
+		{term: "unknown:jane", expectedIDs: []string{}},
+	} {
+		actualValues := []string{}
+		for _, actual := range suggestDestinations(test.term, destinations, 10, 0) {
+			actualValues = append(actualValues, actual.Value)
+		}
+		assert.Equal(t, test.expectedIDs, actualValues, "term: %s", test.term)
+	}
+}
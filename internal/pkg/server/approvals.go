@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// handleAPIBlastApprove handles POST /api/blasts/:id/approve, letting the approver named by the sender
+// release a blast. Its waves are sent as they fall due, starting with recipients who'd have been sent to immediately.
+func (s *Server) handleAPIBlastApprove(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	target, err := s.blasts.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if target == nil || target.WorkspaceID != session.WorkspaceID() {
+		return errNotFound()
+	} else if target.Approval == nil {
+		return errBadRequest("blast doesn't need approval")
+	} else if target.Approval.Approver != session.UserID() {
+		return errForbidden("Only the approver can approve this blast")
+	}
+
+	target, err = s.blasts.Update(target.ID, func(blast *blast) error {
+		if blast.Status != blastStatusPendingApproval {
+			return errBadRequest("blast isn't pending approval")
+		}
+		now := time.Now().UTC()
+		blast.Status = blastStatusScheduled
+		blast.Approval.ApprovedAt = &now
+		return nil
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+	s.emitBlastEvent(eventBlastApproved, target)
+
+	s.dispatchWG.Add(1)
+	go func() {
+		defer s.dispatchWG.Done()
+		s.dispatchWaves(time.Now())
+	}()
+
+	return c.JSON(http.StatusOK, target)
+}
+
+// requestApproval asks blast's approver to review it on its page. Failures are only logged,
+// since the approver can still find it in history.
+func (s *Server) requestApproval(c echo.Context, session slack.Session, blast *blast, recipients int) {
+	text := fmt.Sprintf("<@%s> asked you to approve an announcement to %d recipients: <%s|Review>",
+		blast.CreatedBy, recipients, redirectURI(c, "/blasts/"+blast.ID))
+	if _, err := session.PostMessage(blast.Approval.Approver, slack.Message{Text: text}); err != nil {
+		s.config.Logger.Warn("failed to request approval", zap.String("blast", blast.ID), zap.Error(err))
+	}
+}
+
+// awaitsApprovalBy returns whether blast is pending approval by user.
+func (b *blast) awaitsApprovalBy(user string) bool {
+	return b.Status == blastStatusPendingApproval && b.Approval != nil && b.Approval.Approver == user
+}
+
+// approval holds blast until the approver approves it.
+type approval struct {
+	Approver   string     `json:"approver"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty"`
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlastApproval(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	mock := &mockSlackSession{ClientSession: &slack.ClientSession{Token: "xoxp-1", TeamID: "T1", User: "U9"}}
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+	assert.NoError(t, server.saveAuthorization(mock))
+
+	send := func(body string) *requestTester {
+		return serveAPI(t, server, mock, http.MethodPost, body, server.handleAPISend)
+	}
+	as := func(user string, handler echo.HandlerFunc, id string) *requestTester {
+		return serveAPI(t, server, newMockSession("T1", user), http.MethodPost, "", handler, "id", id)
+	}
+
+	r := send(`{"users":["U1"],"message":"Hi","approver":"U9"}`)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+
+	r = send(`{"users":["U1","U2"],"message":"Office closed","approver":"U5"}`)
+	var sent sendResponse
+	if !assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) || !assert.Len(t, sent.Waves, 1) {
+		return
+	}
+	assert.Empty(t, sent.Sent)
+	assert.Equal(t, []string{"U5"}, slices.Collect(maps.Keys(mock.Posted)), "only the approver is notified")
+	assert.Contains(t, mock.Posted["U5"], "/blasts/"+sent.Blast)
+
+	// Pending blasts aren't sent by the scheduler
+	server.dispatchWaves(sent.Waves[0].SendAt)
+	assert.Len(t, mock.Posted, 1)
+
+	r = as("U9", server.handleAPIBlastApprove, sent.Blast)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	r = as("U5", server.handleAPIBlastApprove, sent.Blast)
+	assert.Equal(t, http.StatusOK, r.Response.Code)
+	server.dispatchWG.Wait()
+	assert.Contains(t, mock.Posted["U1"], "Office closed")
+	assert.Contains(t, mock.Posted["U2"], "Office closed")
+
+	approved, err := server.blasts.Get(sent.Blast)
+	if assert.NoError(t, err) {
+		assert.Equal(t, blastStatusCompleted, approved.Status)
+		assert.NotNil(t, approved.Approval.ApprovedAt)
+	}
+	r = as("U5", server.handleAPIBlastApprove, sent.Blast)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+
+	// Approvers reject by canceling
+	r = send(`{"users":["U1"],"message":"Never mind","approver":"U5"}`)
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent))
+	r = serveAPI(t, server, newMockSession("T1", "U5"), http.MethodDelete, "", server.handleAPIBlastScheduleDelete, "id", sent.Blast)
+	var rejected blast
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &rejected)) {
+		assert.Equal(t, blastStatusCanceled, rejected.Status)
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gouline/blaster/internal/pkg/slack"
 	"github.com/labstack/echo/v4"
@@ -20,6 +21,9 @@ func (s *Server) middlewareAuth(next echo.HandlerFunc) echo.HandlerFunc {
 				return c.String(http.StatusUnauthorized, err.Error())
 			}
 			s.setSession(c, session)
+			if err := s.saveAuthorization(session); err != nil {
+				s.config.Logger.Warn("failed to save authorization", zap.String("user", session.UserID()), zap.Error(err))
+			}
 			return c.Redirect(http.StatusSeeOther, redirectURI)
 		}
 
@@ -50,6 +54,9 @@ func (s *Server) handleAuthLogout(c echo.Context) error {
 	return c.Redirect(http.StatusFound, redirectURI(c, c.Request().Referer()))
 }
 
+// newSession creates Slack session to restore stored credentials into, replaced in tests.
+var newSession = slack.NewSession
+
 // session retrieves current session from context or cookie.
 func (s *Server) session(c echo.Context) slack.Session {
 	session, ok := c.Get(cookieSession).(slack.Session)
@@ -63,6 +70,10 @@ func (s *Server) session(c echo.Context) slack.Session {
 				session.Unmarshal(cookieValue)
 			}
 		}
+		// Cookies from before the workspace was recorded can't be scoped to one, so they must sign in again
+		if session.IsAuthenticated() && session.WorkspaceID() == "" {
+			session.Reset()
+		}
 		c.Set(cookieSession, session)
 	}
 	return session
@@ -86,3 +97,36 @@ func (s *Server) setSession(c echo.Context, session slack.Session) {
 	c.SetCookie(cookie)
 	c.Set(cookieSession, session)
 }
+
+// saveAuthorization keeps user's latest Slack session, so that they can act from within Slack.
+func (s *Server) saveAuthorization(session slack.Session) error {
+	a := &authorization{
+		WorkspaceID: session.WorkspaceID(),
+		User:        session.UserID(),
+		Session:     session.Marshal(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	return s.authorizations.Put(exclusionKey(a.WorkspaceID, a.User), a)
+}
+
+// userSession restores Slack session that user last signed in with, nil if they never signed in.
+func (s *Server) userSession(workspaceID, user string) (slack.Session, error) {
+	a, err := s.authorizations.Get(exclusionKey(workspaceID, user))
+	if err != nil || a == nil {
+		return nil, err
+	}
+	session := newSession()
+	session.Unmarshal(a.Session)
+	return session, nil
+}
+
+// authorization is the Slack session a user signed in with, used for slash commands and interactions.
+type authorization struct {
+	WorkspaceID string `json:"workspace_id"`
+	User        string `json:"user"`
+
+	// Session is the marshalled Slack session
+	Session string `json:"session"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
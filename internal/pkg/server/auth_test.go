@@ -14,6 +14,8 @@ import (
 
 func TestMiddlewareAuth(t *testing.T) {
 	r := newRequestTester(http.MethodGet, "/?code=123", nil)
+	r.Session.TeamID = "T1"
+	r.Session.User = "U1"
 
 	err := r.Server.middlewareAuth(func(c echo.Context) error {
 		return nil
@@ -27,6 +29,11 @@ func TestMiddlewareAuth(t *testing.T) {
 			}
 		}
 	}
+
+	session, err := r.Server.userSession("T1", "U1")
+	if assert.NoError(t, err) && assert.NotNil(t, session) {
+		assert.True(t, session.IsAuthenticated())
+	}
 }
 
 func TestMiddlewareAuthError(t *testing.T) {
@@ -143,3 +150,22 @@ func TestSessionMalformedCookie(t *testing.T) {
 		assert.NotNil(t, r.Server.session(r.Context))
 	}
 }
+
+func TestSessionWithoutWorkspace(t *testing.T) {
+	for _, test := range []struct {
+		session       *slack.ClientSession
+		authenticated bool
+	}{
+		{session: &slack.ClientSession{Token: "xoxp-1", TeamID: "T1", User: "U1"}, authenticated: true},
+		{session: &slack.ClientSession{Token: "xoxp-1", User: "U1"}, authenticated: false},
+	} {
+		r := newRequestTester(http.MethodGet, "/api/lists", nil)
+		r.Context.Set(cookieSession, nil)
+		r.Request.AddCookie(&http.Cookie{Name: cookieSession, Value: url.QueryEscape(test.session.Marshal())})
+		assert.Equal(t, test.authenticated, r.Server.session(r.Context).IsAuthenticated())
+		if !test.authenticated {
+			assert.NoError(t, r.Handle(r.Server.handleAPIListsGet))
+			assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/gouline/blaster/internal/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	blastStatusPendingApproval = "pending_approval"
+	blastStatusScheduled       = "scheduled"
+	blastStatusSending         = "sending"
+	blastStatusCompleted       = "completed"
+	blastStatusFailed          = "failed"
+	blastStatusCanceled        = "canceled"
+)
+
+// handleAPIBlastsGet handles GET /api/blasts, listing workspace's blasts with the most recent first.
+func (s *Server) handleAPIBlastsGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	blasts, err := s.workspaceBlasts(session.WorkspaceID())
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, blasts)
+}
+
+// handleAPIBlastGet handles GET /api/blasts/:id.
+func (s *Server) handleAPIBlastGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	blast, err := s.blasts.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if blast == nil || blast.WorkspaceID != session.WorkspaceID() {
+		return errNotFound()
+	}
+
+	return c.JSON(http.StatusOK, blast)
+}
+
+// workspaceBlasts lists workspace's blasts with the most recent first.
+func (s *Server) workspaceBlasts(workspaceID string) ([]*blast, error) {
+	blasts, err := s.blasts.List(func(blast *blast) bool {
+		return blast.WorkspaceID == workspaceID
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(blasts, func(i, j int) bool {
+		return blasts[i].CreatedAt.After(blasts[j].CreatedAt)
+	})
+	return blasts, nil
+}
+
+// createBlast saves a blast that's about to be sent to workspace's history.
+func (s *Server) createBlast(c echo.Context, session slack.Session, request *sendRequest, override *policyOverride) (*blast, error) {
+	blast := &blast{
+		ID:          store.NewID(),
+		WorkspaceID: session.WorkspaceID(),
+		CreatedBy:   session.UserID(),
+		Message:     request.Message,
+		AsUser:      request.AsUser,
+		Acknowledge: request.Acknowledge,
+		Status:      blastStatusSending,
+		Sent:        []string{},
+		CreatedAt:   time.Now().UTC(),
+
+		ForwardChannel: request.ForwardReplies,
+		PolicyOverride: override,
+	}
+	if request.Schedule != nil {
+		blast.Status = blastStatusScheduled
+	}
+	if request.Approver != "" {
+		blast.Status = blastStatusPendingApproval
+		blast.Approval = &approval{Approver: request.Approver}
+	}
+	if request.remind != nil {
+		blast.ReminderOf = request.remind.ID
+		blast.Threads = map[string]string{}
+		for _, user := range request.Users {
+			if posted := request.remind.Messages[user]; posted != nil {
+				blast.Threads[user] = posted.Timestamp
+			}
+		}
+	}
+	if request.Poll != nil {
+		blast.Poll = &poll{Options: request.Poll.Options, MarkVoted: request.Poll.MarkVoted}
+	}
+	if token := contextToken(c); token != nil {
+		blast.TokenID = token.ID
+	}
+	if hook := contextHook(c); hook != nil {
+		blast.HookID = hook.ID
+	}
+	if err := s.blasts.Put(blast.ID, blast); err != nil {
+		return nil, err
+	}
+	s.emitBlastEvent(eventBlastCreated, blast)
+	return blast, nil
+}
+
+// completeBlast records outcome of sending blast, which failed if no recipient received it.
+func (s *Server) completeBlast(id string, response *sendResponse) (*blast, error) {
+	blast, err := s.blasts.Update(id, func(blast *blast) error {
+		now := time.Now().UTC()
+		blast.Sent = response.Sent
+		blast.Messages = response.messages
+		blast.Skipped = response.Skipped
+		blast.Failed = response.Failed
+		blast.Status = blastStatusCompleted
+		if len(response.Sent) == 0 && len(response.Failed) > 0 {
+			blast.Status = blastStatusFailed
+		}
+		blast.CompletedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if blast.Status == blastStatusFailed {
+		s.emitBlastEvent(eventBlastFailed, blast)
+	} else {
+		s.emitBlastEvent(eventBlastCompleted, blast)
+	}
+	return blast, nil
+}
+
+// blast is a message sent to a set of recipients, kept as workspace history.
+type blast struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	CreatedBy   string `json:"created_by"`
+
+	// TokenID is set when blast was sent through the API with a token
+	TokenID string `json:"token_id,omitempty"`
+
+	// HookID is set when blast was sent by an incoming hook
+	HookID string `json:"hook_id,omitempty"`
+
+	Message     string           `json:"message"`
+	AsUser      bool             `json:"as_user"`
+	Status      string           `json:"status"`
+	Sent        []string         `json:"sent"`
+	Skipped     []*recipientSkip `json:"skipped,omitempty"`
+	Failed      []*sendFailure   `json:"failed,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+
+	// Messages identifies the message posted to each recipient
+	Messages map[string]*slack.PostedMessage `json:"messages,omitempty"`
+
+	// Acknowledge asks recipients to acknowledge with a button, recording when they did in Acks
+	Acknowledge bool                 `json:"acknowledge,omitempty"`
+	Acks        map[string]time.Time `json:"acks,omitempty"`
+	RemindedAt  *time.Time           `json:"reminded_at,omitempty"`
+
+	// ReminderOf is the ID of the blast this one reminds recipients to acknowledge, replying to
+	// each recipient's message in Threads
+	ReminderOf string            `json:"reminder_of,omitempty"`
+	Threads    map[string]string `json:"threads,omitempty"`
+
+	Poll *poll `json:"poll,omitempty"`
+
+	// ForwardChannel is where replies are forwarded, in the thread started by ForwardThread
+	ForwardChannel string `json:"forward_channel,omitempty"`
+	ForwardThread  string `json:"forward_thread,omitempty"`
+
+	// Schedule is set when blast is sent later, in waves by recipients' timezone
+	Schedule *schedule `json:"schedule,omitempty"`
+
+	// PolicyOverride is set when blast was sent outside working hours despite workspace's policy
+	PolicyOverride *policyOverride `json:"policy_override,omitempty"`
+
+	// Approval is set when blast waits for someone else to approve it before it's sent
+	Approval *approval `json:"approval,omitempty"`
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleAPIBlasts(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	request := func(method, body, id, workspace string, handler echo.HandlerFunc) *requestTester {
+		return serveAPI(t, server, newMockSession(workspace, "U9"), method, body, handler, "id", id)
+	}
+
+	r := request(http.MethodPost, `{"users":["U1","U2"],"message":"Hello"}`, "", "T1", server.handleAPISend)
+	var sent sendResponse
+	if !assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) || !assert.NotEmpty(t, sent.Blast) {
+		return
+	}
+
+	r = request(http.MethodGet, "", "", "T1", server.handleAPIBlastsGet)
+	var blasts []*blast
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &blasts)) && assert.Len(t, blasts, 1) {
+		assert.Equal(t, sent.Blast, blasts[0].ID)
+		assert.Equal(t, "U9", blasts[0].CreatedBy)
+		assert.Equal(t, "Hello", blasts[0].Message)
+		assert.ElementsMatch(t, []string{"U1", "U2"}, blasts[0].Sent)
+	}
+
+	r = request(http.MethodGet, "", sent.Blast, "T1", server.handleAPIBlastGet)
+	assert.Equal(t, http.StatusOK, r.Response.Code)
+
+	r = request(http.MethodGet, "", "", "T2", server.handleAPIBlastsGet)
+	assert.Equal(t, "[]\n", r.Response.Body.String())
+
+	r = request(http.MethodGet, "", sent.Blast, "T2", server.handleAPIBlastGet)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// sectionMaxLength is the most characters Slack accepts in a section's text.
+const sectionMaxLength = 3000
+
+// block is a Block Kit layout block, limited to the sections and actions that blasts use.
+type block struct {
+	Type     string          `json:"type"`
+	BlockID  string          `json:"block_id,omitempty"`
+	Text     *textObject     `json:"text,omitempty"`
+	Elements []*blockElement `json:"elements,omitempty"`
+}
+
+// blockElement is an interactive element, such as a button.
+type blockElement struct {
+	Type     string      `json:"type"`
+	Text     *textObject `json:"text"`
+	ActionID string      `json:"action_id"`
+	Value    string      `json:"value,omitempty"`
+	Style    string      `json:"style,omitempty"`
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// sectionBlock creates section with Markdown text.
+func sectionBlock(text string) *block {
+	return &block{
+		Type: "section",
+		Text: &textObject{Type: "mrkdwn", Text: text},
+	}
+}
+
+// actionsBlock creates block of interactive elements.
+func actionsBlock(blockID string, elements ...*blockElement) *block {
+	return &block{
+		Type:     "actions",
+		BlockID:  blockID,
+		Elements: elements,
+	}
+}
+
+// button creates button sending value with action ID, where style is empty, "primary" or "danger".
+func button(actionID, text, value, style string) *blockElement {
+	return &blockElement{
+		Type:     "button",
+		Text:     &textObject{Type: "plain_text", Text: text},
+		ActionID: actionID,
+		Value:    value,
+		Style:    style,
+	}
+}
+
+// textBlocks splits Markdown text into sections, preferring to break between lines.
+func textBlocks(text string) []*block {
+	blocks := []*block{}
+	for utf8.RuneCountInString(text) > sectionMaxLength {
+		runes := []rune(text)
+		end := sectionMaxLength
+		for i := end - 1; i > sectionMaxLength/2; i-- {
+			if runes[i] == '\n' {
+				end = i + 1
+				break
+			}
+		}
+		blocks = append(blocks, sectionBlock(string(runes[:end])))
+		text = string(runes[end:])
+	}
+	if text != "" {
+		blocks = append(blocks, sectionBlock(text))
+	}
+	return blocks
+}
+
+// encodeBlocks encodes message text as sections followed by extra blocks, for posting to Slack.
+func encodeBlocks(text string, extra ...*block) json.RawMessage {
+	encoded, _ := json.Marshal(append(textBlocks(text), extra...))
+	return encoded
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// colorReg matches hex colours, such as "#336699" or "#369".
+var colorReg = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// defaultBranding is used for fields not set in configuration.
+var defaultBranding = Branding{
+	AppName: "Blaster",
+	LogoURL: "/static/img/favicon.png",
+}
+
+// Branding customizes how the app presents itself, so instances can be white-labelled.
+// Empty fields inherit from configured or default branding.
+type Branding struct {
+	AppName      string `json:"app_name"`
+	LogoURL      string `json:"logo_url"`
+	PrimaryColor string `json:"primary_color"`
+	FooterText   string `json:"footer_text"`
+	SupportURL   string `json:"support_url"`
+}
+
+// merge returns copy of b with non-empty fields replaced from override.
+func (b Branding) merge(override Branding) Branding {
+	for _, field := range []struct {
+		value    *string
+		override string
+	}{
+		{&b.AppName, override.AppName},
+		{&b.LogoURL, override.LogoURL},
+		{&b.PrimaryColor, override.PrimaryColor},
+		{&b.FooterText, override.FooterText},
+		{&b.SupportURL, override.SupportURL},
+	} {
+		if field.override != "" {
+			*field.value = field.override
+		}
+	}
+	return b
+}
+
+func (b Branding) validate() error {
+	if b.PrimaryColor != "" && !colorReg.MatchString(b.PrimaryColor) {
+		return fmt.Errorf("primary color must be hex, such as #336699")
+	}
+	if b.LogoURL != "" {
+		if _, err := url.Parse(b.LogoURL); err != nil {
+			return fmt.Errorf("invalid logo URL: %w", err)
+		}
+	}
+	if b.SupportURL != "" {
+		if u, err := url.Parse(b.SupportURL); err != nil || u.Scheme == "" {
+			return fmt.Errorf("support URL must be absolute")
+		}
+	}
+	return nil
+}
+
+// branding returns branding for workspace, applying its overrides over configured branding.
+func (s *Server) branding(workspaceID string) Branding {
+	branding := defaultBranding.merge(s.config.Branding)
+	if override, ok := s.config.WorkspaceBranding[workspaceID]; ok && workspaceID != "" {
+		branding = branding.merge(override)
+	}
+	return branding
+}
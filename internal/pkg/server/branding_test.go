@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrandingValidate(t *testing.T) {
+	for _, test := range []struct {
+		branding      Branding
+		errorContains string
+	}{
+		{branding: Branding{}},
+		{branding: Branding{PrimaryColor: "#336699", LogoURL: "/static/img/logo.png", SupportURL: "https://example.com/help"}},
+		{branding: Branding{PrimaryColor: "#369"}},
+		{branding: Branding{PrimaryColor: "red"}, errorContains: "primary color"},
+		{branding: Branding{PrimaryColor: "#33669"}, errorContains: "primary color"},
+		{branding: Branding{LogoURL: "%zz"}, errorContains: "invalid logo URL"},
+		{branding: Branding{SupportURL: "help"}, errorContains: "support URL"},
+	} {
+		err := test.branding.validate()
+		if test.errorContains == "" {
+			assert.NoError(t, err, "branding: %+v", test.branding)
+		} else {
+			assert.ErrorContains(t, err, test.errorContains, "branding: %+v", test.branding)
+		}
+	}
+}
+
+func TestServerBranding(t *testing.T) {
+	s := newRequestTester(http.MethodGet, "/", nil).Server
+	s.config.Branding = Branding{AppName: "Announcer", PrimaryColor: "#336699"}
+	s.config.WorkspaceBranding = map[string]Branding{
+		"T1": {AppName: "Sales Announcer", SupportURL: "https://example.com/help"},
+	}
+
+	assert.Equal(t, Branding{
+		AppName:      "Announcer",
+		LogoURL:      defaultBranding.LogoURL,
+		PrimaryColor: "#336699",
+	}, s.branding(""))
+	assert.Equal(t, s.branding(""), s.branding("T2"))
+	assert.Equal(t, Branding{
+		AppName:      "Sales Announcer",
+		LogoURL:      defaultBranding.LogoURL,
+		PrimaryColor: "#336699",
+		SupportURL:   "https://example.com/help",
+	}, s.branding("T1"))
+}
+
+func TestHandleIndexBranding(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/", nil)
+	r.Authenticate("1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Server.config.Branding = Branding{PrimaryColor: "#336699", FooterText: "Internal Communications"}
+	r.Server.config.WorkspaceBranding = map[string]Branding{
+		"T1": {AppName: "Sales Announcer", LogoURL: "/static/img/sales.png", SupportURL: "https://example.com/help"},
+	}
+
+	if assert.NoError(t, r.Server.handleIndex(r.Context)) {
+		body := r.Response.Body.String()
+		assert.Contains(t, body, "<title>Sales Announcer</title>")
+		assert.Contains(t, body, `src="/static/img/sales.png"`)
+		assert.Contains(t, body, "color: #336699")
+		assert.Contains(t, body, "Internal Communications")
+		assert.Contains(t, body, `href="https://example.com/help"`)
+		assert.NotContains(t, body, "Mike Gouline")
+	}
+}
+
+func TestServerInvalidBranding(t *testing.T) {
+	config := newRequestTester(http.MethodGet, "/", nil).Server.config
+	config.WorkspaceBranding = map[string]Branding{"T1": {PrimaryColor: "blue"}}
+
+	_, err := New(config)
+	assert.ErrorContains(t, err, "invalid branding for T1")
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/gouline/blaster/internal/pkg/store"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	commandBlast  = "/blast"
+	commandOptOut = "/blast-optout"
+
+	// draftMaxAge is how long a /blast confirmation can be sent from
+	draftMaxAge = time.Hour
+)
+
+// mentionReg matches a mention escaped by Slack, such as "<@U123|jane>" or "<!subteam^S123|@design>".
+var mentionReg = regexp.MustCompile(`^<(?:@|!subteam\^)([A-Z0-9]+)(?:\|[^>]*)?>$`)
+
+// reasonReg matches the option separating message from reason to send outside working hours.
+var reasonReg = regexp.MustCompile(`(?:^|\s)--reason(?:\s|$)`)
+
+// handleSlackCommand handles /slack/commands, dispatching slash commands by name.
+func (s *Server) handleSlackCommand(c echo.Context) error {
+	command, err := slack.ParseSlashCommand(c.Request(), s.config.SlackSigningSecret)
+	if err != nil {
+		s.config.Logger.Warn("rejected slash command", zap.Error(err))
+		return c.String(http.StatusUnauthorized, err.Error())
+	}
+
+	switch command.Command {
+	case commandBlast:
+		return s.handleCommandBlast(c, command)
+	case commandOptOut:
+		return s.handleCommandOptOut(c, command)
+	}
+	return c.JSON(http.StatusOK, ephemeral("Unknown command "+command.Command))
+}
+
+// handleCommandBlast saves a draft from "/blast @recipients message" and asks to confirm it with the recipient count.
+// Sending is done as the user's Slack session from when they last signed in, so they must have signed in before.
+func (s *Server) handleCommandBlast(c echo.Context, command slack.SlashCommand) error {
+	session, err := s.userSession(command.TeamID, command.UserID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	} else if session == nil {
+		return c.JSON(http.StatusOK, ephemeral(fmt.Sprintf("Sign in to <%s|%s> first, so that it can send on your behalf.",
+			redirectURI(c, "/"), s.branding(command.TeamID).AppName)))
+	}
+
+	destinations, err := session.GetDestinations()
+	if err != nil {
+		return c.JSON(http.StatusOK, ephemeral("Failed to load recipients: "+errInternal(err).Message))
+	}
+	recipients, message, reason, err := parseBlastCommand(command.Text, destinations)
+	if err != nil {
+		return c.JSON(http.StatusOK, ephemeral(err.Error()))
+	}
+
+	excluded, err := s.excludedUsers(command.TeamID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	count := 0
+	for _, user := range cachedAudience(recipients, destinations) {
+		if _, ok := excluded[user]; !ok {
+			count++
+		}
+	}
+	if count == 0 {
+		return c.JSON(http.StatusOK, ephemeral("No recipients to send to."))
+	}
+
+	if err := s.pruneDrafts(); err != nil {
+		s.config.Logger.Warn("failed to prune drafts", zap.Error(err))
+	}
+	draft := &commandDraft{
+		ID:          store.NewID(),
+		WorkspaceID: command.TeamID,
+		User:        command.UserID,
+		Recipients:  recipients,
+		Message:     message,
+		Reason:      reason,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.drafts.Put(draft.ID, draft); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	text := fmt.Sprintf("*Send to %d recipients?*\n%s", count, quote(message))
+	if reason != "" {
+		text += "\n_Reason to send outside working hours: " + reason + "_"
+	}
+	response := ephemeral(fmt.Sprintf("Send to %d recipients?", count))
+	response.Blocks = []*block{
+		sectionBlock(text),
+		actionsBlock("blast_confirm",
+			button(actionBlastSend, "Send", draft.ID, "primary"),
+			button(actionBlastCancel, "Cancel", draft.ID, ""),
+		),
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// handleCommandOptOut opts user out, or back in with "undo".
+func (s *Server) handleCommandOptOut(c echo.Context, command slack.SlashCommand) error {
+	if strings.EqualFold(strings.TrimSpace(command.Text), "undo") {
+		removed, err := s.optIn(command.TeamID, command.UserID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		if !removed {
+			return c.JSON(http.StatusOK, ephemeral("You haven't opted out, so there's nothing to undo."))
+		}
+		return c.JSON(http.StatusOK, ephemeral("You will receive announcements again."))
+	}
+
+	if err := s.optOut(command.TeamID, command.UserID); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, ephemeral("You will no longer receive announcements. Changed your mind? Use `"+commandOptOut+" undo`."))
+}
+
+// parseBlastCommand splits command text into leading recipients, message and optional trailing
+// "--reason why" to send outside working hours. Recipients are mentions escaped by Slack,
+// or "@handle" of a user group or user display name looked up in destinations.
+func parseBlastCommand(text string, destinations []*slack.Destination) ([]string, string, string, error) {
+	recipients := []string{}
+	rest := strings.TrimSpace(text)
+	for rest != "" {
+		word, remainder := rest, ""
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			word, remainder = rest[:i], rest[i:]
+		}
+		if match := mentionReg.FindStringSubmatch(word); match != nil {
+			recipients = append(recipients, match[1])
+		} else if handle, ok := strings.CutPrefix(word, "@"); ok {
+			dest := findHandle(handle, destinations)
+			if dest == nil {
+				return nil, "", "", fmt.Errorf("unknown recipient %s", word)
+			}
+			recipients = append(recipients, dest.ID)
+		} else {
+			break
+		}
+		rest = strings.TrimSpace(remainder)
+	}
+
+	reason := ""
+	if matches := reasonReg.FindAllStringIndex(rest, -1); len(matches) > 0 {
+		last := matches[len(matches)-1]
+		rest, reason = strings.TrimSpace(rest[:last[0]]), strings.TrimSpace(rest[last[1]:])
+		if reason == "" {
+			return nil, "", "", fmt.Errorf("usage: `%s @user-group @user message --reason why`", commandBlast)
+		}
+	}
+
+	if len(recipients) == 0 || rest == "" {
+		return nil, "", "", fmt.Errorf("usage: `%s @user-group @user message [--reason why]`", commandBlast)
+	}
+	return uniqueStrings(recipients), rest, reason, nil
+}
+
+// findHandle finds user group by handle, or otherwise user by display name.
+func findHandle(handle string, destinations []*slack.Destination) *slack.Destination {
+	var user *slack.Destination
+	for _, dest := range destinations {
+		if !strings.EqualFold(dest.DisplayName, handle) {
+			continue
+		}
+		if dest.Type == "usergroup" && !dest.Disabled {
+			return dest
+		}
+		if dest.Type == "user" && user == nil {
+			user = dest
+		}
+	}
+	return user
+}
+
+// cachedAudience expands recipient IDs into unique user IDs using cached destinations,
+// an estimate that [Server.resolveRecipients] makes exact when sending.
+func cachedAudience(recipients []string, destinations []*slack.Destination) []string {
+	lookup := map[string]*slack.Destination{}
+	for _, dest := range destinations {
+		lookup[dest.ID] = dest
+	}
+
+	users := []string{}
+	for _, id := range recipients {
+		dest, ok := lookup[id]
+		if !ok {
+			users = append(users, id)
+			continue
+		}
+		if dest.Type == "user" {
+			users = append(users, dest.ID)
+		}
+		for _, child := range dest.Children {
+			users = append(users, child.ID)
+		}
+	}
+	return uniqueStrings(users)
+}
+
+// pruneDrafts deletes drafts that can no longer be sent.
+func (s *Server) pruneDrafts() error {
+	expired, err := s.drafts.List(func(draft *commandDraft) bool {
+		return time.Since(draft.CreatedAt) > draftMaxAge
+	})
+	if err != nil {
+		return err
+	}
+	for _, draft := range expired {
+		if _, err := s.drafts.Delete(draft.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quote formats text as Markdown block quote.
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+// ephemeral creates slash command response only visible to the invoking user.
+func ephemeral(text string) *commandResponse {
+	return &commandResponse{
+		ResponseType: "ephemeral",
+		Text:         text,
+	}
+}
+
+// commandResponse is a message responding to a slash command or interaction.
+type commandResponse struct {
+	ResponseType string   `json:"response_type"`
+	Text         string   `json:"text"`
+	Blocks       []*block `json:"blocks,omitempty"`
+
+	// ReplaceOriginal replaces the message with the interaction, when sent to response URL
+	ReplaceOriginal bool `json:"replace_original,omitempty"`
+}
+
+// commandDraft is a blast composed with a slash command, waiting to be confirmed.
+type commandDraft struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	User        string    `json:"user"`
+	Recipients  []string  `json:"recipients"`
+	Message     string    `json:"message"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Reason sends to recipients outside working hours despite workspace's policy
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBlastCommand(t *testing.T) {
+	destinations := testDestinations()
+	destinations[0].DisplayName = "jane"
+
+	for _, test := range []struct {
+		text               string
+		expectedRecipients []string
+		expectedMessage    string
+		expectedReason     string
+		errorContains      string
+	}{
+		{text: "@oncall Meeting moved to 3pm", expectedRecipients: []string{"S1"}, expectedMessage: "Meeting moved to 3pm"},
+		{text: "@OnCall @jane  Meeting\nmoved", expectedRecipients: []string{"S1", "U1"}, expectedMessage: "Meeting\nmoved"},
+		{text: "<!subteam^S1|@oncall> <@U3|yuki>\tHello @jane", expectedRecipients: []string{"S1", "U3"}, expectedMessage: "Hello @jane"},
+		{text: "<@U2> <@U2> Hi", expectedRecipients: []string{"U2"}, expectedMessage: "Hi"},
+		{text: "@oncall Database is down --reason Paging on-call", expectedRecipients: []string{"S1"}, expectedMessage: "Database is down", expectedReason: "Paging on-call"},
+		{text: "@oncall Use --reason wisely\n--reason  Outage ", expectedRecipients: []string{"S1"}, expectedMessage: "Use --reason wisely", expectedReason: "Outage"},
+		{text: "@oncall Database is down --reason", errorContains: "--reason why"},
+		{text: "@oncall --reason Outage", errorContains: "usage"},
+		{text: "@nobody Hi", errorContains: "unknown recipient @nobody"},
+		{text: "@oncall", errorContains: "usage"},
+		{text: "Hello everyone", errorContains: "usage"},
+		{text: "", errorContains: "usage"},
+	} {
+		recipients, message, reason, err := parseBlastCommand(test.text, destinations)
+		if test.errorContains != "" {
+			assert.ErrorContains(t, err, test.errorContains, test.text)
+			continue
+		}
+		if assert.NoError(t, err, test.text) {
+			assert.Equal(t, test.expectedRecipients, recipients, test.text)
+			assert.Equal(t, test.expectedMessage, message, test.text)
+			assert.Equal(t, test.expectedReason, reason, test.text)
+		}
+	}
+}
+
+func TestHandleSlackCommandBlast(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/", nil).Server
+	mock := &mockSlackSession{
+		ClientSession:    &slack.ClientSession{Token: "xoxp-1", TeamID: "T1", User: "U9"},
+		Destinations:     testDestinations(),
+		UserGroupMembers: map[string][]string{"S1": {"U1", "U2"}},
+	}
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+
+	command := func(text string) *commandResponse {
+		body := url.Values{
+			"team_id": {"T1"},
+			"user_id": {"U9"},
+			"command": {commandBlast},
+			"text":    {text},
+		}.Encode()
+		r := newRequestTester(http.MethodPost, "/slack/commands", strings.NewReader(body))
+		r.Server = server
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		slack.SignRequest(r.Request, mockSigningSecret, body, time.Now())
+		assert.NoError(t, server.handleSlackCommand(r.Context))
+		var response commandResponse
+		assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &response), r.Response.Body.String())
+		return &response
+	}
+
+	responses := make(chan *commandResponse, 10)
+	responseURL := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response commandResponse
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &response)
+		responses <- &response
+	}))
+	defer responseURL.Close()
+
+	interact := func(user, actionID, value string) int {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type":         "block_actions",
+			"team":         map[string]string{"id": "T1"},
+			"user":         map[string]string{"id": user},
+			"response_url": responseURL.URL,
+			"actions":      []map[string]string{{"block_id": "blast_confirm", "action_id": actionID, "value": value}},
+		})
+		body := url.Values{"payload": {string(payload)}}.Encode()
+		r := newRequestTester(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+		r.Server = server
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		slack.SignRequest(r.Request, mockSigningSecret, body, time.Now())
+		assert.NoError(t, server.handleSlackInteraction(r.Context))
+		server.dispatchWG.Wait()
+		return r.Response.Code
+	}
+
+	response := command("@oncall Meeting moved")
+	assert.Equal(t, "ephemeral", response.ResponseType)
+	assert.Contains(t, response.Text, "Sign in")
+
+	assert.NoError(t, server.saveAuthorization(mock))
+	assert.Contains(t, command("@nobody Meeting moved").Text, "unknown recipient")
+	assert.Contains(t, command("Meeting moved").Text, "usage")
+
+	assert.NoError(t, server.optOut("T1", "U2"))
+	response = command("@oncall <@U3|yuki> Meeting moved to 3pm")
+	assert.Equal(t, "Send to 2 recipients?", response.Text)
+	if !assert.Len(t, response.Blocks, 2) || !assert.Len(t, response.Blocks[1].Elements, 2) {
+		return
+	}
+	assert.Contains(t, response.Blocks[0].Text.Text, "> Meeting moved to 3pm")
+	send := response.Blocks[1].Elements[0]
+	assert.Equal(t, actionBlastSend, send.ActionID)
+
+	// Only the author can send their draft
+	assert.Equal(t, http.StatusOK, interact("U1", actionBlastSend, send.Value))
+	assert.Contains(t, (<-responses).Text, "already sent")
+	assert.Empty(t, mock.Posted)
+
+	assert.Equal(t, http.StatusOK, interact("U9", actionBlastSend, send.Value))
+	response = <-responses
+	assert.True(t, response.ReplaceOriginal)
+	assert.Equal(t, "Sent to 2 recipients. Skipped 1.", response.Text)
+	assert.Len(t, mock.Posted, 2)
+	assert.True(t, strings.HasPrefix(mock.Posted["U3"], "Meeting moved to 3pm"))
+
+	assert.Equal(t, http.StatusOK, interact("U9", actionBlastSend, send.Value))
+	assert.Contains(t, (<-responses).Text, "already sent")
+
+	response = command("@oncall Never mind")
+	assert.Equal(t, http.StatusOK, interact("U9", actionBlastCancel, response.Blocks[1].Elements[1].Value))
+	assert.Equal(t, "Cancelled.", (<-responses).Text)
+	drafts, err := server.drafts.List(nil)
+	if assert.NoError(t, err) {
+		assert.Empty(t, drafts)
+	}
+
+	// Outside working hours, the policy asks for a reason in the command
+	mock.Posted = nil
+	workingDay := weekdays[time.Now().UTC().AddDate(0, 0, 3).Weekday()]
+	server.config.WorkspaceDeliveryPolicies = map[string]DeliveryPolicy{"T1": {Mode: policyModeWarn, WorkingDays: []string{workingDay}}}
+	response = command("@oncall Database is down")
+	assert.Equal(t, http.StatusOK, interact("U9", actionBlastSend, response.Blocks[1].Elements[0].Value))
+	assert.Contains(t, (<-responses).Text, "--reason why")
+	assert.Empty(t, mock.Posted)
+
+	response = command("@oncall Database is down --reason Paging on-call")
+	assert.Contains(t, response.Blocks[0].Text.Text, "Reason to send outside working hours: Paging on-call")
+	assert.Equal(t, http.StatusOK, interact("U9", actionBlastSend, response.Blocks[1].Elements[0].Value))
+	assert.Equal(t, "Sent to 1 recipients. Skipped 1.", (<-responses).Text)
+	assert.True(t, strings.HasPrefix(mock.Posted["U1"], "Database is down"))
+}
+
+func TestHandleSlackInteractionUnsigned(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/slack/interactions", strings.NewReader("payload=%7B%7D"))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	if assert.NoError(t, r.Server.handleSlackInteraction(r.Context)) {
+		assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+	}
+}
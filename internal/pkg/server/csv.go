@@ -0,0 +1,273 @@
+package server
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// csvMaxSize limits size of uploaded recipient files.
+	csvMaxSize = 5 << 20
+
+	// csvMaxLookups limits emails looked up in Slack per file, because users.lookupByEmail is rate-limited
+	// to about 50 requests per minute. Rows beyond it are reported as unmatched.
+	csvMaxLookups = 100
+
+	// csvLookupRetries is how many times a rate-limited lookup is retried, waiting as long as Slack asks.
+	csvLookupRetries = 3
+
+	// csvMaxRetryAfter caps each wait between retries, so that uploads don't hang on long rate limits.
+	csvMaxRetryAfter = 10 * time.Second
+)
+
+var (
+	// csvEmailColumns and csvIDColumns are accepted header names identifying recipients.
+	csvEmailColumns = []string{"email", "e_mail", "email_address"}
+	csvIDColumns    = []string{"id", "user", "user_id", "slack_id"}
+
+	// mergeVariableReg matches merge variables, such as "{{first_name}}".
+	mergeVariableReg = regexp.MustCompile(`\{\{\s*([\p{L}\p{N}_. -]+?)\s*\}\}`)
+
+	// variableSeparatorReg matches characters replaced with underscores in variable names.
+	variableSeparatorReg = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+	// csvRetrySleep waits between rate-limited lookups, replaced in tests.
+	csvRetrySleep = time.Sleep
+)
+
+// handleAPIRecipientsCSV handles /api/recipients/csv.
+func (s *Server) handleAPIRecipientsCSV(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return errBadRequest("missing file")
+	}
+	if header.Size > csvMaxSize {
+		return newAPIError(http.StatusRequestEntityTooLarge, errorCodeTooLarge, fmt.Sprintf("file must be under %d MB", csvMaxSize>>20))
+	}
+	file, err := header.Open()
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+	defer file.Close()
+
+	rows, err := readRecipientsCSV(file)
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	destinations, err := session.GetDestinations()
+	if err != nil {
+		return errInternal(err)
+	}
+
+	upload, err := matchRecipientsCSV(session, rows, destinations)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, upload)
+}
+
+// csvRow is a parsed data row with either email or ID identifying the recipient.
+type csvRow struct {
+	Line      int
+	Email     string
+	ID        string
+	Variables map[string]string
+}
+
+// readRecipientsCSV parses CSV with a header containing an email or ID column.
+// Remaining columns become merge variables keyed by normalized header name.
+func readRecipientsCSV(r io.Reader) ([]*csvRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("empty file")
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	emailColumn, idColumn := -1, -1
+	names := make([]string, len(header))
+	for i, column := range header {
+		names[i] = variableName(column)
+		switch {
+		case emailColumn < 0 && slices.Contains(csvEmailColumns, names[i]):
+			emailColumn = i
+		case idColumn < 0 && slices.Contains(csvIDColumns, names[i]):
+			idColumn = i
+		}
+	}
+	if emailColumn < 0 && idColumn < 0 {
+		return nil, fmt.Errorf("header must contain an email or ID column")
+	}
+
+	rows := []*csvRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := &csvRow{Line: line, Variables: map[string]string{}}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch {
+			case i >= len(names):
+				continue
+			case i == emailColumn:
+				row.Email = value
+			case i == idColumn:
+				row.ID = value
+			case names[i] != "":
+				row.Variables[names[i]] = value
+			}
+		}
+		if row.Email == "" && row.ID == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// matchRecipientsCSV resolves rows against directory, falling back to Slack lookup for emails.
+// Once lookups are capped or still rate-limited after retries, remaining unknown emails are reported as unmatched.
+func matchRecipientsCSV(session slack.Session, rows []*csvRow, destinations []*slack.Destination) (*csvUpload, error) {
+	byEmail := map[string]*slack.Destination{}
+	byID := map[string]*slack.Destination{}
+	for _, dest := range destinations {
+		if dest.Type != "user" {
+			continue
+		}
+		byID[dest.ID] = dest
+		if dest.Email != "" {
+			byEmail[strings.ToLower(dest.Email)] = dest
+		}
+	}
+
+	upload := &csvUpload{
+		Recipients: []*recipient{},
+		Unmatched:  []*csvUnmatched{},
+	}
+	lines := map[string]int{}
+	lookups := 0
+	skipReason := ""
+	for _, row := range rows {
+		var user *slack.Destination
+		value := row.ID
+		if row.ID != "" {
+			user = byID[row.ID]
+		} else {
+			value = row.Email
+			user = byEmail[strings.ToLower(row.Email)]
+			if user == nil && skipReason == "" && lookups >= csvMaxLookups {
+				skipReason = fmt.Sprintf("Skipped, only %d emails outside the directory are looked up per file", csvMaxLookups)
+			}
+			if user == nil && skipReason == "" {
+				lookups++
+				var err error
+				user, err = lookupUserByEmail(session, row.Email)
+				if apiErr := slack.ParseError(err); apiErr != nil && apiErr.RateLimited {
+					skipReason = "Skipped, Slack rate limit exceeded, try again later"
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			if user == nil && skipReason != "" {
+				upload.Unmatched = append(upload.Unmatched, &csvUnmatched{Line: row.Line, Value: value, Reason: skipReason})
+				continue
+			}
+		}
+
+		switch {
+		case user == nil:
+			upload.Unmatched = append(upload.Unmatched, &csvUnmatched{Line: row.Line, Value: value, Reason: "No matching user"})
+		case lines[user.ID] > 0:
+			upload.Unmatched = append(upload.Unmatched, &csvUnmatched{Line: row.Line, Value: value,
+				Reason: fmt.Sprintf("Duplicate of line %d", lines[user.ID])})
+		default:
+			lines[user.ID] = row.Line
+			upload.Recipients = append(upload.Recipients, &recipient{
+				User:      user.ID,
+				Label:     suggestionLabel(user.Name, user.DisplayName),
+				Variables: row.Variables,
+			})
+		}
+	}
+	upload.Count = len(upload.Recipients)
+
+	return upload, nil
+}
+
+// lookupUserByEmail looks up user by email in Slack, retrying when rate-limited.
+func lookupUserByEmail(session slack.Session, email string) (*slack.Destination, error) {
+	for attempt := 0; ; attempt++ {
+		user, err := session.LookupUserByEmail(email)
+		apiErr := slack.ParseError(err)
+		if apiErr == nil || !apiErr.RateLimited || attempt >= csvLookupRetries {
+			return user, err
+		}
+		csvRetrySleep(min(max(apiErr.RetryAfter, time.Second), csvMaxRetryAfter))
+	}
+}
+
+// mergeVariables replaces "{{name}}" placeholders in message with recipient's variables.
+// Unknown placeholders are left intact.
+func mergeVariables(message string, variables map[string]string) string {
+	if len(variables) == 0 {
+		return message
+	}
+	return mergeVariableReg.ReplaceAllStringFunc(message, func(match string) string {
+		name := variableName(mergeVariableReg.FindStringSubmatch(match)[1])
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// variableName normalizes column header or placeholder, such as "First Name" into "first_name".
+func variableName(s string) string {
+	return strings.Trim(variableSeparatorReg.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
+
+// recipient is a user with optional merge variables, accepted by /api/send.
+type recipient struct {
+	User      string            `json:"user"`
+	Label     string            `json:"label,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+type csvUpload struct {
+	Recipients []*recipient    `json:"recipients"`
+	Count      int             `json:"count"`
+	Unmatched  []*csvUnmatched `json:"unmatched"`
+}
+
+type csvUnmatched struct {
+	Line   int    `json:"line"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
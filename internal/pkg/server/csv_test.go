@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	slackapi "github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRecipientsCSV(t *testing.T) {
+	rows, err := readRecipientsCSV(strings.NewReader("E-mail,First Name,Team\njane@example.com, Jane ,Platform\n,,\nJOHN@example.com,John\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []*csvRow{
+		{Line: 2, Email: "jane@example.com", Variables: map[string]string{"first_name": "Jane", "team": "Platform"}},
+		{Line: 4, Email: "JOHN@example.com", Variables: map[string]string{"first_name": "John"}},
+	}, rows)
+
+	rows, err = readRecipientsCSV(strings.NewReader("Slack ID,Name\nU1,Jane\n"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*csvRow{{Line: 2, ID: "U1", Variables: map[string]string{"name": "Jane"}}}, rows)
+	}
+}
+
+func TestReadRecipientsCSVErrors(t *testing.T) {
+	for _, test := range []struct {
+		s             string
+		errorContains string
+	}{
+		{s: "", errorContains: "empty file"},
+		{s: "name,team\nJane,Platform\n", errorContains: "email or ID column"},
+		{s: "email\n\"jane@example.com\n", errorContains: "invalid CSV"},
+	} {
+		_, err := readRecipientsCSV(strings.NewReader(test.s))
+		assert.ErrorContains(t, err, test.errorContains, "s: %s", test.s)
+	}
+}
+
+func TestMatchRecipientsCSV(t *testing.T) {
+	session := &mockSlackSession{
+		ClientSession: &slack.ClientSession{},
+		UsersByEmail: map[string]*slack.Destination{
+			"new@example.com": {Type: "user", ID: "U9", Name: "New Starter"},
+		},
+	}
+	destinations := []*slack.Destination{
+		{Type: "user", ID: "U1", Name: "Jane Doe", Email: "Jane@Example.com"},
+		{Type: "user", ID: "U2", Name: "John Smith", Email: "john@example.com"},
+		{Type: "usergroup", ID: "S1", Name: "Managers"},
+	}
+	rows := []*csvRow{
+		{Line: 2, Email: "jane@example.com", Variables: map[string]string{"first_name": "Jane"}},
+		{Line: 3, Email: "new@example.com"},
+		{Line: 4, Email: "nobody@example.com"},
+		{Line: 5, ID: "U2"},
+		{Line: 6, ID: "U1"},
+		{Line: 7, ID: "S1"},
+	}
+
+	upload, err := matchRecipientsCSV(session, rows, destinations)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, upload.Count)
+	assert.Equal(t, []*recipient{
+		{User: "U1", Label: "Jane Doe", Variables: map[string]string{"first_name": "Jane"}},
+		{User: "U9", Label: "New Starter"},
+		{User: "U2", Label: "John Smith"},
+	}, upload.Recipients)
+	assert.Equal(t, []*csvUnmatched{
+		{Line: 4, Value: "nobody@example.com", Reason: "No matching user"},
+		{Line: 6, Value: "U1", Reason: "Duplicate of line 2"},
+		{Line: 7, Value: "S1", Reason: "No matching user"},
+	}, upload.Unmatched)
+}
+
+func TestMatchRecipientsCSVRateLimited(t *testing.T) {
+	var waits []time.Duration
+	defer func(original func(time.Duration)) { csvRetrySleep = original }(csvRetrySleep)
+	csvRetrySleep = func(d time.Duration) { waits = append(waits, d) }
+
+	rateLimited := func(retryAfter time.Duration) error {
+		return fmt.Errorf("failed to look up user: %w", &slackapi.RateLimitedError{RetryAfter: retryAfter})
+	}
+	session := &mockSlackSession{
+		ClientSession: &slack.ClientSession{},
+		UsersByEmail: map[string]*slack.Destination{
+			"new@example.com":   {Type: "user", ID: "U9", Name: "New Starter"},
+			"later@example.com": {Type: "user", ID: "U8", Name: "Later Starter"},
+		},
+		LookupErrors: []error{rateLimited(2 * time.Second), rateLimited(time.Minute)},
+	}
+	destinations := []*slack.Destination{{Type: "user", ID: "U1", Name: "Jane Doe", Email: "jane@example.com"}}
+	rows := []*csvRow{
+		{Line: 2, Email: "new@example.com"},
+		{Line: 3, Email: "jane@example.com"},
+	}
+
+	// Lookups are retried after waiting as long as Slack asks, up to a limit
+	upload, err := matchRecipientsCSV(session, rows, destinations)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, upload.Count)
+		assert.Empty(t, upload.Unmatched)
+		assert.Equal(t, []time.Duration{2 * time.Second, csvMaxRetryAfter}, waits)
+	}
+
+	// Still rate-limited after retries, remaining lookups are skipped, but directory matches aren't
+	rows = append(rows, &csvRow{Line: 4, Email: "later@example.com"})
+	for i := 0; i <= csvLookupRetries; i++ {
+		session.LookupErrors = append(session.LookupErrors, rateLimited(time.Second))
+	}
+	upload, err = matchRecipientsCSV(session, rows, destinations)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, upload.Count)
+		assert.Equal(t, "U1", upload.Recipients[0].User)
+		if assert.Len(t, upload.Unmatched, 2) {
+			assert.Equal(t, 2, upload.Unmatched[0].Line)
+			assert.Contains(t, upload.Unmatched[0].Reason, "rate limit")
+			assert.Equal(t, 4, upload.Unmatched[1].Line)
+		}
+	}
+
+	// Lookups are capped per file
+	rows = []*csvRow{}
+	for i := 0; i <= csvMaxLookups; i++ {
+		rows = append(rows, &csvRow{Line: i + 2, Email: fmt.Sprintf("user%d@example.com", i)})
+	}
+	upload, err = matchRecipientsCSV(session, rows, destinations)
+	if assert.NoError(t, err) && assert.Len(t, upload.Unmatched, csvMaxLookups+1) {
+		assert.Equal(t, "No matching user", upload.Unmatched[csvMaxLookups-1].Reason)
+		assert.Contains(t, upload.Unmatched[csvMaxLookups].Reason, "looked up per file")
+	}
+
+	session.LookupErrors = []error{fmt.Errorf("failed to look up user: %w", slackapi.SlackErrorResponse{Err: "invalid_auth"})}
+	_, err = matchRecipientsCSV(session, rows[:1], destinations)
+	assert.ErrorContains(t, err, "invalid_auth")
+}
+
+func TestMergeVariables(t *testing.T) {
+	variables := map[string]string{"first_name": "Jane", "team": "Platform"}
+	for _, test := range []struct {
+		message  string
+		expected string
+	}{
+		{message: "Hi {{first_name}}!", expected: "Hi Jane!"},
+		{message: "Hi {{ First Name }} from {{team}}", expected: "Hi Jane from Platform"},
+		{message: "Hi {{unknown}}", expected: "Hi {{unknown}}"},
+		{message: "No variables", expected: "No variables"},
+	} {
+		assert.Equal(t, test.expected, mergeVariables(test.message, variables))
+	}
+	assert.Equal(t, "Hi {{first_name}}", mergeVariables("Hi {{first_name}}", nil))
+}
+
+func TestHandleAPIRecipientsCSV(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "recipients.csv")
+	part.Write([]byte("id,first_name\nU1,Jane\nU404,Nobody\n"))
+	writer.Close()
+
+	r := newRequestTester(http.MethodPost, "/api", body)
+	r.Request.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	r.Authenticate("1", "")
+	r.Session.Destinations = testDestinations()
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPIRecipientsCSV)) && assert.Equal(t, http.StatusOK, r.Response.Code) {
+		var upload csvUpload
+		if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &upload)) {
+			assert.Equal(t, 1, upload.Count)
+			assert.Len(t, upload.Unmatched, 1)
+		}
+	}
+}
+
+func TestHandleAPIRecipientsCSVMissingFile(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api", nil)
+	r.Authenticate("1", "")
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPIRecipientsCSV)) {
+		assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+	}
+}
+
+func TestHandleAPISendVariables(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader(
+		`{"recipients":[{"user":"U1","variables":{"first_name":"Jane"}},{"user":"U2"}],"message":"Hi {{first_name}}"}`))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Authenticate("1", "")
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPISend)) && assert.Equal(t, http.StatusOK, r.Response.Code) {
+		assert.Len(t, r.Session.Posted, 2)
+		assert.True(t, strings.HasPrefix(r.Session.Posted["U1"], "Hi Jane\n"))
+		assert.True(t, strings.HasPrefix(r.Session.Posted["U2"], "Hi {{first_name}}\n"))
+	}
+}
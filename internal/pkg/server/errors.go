@@ -0,0 +1,182 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Error codes returned in [apiError], stable for clients to handle programmatically.
+const (
+	errorCodeBadRequest   = "bad_request"
+	errorCodeUnauthorized = "unauthorized"
+	errorCodeForbidden    = "forbidden"
+	errorCodeNotFound     = "not_found"
+	errorCodeOutsideHours = "outside_working_hours"
+	errorCodeTooLarge     = "payload_too_large"
+	errorCodeRateLimited  = "rate_limited"
+	errorCodeSlack        = "slack_error"
+	errorCodeInternal     = "internal_error"
+)
+
+// apiError is the error envelope returned by all /api endpoints.
+type apiError struct {
+	Status     int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	SlackError string `json:"slack_error,omitempty"`
+	Retryable  bool   `json:"retryable"`
+	RequestID  string `json:"request_id,omitempty"`
+
+	// RetryAfter is seconds until a rate-limited request can be retried, sent as Retry-After header
+	RetryAfter int `json:"-"`
+
+	// err is the underlying cause, logged but never returned to clients
+	err error
+}
+
+func newAPIError(status int, code, message string) *apiError {
+	return &apiError{Status: status, Code: code, Message: message}
+}
+
+func (e *apiError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.err)
+	}
+	return e.Message
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+func errBadRequest(message string) *apiError {
+	return newAPIError(http.StatusBadRequest, errorCodeBadRequest, message)
+}
+
+func errUnauthorized() *apiError {
+	return newAPIError(http.StatusUnauthorized, errorCodeUnauthorized, "Not authorized against Slack")
+}
+
+func errForbidden(message string) *apiError {
+	return newAPIError(http.StatusForbidden, errorCodeForbidden, message)
+}
+
+func errNotFound() *apiError {
+	return newAPIError(http.StatusNotFound, errorCodeNotFound, "Not found")
+}
+
+// errOutsideWorkingHours asks sender for a reason to reach recipients outside working hours.
+func errOutsideWorkingHours(count int) *apiError {
+	return newAPIError(http.StatusConflict, errorCodeOutsideHours,
+		fmt.Sprintf("%d recipients are outside working hours, a reason is required to send anyway", count))
+}
+
+// errInternal converts unexpected error, distinguishing failed Slack API requests from internal ones.
+// Raw error messages aren't exposed, only Slack error codes.
+func errInternal(err error) *apiError {
+	var e *apiError
+	if errors.As(err, &e) {
+		return e
+	}
+
+	if slackErr := slack.ParseError(err); slackErr != nil {
+		e = newAPIError(http.StatusBadGateway, errorCodeSlack, "Slack API request failed")
+		if slackErr.RateLimited {
+			e = newAPIError(http.StatusTooManyRequests, errorCodeRateLimited, "Slack API rate limit exceeded")
+			e.RetryAfter = int(math.Ceil(slackErr.RetryAfter.Seconds()))
+		}
+		e.SlackError = slackErr.Code
+		e.Retryable = slackErr.Retryable
+	} else {
+		e = newAPIError(http.StatusInternalServerError, errorCodeInternal, "Internal server error")
+	}
+	e.err = err
+	return e
+}
+
+// handleHTTPError implements [echo.HTTPErrorHandler], responding to /api requests with [apiError]
+// and leaving other pages to the default handler.
+func (s *Server) handleHTTPError(err error, c echo.Context) {
+	if !isAPIRequest(c) {
+		// Pages respond with the status only, so internal errors are logged rather than shown
+		var e *apiError
+		if errors.As(err, &e) && e.Status >= http.StatusInternalServerError {
+			s.config.Logger.Error("request failed", zap.String("path", c.Request().URL.Path), zap.Error(err))
+		}
+		s.echo.DefaultHTTPErrorHandler(err, c)
+		return
+	}
+	if c.Response().Committed {
+		return
+	}
+
+	var e *apiError
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &e):
+		clone := *e
+		e = &clone
+	case errors.As(err, &httpErr):
+		e = newAPIError(httpErr.Code, errorCode(httpErr.Code), http.StatusText(httpErr.Code))
+		if message, ok := httpErr.Message.(string); ok {
+			e.Message = message
+		}
+		e.Retryable = httpErr.Code == http.StatusTooManyRequests
+	default:
+		e = errInternal(err)
+	}
+	e.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+	if e.Status >= http.StatusInternalServerError {
+		s.config.Logger.Error("API request failed",
+			zap.String("request_id", e.RequestID),
+			zap.String("code", e.Code),
+			zap.Error(err),
+		)
+	}
+
+	if e.RetryAfter > 0 {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(e.Status)
+	} else {
+		err = c.JSON(e.Status, e)
+	}
+	if err != nil {
+		s.config.Logger.Error("failed to send error", zap.Error(err))
+	}
+}
+
+// errorCode returns error code for HTTP status.
+func errorCode(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return errorCodeUnauthorized
+	case status == http.StatusForbidden:
+		return errorCodeForbidden
+	case status == http.StatusNotFound:
+		return errorCodeNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return errorCodeTooLarge
+	case status == http.StatusTooManyRequests:
+		return errorCodeRateLimited
+	case status >= http.StatusInternalServerError:
+		return errorCodeInternal
+	}
+	return errorCodeBadRequest
+}
+
+// isAPIRequest checks whether request is for an /api endpoint or an incoming hook.
+func isAPIRequest(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return path == "/api" || strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/hooks/")
+}
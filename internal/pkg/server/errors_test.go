@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	slackapi "github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrInternal(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected *apiError
+	}{
+		{
+			err:      errors.New("simulated"),
+			expected: &apiError{Status: http.StatusInternalServerError, Code: errorCodeInternal, Message: "Internal server error"},
+		},
+		{
+			err: fmt.Errorf("failed to expand S1: %w", slackapi.SlackErrorResponse{Err: "service_unavailable"}),
+			expected: &apiError{Status: http.StatusBadGateway, Code: errorCodeSlack, Message: "Slack API request failed",
+				SlackError: "service_unavailable", Retryable: true},
+		},
+		{
+			err: &slackapi.RateLimitedError{RetryAfter: 1500 * time.Millisecond},
+			expected: &apiError{Status: http.StatusTooManyRequests, Code: errorCodeRateLimited, Message: "Slack API rate limit exceeded",
+				SlackError: "ratelimited", Retryable: true, RetryAfter: 2},
+		},
+		{
+			err:      errBadRequest("missing name"),
+			expected: &apiError{Status: http.StatusBadRequest, Code: errorCodeBadRequest, Message: "missing name"},
+		},
+	} {
+		actual := errInternal(test.err)
+		actual.err = nil
+		assert.Equal(t, test.expected, actual, "error: %s", test.err)
+	}
+}
+
+func TestHandleHTTPError(t *testing.T) {
+	for _, test := range []struct {
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedHeader string
+	}{
+		{
+			err:            echo.NewHTTPError(http.StatusBadRequest, "Syntax error"),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   errorCodeBadRequest,
+		},
+		{
+			err:            echo.ErrMethodNotAllowed,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   errorCodeBadRequest,
+		},
+		{
+			err:            &slackapi.RateLimitedError{RetryAfter: 30 * time.Second},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCode:   errorCodeRateLimited,
+			expectedHeader: "30",
+		},
+	} {
+		r := newRequestTester(http.MethodGet, "/api/suggest", nil)
+		r.Server.handleHTTPError(test.err, r.Context)
+
+		assert.Equal(t, test.expectedStatus, r.Response.Code, "error: %s", test.err)
+		assert.Equal(t, test.expectedCode, r.APIError().Code, "error: %s", test.err)
+		assert.Equal(t, test.expectedHeader, r.Response.Header().Get("Retry-After"), "error: %s", test.err)
+	}
+}
+
+func TestAPIErrorEnvelope(t *testing.T) {
+	s := newRequestTester(http.MethodGet, "/", nil).Server
+
+	request := httptest.NewRequest(http.MethodGet, "/api/missing", nil)
+	response := httptest.NewRecorder()
+	s.echo.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	r := &requestTester{Response: response}
+	if e := r.APIError(); assert.NotNil(t, e) {
+		assert.Equal(t, errorCodeNotFound, e.Code)
+		assert.NotEmpty(t, e.RequestID)
+		assert.Equal(t, response.Header().Get(echo.HeaderXRequestID), e.RequestID)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/api/suggest", nil)
+	response = httptest.NewRecorder()
+	s.echo.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	r = &requestTester{Response: response}
+	if e := r.APIError(); assert.NotNil(t, e) {
+		assert.Equal(t, errorCodeUnauthorized, e.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	response = httptest.NewRecorder()
+	s.echo.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Contains(t, response.Body.String(), "<body>")
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	exclusionSourceAdmin  = "admin"
+	exclusionSourceOptOut = "opt-out"
+
+	// optOutTokenLifetime limits how long opt-out links in sent messages work, after which
+	// recipients can still opt out with the slash command
+	optOutTokenLifetime = 90 * 24 * time.Hour
+)
+
+// handleAPIExclusionsGet handles GET /api/exclusions.
+func (s *Server) handleAPIExclusionsGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	exclusions, err := s.exclusions.List(func(exclusion *exclusion) bool {
+		return exclusion.WorkspaceID == session.WorkspaceID()
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, exclusions)
+}
+
+// handleAPIExclusionsCreate handles POST /api/exclusions.
+func (s *Server) handleAPIExclusionsCreate(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request exclusionRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if request.User == "" {
+		return errBadRequest("missing user")
+	}
+	if err := checkAdmin(session); err != nil {
+		return err
+	}
+
+	existing, err := s.exclusions.Get(exclusionKey(session.WorkspaceID(), request.User))
+	if err != nil {
+		return errInternal(err)
+	} else if err := checkOptOutOwner(session, existing); err != nil {
+		return err
+	}
+
+	exclusion := &exclusion{
+		WorkspaceID: session.WorkspaceID(),
+		User:        request.User,
+		Reason:      strings.TrimSpace(request.Reason),
+		Source:      exclusionSourceAdmin,
+		CreatedBy:   session.UserID(),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.exclusions.Put(exclusion.key(), exclusion); err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusCreated, exclusion)
+}
+
+// handleAPIExclusionDelete handles DELETE /api/exclusions/:user.
+func (s *Server) handleAPIExclusionDelete(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	key := exclusionKey(session.WorkspaceID(), c.Param("user"))
+	existing, err := s.exclusions.Get(key)
+	if err != nil {
+		return errInternal(err)
+	} else if existing == nil {
+		return errNotFound()
+	} else if err := checkOptOutOwner(session, existing); err != nil {
+		return err
+	} else if existing.Source != exclusionSourceOptOut {
+		if err := checkAdmin(session); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := s.exclusions.Delete(key)
+	if err != nil {
+		return errInternal(err)
+	} else if !deleted {
+		return errNotFound()
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkOptOutOwner rejects changing exclusion if it's another user's own opt-out, which only they can undo.
+func checkOptOutOwner(session slack.Session, exclusion *exclusion) error {
+	if exclusion != nil && exclusion.Source == exclusionSourceOptOut && exclusion.User != session.UserID() {
+		return errForbidden("User opted out themselves, so only they can change it")
+	}
+	return nil
+}
+
+// checkAdmin rejects changing exclusions unless session's user is currently a workspace admin or owner.
+func checkAdmin(session slack.Session) error {
+	users, err := session.GetActiveUsers([]string{session.UserID()})
+	if err != nil {
+		return errInternal(err)
+	}
+	if len(users) == 0 || !users[0].IsAdmin {
+		return errForbidden("Only workspace admins can exclude users")
+	}
+	return nil
+}
+
+// handleOptOut handles GET /optout, confirming before opting out.
+func (s *Server) handleOptOut(c echo.Context) error {
+	t := s.translator(c)
+	workspaceID, _, err := s.parseOptOutToken(c.QueryParam("token"))
+	if err != nil {
+		return s.renderOptOut(c, http.StatusBadRequest, "", t("optout.invalid_title"), t("optout.invalid_message"), "")
+	}
+
+	return s.renderOptOut(c, http.StatusOK, workspaceID, t("optout.title"),
+		t("optout.message", s.branding(workspaceID).AppName), c.QueryParam("token"))
+}
+
+// handleOptOutConfirm handles POST /optout.
+func (s *Server) handleOptOutConfirm(c echo.Context) error {
+	t := s.translator(c)
+	workspaceID, user, err := s.parseOptOutToken(c.FormValue("token"))
+	if err != nil {
+		return s.renderOptOut(c, http.StatusBadRequest, "", t("optout.invalid_title"), t("optout.invalid_message"), "")
+	}
+
+	if err := s.optOut(workspaceID, user); err != nil {
+		return errInternal(err)
+	}
+
+	return s.renderOptOut(c, http.StatusOK, workspaceID, t("optout.done_title"), t("optout.done_message"), "")
+}
+
+// renderOptOut renders opt-out page branded for workspace, since recipients aren't signed in.
+func (s *Server) renderOptOut(c echo.Context, code int, workspaceID, title, message, token string) error {
+	return c.Render(code, "optout.html", s.baseData(c, map[string]interface{}{
+		"title":    title,
+		"message":  message,
+		"token":    token,
+		"branding": s.branding(workspaceID),
+	}))
+}
+
+// optOut records user's request to stop receiving blasts in workspace.
+func (s *Server) optOut(workspaceID, user string) error {
+	exclusion := &exclusion{
+		WorkspaceID: workspaceID,
+		User:        user,
+		Reason:      "Opted out",
+		Source:      exclusionSourceOptOut,
+		CreatedBy:   user,
+		CreatedAt:   time.Now().UTC(),
+	}
+	return s.exclusions.Put(exclusion.key(), exclusion)
+}
+
+// optIn removes user's own opt-out in workspace, leaving exclusions set by others intact.
+func (s *Server) optIn(workspaceID, user string) (bool, error) {
+	existing, err := s.exclusions.Get(exclusionKey(workspaceID, user))
+	if err != nil || existing == nil || existing.Source != exclusionSourceOptOut {
+		return false, err
+	}
+	return s.exclusions.Delete(existing.key())
+}
+
+// excludedUsers returns reasons keyed by user ID for global and workspace exclusions.
+func (s *Server) excludedUsers(workspaceID string) (map[string]string, error) {
+	excluded := map[string]string{}
+	for _, user := range s.config.GlobalExclusions {
+		excluded[user] = "Excluded globally"
+	}
+
+	exclusions, err := s.exclusions.List(func(exclusion *exclusion) bool {
+		return exclusion.WorkspaceID == "" || exclusion.WorkspaceID == workspaceID
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, exclusion := range exclusions {
+		reason := exclusion.Reason
+		if reason == "" {
+			reason = "Excluded"
+		}
+		excluded[exclusion.User] = reason
+	}
+	return excluded, nil
+}
+
+// optOutURL creates a signed link that lets user opt out without authenticating, until it expires.
+func (s *Server) optOutURL(c echo.Context, workspaceID, user string) string {
+	token := s.optOutToken(workspaceID, user, time.Now().Add(optOutTokenLifetime))
+	return redirectURI(c, "/optout") + "?token=" + url.QueryEscape(token)
+}
+
+// optOutToken signs workspace and user IDs with expiry time.
+func (s *Server) optOutToken(workspaceID, user string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(workspaceID + ":" + user + ":" + strconv.FormatInt(expires.Unix(), 10)))
+	return payload + "." + s.sign("optout", payload)
+}
+
+// parseOptOutToken verifies token created by [Server.optOutToken] hasn't expired and returns workspace and user IDs.
+func (s *Server) parseOptOutToken(token string) (string, string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(s.sign("optout", payload))) {
+		return "", "", fmt.Errorf("invalid signature")
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid payload: %w", err)
+	}
+	parts := strings.Split(string(decoded), ":")
+	if len(parts) != 3 || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid payload")
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid expiry: %w", err)
+	} else if time.Now().After(time.Unix(expires, 0)) {
+		return "", "", fmt.Errorf("expired")
+	}
+	return parts[0], parts[1], nil
+}
+
+// sign creates HMAC-SHA256 signature of value, scoped by purpose, using client secret.
+func (s *Server) sign(purpose, value string) string {
+	h := hmac.New(sha256.New, []byte(s.config.SlackClientSecret))
+	h.Write([]byte(purpose + ":" + value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// optOutFooter formats message footer with opt-out link for user.
+func (s *Server) optOutFooter(c echo.Context, session slack.Session, user string) string {
+	return fmt.Sprintf("\n\n_Don't want these announcements? <%s|Opt out>_", s.optOutURL(c, session.WorkspaceID(), user))
+}
+
+// exclusion prevents a user from receiving blasts, globally when WorkspaceID is empty.
+type exclusion struct {
+	WorkspaceID string    `json:"workspace_id"`
+	User        string    `json:"user"`
+	Reason      string    `json:"reason"`
+	Source      string    `json:"source"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (e *exclusion) key() string {
+	return exclusionKey(e.WorkspaceID, e.User)
+}
+
+func exclusionKey(workspaceID, user string) string {
+	return workspaceID + "/" + user
+}
+
+type exclusionRequest struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleAPIExclusions(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	sender := "U9"
+	request := func(method, body, user string, handler echo.HandlerFunc) *requestTester {
+		session := newMockSession("T1", sender)
+		session.Admins = []string{"U9"}
+		return serveAPI(t, server, session, method, body, handler, "user", user)
+	}
+
+	r := request(http.MethodPost, `{"user":""}`, "", server.handleAPIExclusionsCreate)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+
+	// Only admins can exclude others
+	sender = "U8"
+	r = request(http.MethodPost, `{"user":"U1","reason":"On leave"}`, "", server.handleAPIExclusionsCreate)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	sender = "U9"
+
+	r = request(http.MethodPost, `{"user":"U1","reason":"On leave"}`, "", server.handleAPIExclusionsCreate)
+	assert.Equal(t, http.StatusCreated, r.Response.Code)
+
+	r = request(http.MethodGet, "", "", server.handleAPIExclusionsGet)
+	var exclusions []*exclusion
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &exclusions)) && assert.Len(t, exclusions, 1) {
+		assert.Equal(t, "U1", exclusions[0].User)
+		assert.Equal(t, exclusionSourceAdmin, exclusions[0].Source)
+	}
+
+	sender = "U8"
+	r = request(http.MethodDelete, "", "U1", server.handleAPIExclusionDelete)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	sender = "U9"
+	r = request(http.MethodDelete, "", "U1", server.handleAPIExclusionDelete)
+	assert.Equal(t, http.StatusNoContent, r.Response.Code)
+
+	r = request(http.MethodDelete, "", "U1", server.handleAPIExclusionDelete)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+
+	// Opt-outs can only be undone, or replaced, by the user who opted out
+	assert.NoError(t, server.optOut("T1", "U2"))
+	r = request(http.MethodPost, `{"user":"U2","reason":"On leave"}`, "", server.handleAPIExclusionsCreate)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	r = request(http.MethodDelete, "", "U2", server.handleAPIExclusionDelete)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	sender = "U2"
+	r = request(http.MethodDelete, "", "U2", server.handleAPIExclusionDelete)
+	assert.Equal(t, http.StatusNoContent, r.Response.Code)
+}
+
+func TestHandleAPISendExclusions(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader(`{"audience":"everyone","message":"test"}`))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Authenticate("1", "")
+	r.Session.TeamID = "T1"
+	r.Session.Destinations = testDestinations()
+	r.Server.config.GlobalExclusions = []string{"U3"}
+	assert.NoError(t, r.Server.optOut("T1", "U2"))
+	assert.NoError(t, r.Server.optOut("T2", "U1"))
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPISend)) && assert.Equal(t, http.StatusOK, r.Response.Code) {
+		var response sendResponse
+		if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &response)) {
+			assert.Equal(t, []string{"U1"}, response.Sent)
+			assert.Equal(t, []*recipientSkip{
+				{User: "U2", Reason: "Opted out"},
+				{User: "U3", Reason: "Excluded globally"},
+			}, response.Skipped)
+		}
+	}
+}
+
+func TestOptOutToken(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "https://example.com/", nil)
+
+	optOutURL, err := url.Parse(r.Server.optOutURL(r.Context, "T1", "U1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "https://example.com/optout", optOutURL.Scheme+"://"+optOutURL.Host+optOutURL.Path)
+
+	token := optOutURL.Query().Get("token")
+	workspaceID, user, err := r.Server.parseOptOutToken(token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "T1", workspaceID)
+		assert.Equal(t, "U1", user)
+	}
+
+	expired := r.Server.optOutToken("T1", "U1", time.Now().Add(-time.Minute))
+	for _, invalid := range []string{"", "abc", token + "0", "e30." + strings.SplitN(token, ".", 2)[1], expired} {
+		_, _, err := r.Server.parseOptOutToken(invalid)
+		assert.Error(t, err, "token: %s", invalid)
+	}
+}
+
+func TestHandleOptOut(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/", nil).Server
+	optOutURL, _ := url.Parse(server.optOutURL(newRequestTester(http.MethodGet, "/", nil).Context, "T1", "U1"))
+	token := optOutURL.Query().Get("token")
+
+	r := newRequestTester(http.MethodGet, "/optout?token="+url.QueryEscape(token), nil)
+	r.Server = server
+	if assert.NoError(t, server.handleOptOut(r.Context)) {
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+		assert.Contains(t, r.Response.Body.String(), "<form")
+	}
+
+	r = newRequestTester(http.MethodGet, "/optout?token=invalid", nil)
+	if assert.NoError(t, server.handleOptOut(r.Context)) {
+		assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+	}
+
+	r = newRequestTester(http.MethodPost, "/optout", strings.NewReader("token="+url.QueryEscape(token)))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if assert.NoError(t, server.handleOptOutConfirm(r.Context)) {
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+	}
+
+	excluded, err := server.excludedUsers("T1")
+	if assert.NoError(t, err) {
+		assert.Contains(t, excluded, "U1")
+	}
+}
+
+func TestHandleSlackCommandOptOut(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/", nil).Server
+	command := func(text string) *requestTester {
+		body := url.Values{
+			"team_id": {"T1"},
+			"user_id": {"U1"},
+			"command": {commandOptOut},
+			"text":    {text},
+		}.Encode()
+		r := newRequestTester(http.MethodPost, "/slack/commands", strings.NewReader(body))
+		r.Server = server
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		slack.SignRequest(r.Request, mockSigningSecret, body, time.Now())
+		assert.NoError(t, server.handleSlackCommand(r.Context))
+		return r
+	}
+
+	r := command("undo")
+	assert.Contains(t, r.Response.Body.String(), "nothing to undo")
+
+	r = command("")
+	assert.Contains(t, r.Response.Body.String(), "ephemeral")
+	excluded, _ := server.excludedUsers("T1")
+	assert.Contains(t, excluded, "U1")
+
+	r = command("undo")
+	assert.Contains(t, r.Response.Body.String(), "receive announcements again")
+	excluded, _ = server.excludedUsers("T1")
+	assert.NotContains(t, excluded, "U1")
+}
+
+func TestHandleSlackCommandUnsigned(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/slack/commands", strings.NewReader("command=%2Fblast-optout"))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	if assert.NoError(t, r.Server.handleSlackCommand(r.Context)) {
+		assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+	}
+}
@@ -0,0 +1,429 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/audience"
+	"github.com/gouline/blaster/internal/pkg/store"
+	"github.com/gouline/blaster/internal/pkg/webhook"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	hookMaxBodySize = 1 << 20
+
+	// hookSignatureTolerance is how far request timestamps may be from now, limiting replays
+	hookSignatureTolerance = 5 * time.Minute
+
+	// hookRateWindow is the period that RateLimit applies to
+	hookRateWindow = time.Hour
+
+	hookDefaultRateLimit     = 10
+	hookMaxRateLimit         = 100
+	hookDefaultDedupeMinutes = 10
+	hookMaxDedupeMinutes     = 24 * 60
+
+	contextKeyHook = "incoming_hook"
+)
+
+// errHookDuplicate aborts recording a trigger that repeats a recent one.
+var errHookDuplicate = errors.New("duplicate trigger")
+
+// handleHook handles POST /hooks/:id, sending the hook's message rendered from the signed JSON body
+// to its audience. Repeated alerts within the dedupe window are acknowledged without sending.
+func (s *Server) handleHook(c echo.Context) error {
+	hook, err := s.hooks.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if hook == nil {
+		return errNotFound()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, hookMaxBodySize+1))
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+	if len(body) > hookMaxBodySize {
+		return newAPIError(http.StatusRequestEntityTooLarge, errorCodeTooLarge, fmt.Sprintf("body must be under %d MB", hookMaxBodySize>>20))
+	}
+
+	request := c.Request()
+	if err := webhook.Verify(hook.Secret, request.Header.Get(webhook.HeaderSignature), request.Header.Get(webhook.HeaderTimestamp), body, hookSignatureTolerance); err != nil {
+		return newAPIError(http.StatusUnauthorized, errorCodeUnauthorized, "Invalid signature: "+err.Error())
+	}
+
+	variables, err := hookVariables(body)
+	if err != nil {
+		return errBadRequest(err.Error())
+	}
+	message := strings.TrimSpace(mergeVariables(hook.Template, variables))
+	if message == "" {
+		return errBadRequest("message is empty")
+	}
+
+	// Sending uses the creator's latest sign-in rather than a copy taken when the hook was created
+	session, err := s.userSession(hook.WorkspaceID, hook.CreatedBy)
+	if err != nil {
+		return errInternal(err)
+	} else if session == nil {
+		return errForbidden("Hook's creator has to sign in again")
+	}
+
+	key := message
+	if value, ok := variables[variableName(hook.DedupeKey)]; ok && hook.DedupeKey != "" {
+		key = value
+	}
+	trigger, err := s.recordHookTrigger(hook.ID, key)
+	if errors.Is(err, errHookDuplicate) {
+		return c.JSON(http.StatusOK, &hookResponse{
+			sendResponse: sendResponse{Blast: trigger.Blast, Sent: []string{}},
+			Duplicate:    true,
+		})
+	} else if err != nil {
+		return errInternal(err)
+	}
+
+	c.Set(contextKeyHook, hook)
+
+	response, err := s.sendBlast(c, session, &sendRequest{
+		Audience: hook.Audience,
+		Message:  message,
+
+		OverrideReason: hook.OverrideReason,
+	})
+	if _, updateErr := s.hooks.Update(hook.ID, func(hook *incomingHook) error {
+		for i, t := range hook.Triggers {
+			if t.At.Equal(trigger.At) && t.Key == trigger.Key {
+				if err != nil {
+					// Failed sends don't count, so that the sender's retry goes through
+					hook.Triggers = append(hook.Triggers[:i], hook.Triggers[i+1:]...)
+				} else {
+					hook.Triggers[i].Blast = response.Blast
+				}
+				break
+			}
+		}
+		return nil
+	}); updateErr != nil && err == nil {
+		err = errInternal(updateErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &hookResponse{sendResponse: *response})
+}
+
+// recordHookTrigger records hook being triggered for dedupe key, unless it exceeds rate limit
+// or repeats a recent trigger, in which case the earlier trigger is returned with [errHookDuplicate].
+func (s *Server) recordHookTrigger(id, key string) (*hookTrigger, error) {
+	now := time.Now().UTC()
+	h := sha256.Sum256([]byte(key))
+	trigger := &hookTrigger{At: now, Key: hex.EncodeToString(h[:])}
+
+	_, err := s.hooks.Update(id, func(hook *incomingHook) error {
+		dedupeWindow := time.Duration(hook.DedupeMinutes) * time.Minute
+		recent := []hookTrigger{}
+		for _, t := range hook.Triggers {
+			if now.Sub(t.At) < max(dedupeWindow, hookRateWindow) {
+				recent = append(recent, t)
+			}
+		}
+		hook.Triggers = recent
+
+		count := 0
+		for _, t := range recent {
+			if t.Key == trigger.Key && now.Sub(t.At) < dedupeWindow {
+				*trigger = t
+				return errHookDuplicate
+			}
+			if now.Sub(t.At) < hookRateWindow {
+				count++
+			}
+		}
+		if count >= hook.RateLimit {
+			e := newAPIError(http.StatusTooManyRequests, errorCodeRateLimited, fmt.Sprintf("Hook is limited to %d blasts per hour", hook.RateLimit))
+			e.Retryable = true
+			for _, t := range recent {
+				if now.Sub(t.At) < hookRateWindow {
+					e.RetryAfter = int(math.Ceil(hookRateWindow.Seconds() - now.Sub(t.At).Seconds()))
+					break
+				}
+			}
+			return e
+		}
+
+		hook.Triggers = append(hook.Triggers, *trigger)
+		return nil
+	})
+	return trigger, err
+}
+
+// hookVariables flattens JSON object into merge variables named by path, so that {"alert": {"name": "x"}}
+// fills "{{alert.name}}".
+func hookVariables(body []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("body must be a JSON object")
+	}
+
+	variables := map[string]string{}
+	var flatten func(path string, value interface{})
+	flatten = func(path string, value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, child := range value {
+				flatten(path+"."+key, child)
+			}
+		case []interface{}:
+			for i, child := range value {
+				flatten(path+"."+strconv.Itoa(i), child)
+			}
+		case nil:
+			variables[variableName(path)] = ""
+		default:
+			variables[variableName(path)] = fmt.Sprint(value)
+		}
+	}
+	flatten("", object)
+	return variables, nil
+}
+
+// contextHook returns incoming hook that triggered the request, nil otherwise.
+func contextHook(c echo.Context) *incomingHook {
+	hook, _ := c.Get(contextKeyHook).(*incomingHook)
+	return hook
+}
+
+// handleAPIHooksGet handles GET /api/hooks.
+func (s *Server) handleAPIHooksGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	hooks, err := s.hooks.List(func(hook *incomingHook) bool {
+		return hook.WorkspaceID == session.WorkspaceID()
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+
+	infos := []*hookInfo{}
+	for _, hook := range hooks {
+		infos = append(infos, hook.info(c))
+	}
+	return c.JSON(http.StatusOK, infos)
+}
+
+// handleAPIHooksCreate handles POST /api/hooks, returning the signing secret only in this response.
+// Hook sends as its creator, with the Slack authorization they last signed in with.
+func (s *Server) handleAPIHooksCreate(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request hookRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if err := request.validate(); err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	secret, err := newTokenSecret()
+	if err != nil {
+		return errInternal(err)
+	}
+	hook := &incomingHook{
+		ID:             store.NewID(),
+		WorkspaceID:    session.WorkspaceID(),
+		Name:           request.Name,
+		Audience:       request.Audience,
+		Template:       request.Template,
+		DedupeKey:      request.DedupeKey,
+		DedupeMinutes:  request.DedupeMinutes,
+		RateLimit:      request.RateLimit,
+		OverrideReason: request.OverrideReason,
+		Secret:         secret,
+		Triggers:       []hookTrigger{},
+		CreatedBy:      session.UserID(),
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := s.saveAuthorization(session); err != nil {
+		return errInternal(err)
+	}
+	if err := s.hooks.Put(hook.ID, hook); err != nil {
+		return errInternal(err)
+	}
+
+	info := hook.info(c)
+	info.Secret = hook.Secret
+	return c.JSON(http.StatusCreated, info)
+}
+
+// handleAPIHookDelete handles DELETE /api/hooks/:id.
+func (s *Server) handleAPIHookDelete(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	hook, err := s.hooks.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if hook == nil || hook.WorkspaceID != session.WorkspaceID() {
+		return errNotFound()
+	} else if hook.CreatedBy != session.UserID() {
+		return errForbidden("Only the creator can delete this hook")
+	}
+
+	if _, err := s.hooks.Delete(hook.ID); err != nil {
+		return errInternal(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// incomingHook sends a fixed audience a message rendered from signed requests, such as monitoring alerts.
+type incomingHook struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	Name        string `json:"name"`
+	Audience    string `json:"audience"`
+
+	// Template is the message with merge variables filled from the request body
+	Template string `json:"template"`
+
+	// DedupeKey is the body field identifying repeats of an alert, the rendered message if unset
+	DedupeKey     string `json:"dedupe_key,omitempty"`
+	DedupeMinutes int    `json:"dedupe_minutes"`
+
+	// RateLimit is the maximum number of blasts per hour
+	RateLimit int `json:"rate_limit"`
+
+	// OverrideReason sends to recipients outside working hours despite workspace's policy, for alerts that can't wait
+	OverrideReason string `json:"override_reason,omitempty"`
+
+	Secret string `json:"secret"`
+
+	// Triggers are the recent triggers, kept for rate limiting and dedupe
+	Triggers []hookTrigger `json:"triggers"`
+
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *incomingHook) info(c echo.Context) *hookInfo {
+	info := &hookInfo{
+		ID:             h.ID,
+		URL:            redirectURI(c, "/hooks/"+h.ID),
+		Name:           h.Name,
+		Audience:       h.Audience,
+		Template:       h.Template,
+		DedupeKey:      h.DedupeKey,
+		DedupeMinutes:  h.DedupeMinutes,
+		RateLimit:      h.RateLimit,
+		OverrideReason: h.OverrideReason,
+		CreatedBy:      h.CreatedBy,
+		CreatedAt:      h.CreatedAt,
+	}
+	if len(h.Triggers) > 0 {
+		info.LastTriggeredAt = &h.Triggers[len(h.Triggers)-1].At
+	}
+	return info
+}
+
+// hookTrigger is a request that sent, or is sending, a blast.
+type hookTrigger struct {
+	At time.Time `json:"at"`
+
+	// Key is SHA-256 of the dedupe key
+	Key string `json:"key"`
+
+	Blast string `json:"blast,omitempty"`
+}
+
+// hookInfo describes hook without its secret.
+type hookInfo struct {
+	ID              string     `json:"id"`
+	URL             string     `json:"url"`
+	Name            string     `json:"name"`
+	Audience        string     `json:"audience"`
+	Template        string     `json:"template"`
+	DedupeKey       string     `json:"dedupe_key,omitempty"`
+	DedupeMinutes   int        `json:"dedupe_minutes"`
+	RateLimit       int        `json:"rate_limit"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	OverrideReason  string     `json:"override_reason,omitempty"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+
+	// Secret signs requests, only set when hook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type hookRequest struct {
+	Name          string `json:"name"`
+	Audience      string `json:"audience"`
+	Template      string `json:"template"`
+	DedupeKey     string `json:"dedupe_key"`
+	DedupeMinutes int    `json:"dedupe_minutes"`
+	RateLimit     int    `json:"rate_limit"`
+
+	// OverrideReason sends alerts to recipients outside working hours, recorded with each blast
+	OverrideReason string `json:"override_reason,omitempty"`
+}
+
+func (r *hookRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+
+	expr, err := audience.Parse(r.Audience)
+	if err != nil {
+		return fmt.Errorf("invalid audience: %w", err)
+	}
+	r.Audience = expr.String()
+
+	if strings.TrimSpace(r.Template) == "" {
+		return fmt.Errorf("missing template")
+	}
+	r.DedupeKey = strings.TrimSpace(r.DedupeKey)
+	r.OverrideReason = strings.TrimSpace(r.OverrideReason)
+
+	if r.DedupeMinutes == 0 {
+		r.DedupeMinutes = hookDefaultDedupeMinutes
+	}
+	if r.DedupeMinutes < 1 || r.DedupeMinutes > hookMaxDedupeMinutes {
+		return fmt.Errorf("dedupe window must be between 1 and %d minutes", hookMaxDedupeMinutes)
+	}
+	if r.RateLimit == 0 {
+		r.RateLimit = hookDefaultRateLimit
+	}
+	if r.RateLimit < 1 || r.RateLimit > hookMaxRateLimit {
+		return fmt.Errorf("rate limit must be between 1 and %d per hour", hookMaxRateLimit)
+	}
+	return nil
+}
+
+// hookResponse is the send response, or the earlier blast when the request is a duplicate.
+type hookResponse struct {
+	sendResponse
+	Duplicate bool `json:"duplicate,omitempty"`
+}
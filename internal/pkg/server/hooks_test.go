@@ -0,0 +1,244 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/gouline/blaster/internal/pkg/webhook"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookVariables(t *testing.T) {
+	variables, err := hookVariables([]byte(`{"alert":{"name":"Disk full","value":92.5,"hosts":["db1","db2"],"resolved":false,"runbook":null},"Severity Level":"high"}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{
+			"alert_name":     "Disk full",
+			"alert_value":    "92.5",
+			"alert_hosts_0":  "db1",
+			"alert_hosts_1":  "db2",
+			"alert_resolved": "false",
+			"alert_runbook":  "",
+			"severity_level": "high",
+		}, variables)
+		assert.Equal(t, "Disk full at 92.5% on db1 ({{missing}})", mergeVariables("{{alert.name}} at {{ alert.value }}% on {{alert.hosts.0}} ({{missing}})", variables))
+	}
+
+	for _, body := range []string{``, `[1,2]`, `"text"`, `{"a":`} {
+		_, err := hookVariables([]byte(body))
+		assert.ErrorContains(t, err, "JSON object", body)
+	}
+}
+
+func TestHookRequestValidate(t *testing.T) {
+	for _, test := range []struct {
+		request       hookRequest
+		errorContains string
+	}{
+		{request: hookRequest{Name: " Alerts ", Audience: "group:oncall", Template: "{{alert}}"}},
+		{request: hookRequest{Audience: "group:oncall", Template: "{{alert}}"}, errorContains: "missing name"},
+		{request: hookRequest{Name: "Alerts", Audience: "group:", Template: "{{alert}}"}, errorContains: "invalid audience"},
+		{request: hookRequest{Name: "Alerts", Audience: "group:oncall", Template: " "}, errorContains: "missing template"},
+		{request: hookRequest{Name: "Alerts", Audience: "group:oncall", Template: "x", DedupeMinutes: -1}, errorContains: "dedupe window"},
+		{request: hookRequest{Name: "Alerts", Audience: "group:oncall", Template: "x", RateLimit: 1000}, errorContains: "rate limit"},
+	} {
+		err := test.request.validate()
+		if test.errorContains == "" {
+			assert.NoError(t, err)
+			assert.Equal(t, "Alerts", test.request.Name)
+			assert.Equal(t, hookDefaultDedupeMinutes, test.request.DedupeMinutes)
+			assert.Equal(t, hookDefaultRateLimit, test.request.RateLimit)
+		} else {
+			assert.ErrorContains(t, err, test.errorContains)
+		}
+	}
+}
+
+func TestHooksLifecycle(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api/hooks", strings.NewReader(
+		`{"name":"Alerts","audience":"group:oncall","template":"*{{alert.name}}* is {{status}}","dedupe_key":"alert.id","rate_limit":2}`,
+	))
+	server := r.Server
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.User = "U9"
+	r.Session.Destinations = testDestinations()
+	mock := r.Session
+
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+
+	assert.NoError(t, r.Handle(server.handleAPIHooksCreate))
+	if !assert.Equal(t, http.StatusCreated, r.Response.Code, r.Response.Body.String()) {
+		return
+	}
+	var created hookInfo
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, "http://example.com/hooks/"+created.ID, created.URL)
+
+	trigger := func(body, secret string, timestamp time.Time) *requestTester {
+		tr := newRequestTester(http.MethodPost, "/hooks/"+created.ID, strings.NewReader(body))
+		tr.Server = server
+		tr.Context = server.echo.NewContext(tr.Request, tr.Response)
+		tr.Context.SetParamNames("id")
+		tr.Context.SetParamValues(created.ID)
+		tr.Request.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+		tr.Request.Header.Set(webhook.HeaderSignature, webhook.Sign(secret, timestamp.Unix(), []byte(body)))
+		assert.NoError(t, tr.Handle(server.handleHook))
+		return tr
+	}
+
+	tr := trigger(`{"alert":{"id":"a1","name":"Disk full"},"status":"firing"}`, "wrong", time.Now())
+	assert.Equal(t, http.StatusUnauthorized, tr.Response.Code)
+	tr = trigger(`{"alert":{"id":"a1","name":"Disk full"},"status":"firing"}`, created.Secret, time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusUnauthorized, tr.Response.Code)
+	tr = trigger(`not json`, created.Secret, time.Now())
+	assert.Equal(t, http.StatusBadRequest, tr.Response.Code)
+	assert.Empty(t, mock.Posted)
+
+	tr = trigger(`{"alert":{"id":"a1","name":"Disk full"},"status":"firing"}`, created.Secret, time.Now())
+	if !assert.Equal(t, http.StatusOK, tr.Response.Code, tr.Response.Body.String()) {
+		return
+	}
+	var first hookResponse
+	assert.NoError(t, json.Unmarshal(tr.Response.Body.Bytes(), &first))
+	assert.False(t, first.Duplicate)
+	assert.ElementsMatch(t, []string{"U1", "U2"}, first.Sent)
+	assert.True(t, strings.HasPrefix(mock.Posted["U1"], "*Disk full* is firing"))
+
+	blast, err := server.blasts.Get(first.Blast)
+	if assert.NoError(t, err) && assert.NotNil(t, blast) {
+		assert.Equal(t, created.ID, blast.HookID)
+		assert.Equal(t, "U9", blast.CreatedBy)
+	}
+
+	// Same alert ID within dedupe window
+	mock.Posted = nil
+	tr = trigger(`{"alert":{"id":"a1","name":"Disk full"},"status":"still firing"}`, created.Secret, time.Now())
+	var duplicate hookResponse
+	assert.NoError(t, json.Unmarshal(tr.Response.Body.Bytes(), &duplicate))
+	assert.True(t, duplicate.Duplicate)
+	assert.Equal(t, first.Blast, duplicate.Blast)
+	assert.Empty(t, mock.Posted)
+
+	// Failed sends don't count towards limits
+	mock.PostMessageError = errors.New("channel_not_found")
+	tr = trigger(`{"alert":{"id":"a2","name":"CPU"},"status":"firing"}`, created.Secret, time.Now())
+	assert.NotEqual(t, http.StatusOK, tr.Response.Code)
+	mock.PostMessageError = nil
+
+	tr = trigger(`{"alert":{"id":"a2","name":"CPU"},"status":"firing"}`, created.Secret, time.Now())
+	assert.Equal(t, http.StatusOK, tr.Response.Code)
+
+	tr = trigger(`{"alert":{"id":"a3","name":"Memory"},"status":"firing"}`, created.Secret, time.Now())
+	if assert.Equal(t, http.StatusTooManyRequests, tr.Response.Code) {
+		assert.Equal(t, errorCodeRateLimited, tr.APIError().Code)
+		assert.NotEmpty(t, tr.Response.Header().Get("Retry-After"))
+	}
+
+	// Hook stops sending when its creator's authorization is gone
+	_, err = server.authorizations.Delete(exclusionKey("T1", "U9"))
+	assert.NoError(t, err)
+	tr = trigger(`{"alert":{"id":"a5","name":"Network"},"status":"firing"}`, created.Secret, time.Now())
+	assert.Equal(t, http.StatusForbidden, tr.Response.Code)
+
+	r = newRequestTester(http.MethodGet, "/api/hooks", nil)
+	r.Server = server
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T1"
+	assert.NoError(t, r.Handle(server.handleAPIHooksGet))
+	assert.NotContains(t, r.Response.Body.String(), created.Secret)
+	assert.Contains(t, r.Response.Body.String(), `"last_triggered_at"`)
+
+	r = newRequestTester(http.MethodDelete, "/api/hooks/"+created.ID, nil)
+	r.Server = server
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T2"
+	r.Context.SetParamNames("id")
+	r.Context.SetParamValues(created.ID)
+	assert.NoError(t, r.Handle(server.handleAPIHookDelete))
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+
+	r = newRequestTester(http.MethodDelete, "/api/hooks/"+created.ID, nil)
+	r.Server = server
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.User = "U2"
+	r.Context.SetParamNames("id")
+	r.Context.SetParamValues(created.ID)
+	assert.NoError(t, r.Handle(server.handleAPIHookDelete))
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+
+	r = newRequestTester(http.MethodDelete, "/api/hooks/"+created.ID, nil)
+	r.Server = server
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.User = "U9"
+	r.Context.SetParamNames("id")
+	r.Context.SetParamValues(created.ID)
+	assert.NoError(t, r.Handle(server.handleAPIHookDelete))
+	assert.Equal(t, http.StatusNoContent, r.Response.Code)
+
+	tr = trigger(`{"alert":{"id":"a4"}}`, created.Secret, time.Now())
+	assert.Equal(t, http.StatusNotFound, tr.Response.Code)
+}
+
+func TestHookDeliveryPolicy(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/", nil).Server
+	mock := &mockSlackSession{
+		ClientSession:    &slack.ClientSession{Token: "xoxp-1", TeamID: "T1", User: "U9"},
+		Destinations:     testDestinations(),
+		UserGroupMembers: map[string][]string{"S1": {"U1", "U2"}},
+	}
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+
+	// Only the weekday three days from now is a working day, so recipients in UTC are outside working hours
+	workingDay := weekdays[time.Now().UTC().AddDate(0, 0, 3).Weekday()]
+	server.config.WorkspaceDeliveryPolicies = map[string]DeliveryPolicy{"T1": {Mode: policyModeWarn, WorkingDays: []string{workingDay}}}
+
+	trigger := func(request string) *requestTester {
+		r := newRequestTester(http.MethodPost, "/api/hooks", strings.NewReader(request))
+		r.Server = server
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.Context.Set(cookieSession, mock)
+		assert.NoError(t, r.Handle(server.handleAPIHooksCreate))
+		var created hookInfo
+		assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &created))
+
+		body := `{"alert":"Database is down"}`
+		timestamp := time.Now().Unix()
+		tr := newRequestTester(http.MethodPost, "/hooks/"+created.ID, strings.NewReader(body))
+		tr.Server = server
+		tr.Context = server.echo.NewContext(tr.Request, tr.Response)
+		tr.Context.SetParamNames("id")
+		tr.Context.SetParamValues(created.ID)
+		tr.Request.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		tr.Request.Header.Set(webhook.HeaderSignature, webhook.Sign(created.Secret, timestamp, []byte(body)))
+		assert.NoError(t, tr.Handle(server.handleHook))
+		return tr
+	}
+
+	tr := trigger(`{"name":"Alerts","audience":"group:oncall","template":"{{alert}}"}`)
+	assert.Equal(t, http.StatusConflict, tr.Response.Code)
+	assert.Empty(t, mock.Posted)
+
+	// Hooks with a reason page recipients at any time, recording the reason with each blast
+	tr = trigger(`{"name":"Pager","audience":"group:oncall","template":"{{alert}}","override_reason":"On-call alert"}`)
+	var sent hookResponse
+	if assert.NoError(t, json.Unmarshal(tr.Response.Body.Bytes(), &sent)) && assert.Len(t, sent.Sent, 2) {
+		paged, err := server.blasts.Get(sent.Blast)
+		if assert.NoError(t, err) && assert.NotNil(t, paged.PolicyOverride) {
+			assert.Equal(t, "On-call alert", paged.PolicyOverride.Reason)
+		}
+	}
+	assert.True(t, strings.HasPrefix(mock.Posted["U1"], "Database is down"))
+}
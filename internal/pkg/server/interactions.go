@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Action IDs of interactive elements in messages sent by the server.
+const (
+	actionBlastSend   = "blast_send"
+	actionBlastCancel = "blast_cancel"
+	actionBlastAck    = "blast_ack"
+
+	// actionBlastVote is followed by the index of the poll option
+	actionBlastVote = "blast_vote_"
+)
+
+// handleSlackInteraction handles /slack/interactions, dispatching button clicks by action ID.
+// Slack expects acknowledgement within seconds, so results are posted to the response URL.
+func (s *Server) handleSlackInteraction(c echo.Context) error {
+	callback, err := slack.ParseInteraction(c.Request(), s.config.SlackSigningSecret)
+	if err != nil {
+		s.config.Logger.Warn("rejected interaction", zap.Error(err))
+		return c.String(http.StatusUnauthorized, err.Error())
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case actionBlastSend:
+			s.handleActionBlastSend(c, callback, action.Value)
+		case actionBlastCancel:
+			s.handleActionBlastCancel(callback, action.Value)
+		case actionBlastAck:
+			s.handleActionBlastAck(callback, action.Value)
+		default:
+			if strings.HasPrefix(action.ActionID, actionBlastVote) {
+				s.handleActionBlastVote(callback, action.ActionID, action.Value)
+			}
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// handleActionBlastSend sends confirmed /blast draft in the background, replacing the confirmation with the outcome.
+func (s *Server) handleActionBlastSend(c echo.Context, callback slack.InteractionCallback, id string) {
+	draft, err := s.takeDraft(callback, id)
+	if err != nil {
+		s.respond(callback, ephemeral("Failed to send: "+err.Error()))
+		return
+	} else if draft == nil {
+		s.respond(callback, ephemeral("This blast was already sent, cancelled or has expired."))
+		return
+	}
+
+	session, err := s.userSession(draft.WorkspaceID, draft.User)
+	if err != nil || session == nil {
+		s.respond(callback, ephemeral("Failed to send: sign in again and retry."))
+		return
+	}
+
+	background := s.echo.NewContext(c.Request().Clone(context.Background()), nil)
+	s.dispatchWG.Add(1)
+	go func() {
+		defer s.dispatchWG.Done()
+
+		response, err := s.sendBlast(background, session, &sendRequest{
+			Users:   draft.Recipients,
+			Message: draft.Message,
+
+			OverrideReason: draft.Reason,
+		})
+		if err != nil {
+			e := errInternal(err)
+			text := "Failed to send: " + e.Message
+			if e.Code == errorCodeOutsideHours {
+				text += fmt.Sprintf(". Run `%s` again with `--reason why` at the end to send anyway.", commandBlast)
+			}
+			s.respond(callback, ephemeral(text))
+			return
+		}
+
+		text := fmt.Sprintf("Sent to %d recipients.", len(response.Sent))
+		if len(response.Skipped) > 0 {
+			text += fmt.Sprintf(" Skipped %d.", len(response.Skipped))
+		}
+		if len(response.Failed) > 0 {
+			text += fmt.Sprintf(" Failed to send to %d.", len(response.Failed))
+		}
+		s.respond(callback, ephemeral(text))
+	}()
+}
+
+// handleActionBlastCancel discards /blast draft.
+func (s *Server) handleActionBlastCancel(callback slack.InteractionCallback, id string) {
+	if _, err := s.takeDraft(callback, id); err != nil {
+		s.config.Logger.Warn("failed to cancel draft", zap.String("draft", id), zap.Error(err))
+	}
+	s.respond(callback, ephemeral("Cancelled."))
+}
+
+// takeDraft deletes and returns draft confirmed by its author, nil if it's not found or expired.
+// Deleting first guarantees that repeated clicks send only once.
+func (s *Server) takeDraft(callback slack.InteractionCallback, id string) (*commandDraft, error) {
+	draft, err := s.drafts.Get(id)
+	if err != nil || draft == nil {
+		return nil, err
+	}
+	if draft.WorkspaceID != callback.Team.ID || draft.User != callback.User.ID {
+		return nil, nil
+	}
+	deleted, err := s.drafts.Delete(id)
+	if err != nil || !deleted || time.Since(draft.CreatedAt) > draftMaxAge {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// replaceBlock rebuilds interactive message with block by ID replaced, keeping the rest of the message,
+// which must only consist of blocks that [block] represents.
+func replaceBlock(callback slack.InteractionCallback, blockID string, replacement *block) *commandResponse {
+	var original []*block
+	if encoded, err := json.Marshal(callback.Message.Blocks); err == nil {
+		json.Unmarshal(encoded, &original)
+	}
+	blocks := []*block{}
+	for _, b := range original {
+		if b.BlockID == blockID {
+			b = replacement
+		}
+		blocks = append(blocks, b)
+	}
+	return &commandResponse{Text: callback.Message.Text, Blocks: blocks}
+}
+
+// respond replaces interactive message with response.
+func (s *Server) respond(callback slack.InteractionCallback, response *commandResponse) {
+	response.ReplaceOriginal = true
+	if err := slack.Respond(callback.ResponseURL, response); err != nil {
+		s.config.Logger.Warn("failed to respond to interaction", zap.Error(err))
+	}
+}
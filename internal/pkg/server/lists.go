@@ -0,0 +1,250 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/gouline/blaster/internal/pkg/store"
+	"github.com/labstack/echo/v4"
+)
+
+// listIDPrefix distinguishes saved list IDs from Slack IDs.
+const listIDPrefix = "L"
+
+// listMemberReg matches the IDs that lists can contain: users (U, W), user groups (S),
+// channels (C, G) and other lists.
+var listMemberReg = regexp.MustCompile(`^[UWSCG` + listIDPrefix + `][A-Z0-9]+$`)
+
+// handleAPIListsGet handles GET /api/lists.
+func (s *Server) handleAPIListsGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	lists, err := s.workspaceLists(session)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, lists)
+}
+
+// handleAPIListsCreate handles POST /api/lists.
+func (s *Server) handleAPIListsCreate(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request listRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if err := request.validate(); err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	now := time.Now().UTC()
+	list := &recipientList{
+		ID:          listIDPrefix + strings.ToUpper(store.NewID()),
+		WorkspaceID: session.WorkspaceID(),
+		Name:        request.Name,
+		Users:       uniqueStrings(request.Users),
+		CreatedBy:   session.UserID(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.lists.Put(list.ID, list); err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusCreated, list)
+}
+
+// handleAPIListGet handles GET /api/lists/:id.
+func (s *Server) handleAPIListGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	list, err := s.workspaceList(session, c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if list == nil {
+		return errNotFound()
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
+
+// handleAPIListUpdate handles PUT /api/lists/:id.
+func (s *Server) handleAPIListUpdate(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request listRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if err := request.validate(); err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	list, err := s.workspaceList(session, c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if list == nil {
+		return errNotFound()
+	} else if list.CreatedBy != session.UserID() {
+		return errForbidden("Only the creator can change this list")
+	}
+
+	list, err = s.lists.Update(list.ID, func(list *recipientList) error {
+		list.Name = request.Name
+		list.Users = uniqueStrings(request.Users)
+		list.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
+
+// handleAPIListDelete handles DELETE /api/lists/:id.
+func (s *Server) handleAPIListDelete(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	list, err := s.workspaceList(session, c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if list == nil {
+		return errNotFound()
+	} else if list.CreatedBy != session.UserID() {
+		return errForbidden("Only the creator can delete this list")
+	}
+
+	if _, err := s.lists.Delete(list.ID); err != nil {
+		return errInternal(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// workspaceLists returns all saved lists in session's workspace.
+func (s *Server) workspaceLists(session slack.Session) ([]*recipientList, error) {
+	return s.lists.List(func(list *recipientList) bool {
+		return list.WorkspaceID == session.WorkspaceID()
+	})
+}
+
+// workspaceList returns saved list by ID, or nil if it's not found in session's workspace.
+func (s *Server) workspaceList(session slack.Session, id string) (*recipientList, error) {
+	list, err := s.lists.Get(id)
+	if err != nil || list == nil || list.WorkspaceID != session.WorkspaceID() {
+		return nil, err
+	}
+	return list, nil
+}
+
+// listDestinations converts saved lists into destinations, with members resolved against directory.
+// User groups in a list contribute their members. Users no longer in the directory, such as
+// deactivated ones, are omitted from children, as are nested lists and channels that are only
+// expanded when sending.
+func listDestinations(lists []*recipientList, destinations []*slack.Destination) []*slack.Destination {
+	lookup := map[string]*slack.Destination{}
+	for _, dest := range destinations {
+		lookup[dest.ID] = dest
+	}
+
+	listDestinations := []*slack.Destination{}
+	for _, list := range lists {
+		children := []*slack.Destination{}
+		seen := map[string]bool{}
+		add := func(user *slack.Destination) {
+			if !seen[user.ID] {
+				seen[user.ID] = true
+				children = append(children, user)
+			}
+		}
+		for _, id := range list.Users {
+			dest, found := lookup[id]
+			switch {
+			case !found:
+				continue
+			case dest.Type == "user":
+				add(dest)
+			case dest.Type == "usergroup":
+				for _, child := range dest.Children {
+					add(child)
+				}
+			}
+		}
+		listDestinations = append(listDestinations, &slack.Destination{
+			Type:     "list",
+			ID:       list.ID,
+			Name:     list.Name,
+			Children: children,
+		})
+	}
+	return listDestinations
+}
+
+// uniqueStrings removes empty and duplicate values, preserving order.
+func uniqueStrings(values []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// recipientList is a named set of recipients saved for a workspace, which only its creator can change.
+// Users are usually user IDs, but may also be user group, channel or other list IDs, expanded to
+// their current members when sending.
+type recipientList struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Users       []string  `json:"users"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type listRequest struct {
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
+}
+
+func (r *listRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if len(uniqueStrings(r.Users)) == 0 {
+		return fmt.Errorf("missing users")
+	}
+	for _, id := range r.Users {
+		if id != "" && !listMemberReg.MatchString(id) {
+			return fmt.Errorf("invalid member %q, must be a user, user group, channel or list ID", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleAPIListsUnauthenticated(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	for _, f := range []echo.HandlerFunc{
+		server.handleAPIListsGet,
+		server.handleAPIListsCreate,
+		server.handleAPIListGet,
+		server.handleAPIListUpdate,
+		server.handleAPIListDelete,
+	} {
+		r := newRequestTester(http.MethodGet, "/api", nil)
+		if assert.NoError(t, r.Handle(f)) {
+			assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+		}
+	}
+}
+
+func TestHandleAPIListsLifecycle(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	request := func(method, body, id, workspace string, handler echo.HandlerFunc) *requestTester {
+		return serveAPI(t, server, newMockSession(workspace, "U9"), method, body, handler, "id", id)
+	}
+
+	r := request(http.MethodPost, `{"name":" Ops ","users":["U1","U2","U1","","S1"]}`, "", "T1", server.handleAPIListsCreate)
+	if !assert.Equal(t, http.StatusCreated, r.Response.Code) {
+		return
+	}
+	var created recipientList
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.ID, listIDPrefix))
+	assert.Equal(t, "Ops", created.Name)
+	assert.Equal(t, []string{"U1", "U2", "S1"}, created.Users)
+
+	r = request(http.MethodGet, "", created.ID, "T1", server.handleAPIListGet)
+	assert.Equal(t, http.StatusOK, r.Response.Code)
+
+	r = request(http.MethodGet, "", created.ID, "T2", server.handleAPIListGet)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+
+	r = request(http.MethodGet, "", "", "T2", server.handleAPIListsGet)
+	assert.Equal(t, "[]\n", r.Response.Body.String())
+
+	r = request(http.MethodPut, `{"name":"Ops weekly","users":["U3"]}`, created.ID, "T1", server.handleAPIListUpdate)
+	if assert.Equal(t, http.StatusOK, r.Response.Code) {
+		var updated recipientList
+		assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &updated))
+		assert.Equal(t, "Ops weekly", updated.Name)
+		assert.Equal(t, []string{"U3"}, updated.Users)
+	}
+
+	r = request(http.MethodPut, `{"name":"","users":["U3"]}`, created.ID, "T1", server.handleAPIListUpdate)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+
+	// Other users in the workspace can use the list but not change it
+	r = serveAPI(t, server, newMockSession("T1", "U2"), http.MethodPut, `{"name":"Mine","users":["U2"]}`, server.handleAPIListUpdate, "id", created.ID)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	r = serveAPI(t, server, newMockSession("T1", "U2"), http.MethodDelete, "", server.handleAPIListDelete, "id", created.ID)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+
+	r = request(http.MethodDelete, "", created.ID, "T2", server.handleAPIListDelete)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+
+	r = request(http.MethodDelete, "", created.ID, "T1", server.handleAPIListDelete)
+	assert.Equal(t, http.StatusNoContent, r.Response.Code)
+
+	r = request(http.MethodGet, "", created.ID, "T1", server.handleAPIListGet)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+}
+
+func TestHandleAPIListsCreateInvalid(t *testing.T) {
+	for _, body := range []string{
+		`{"name":"","users":["U1"]}`,
+		`{"name":"Ops","users":[]}`,
+		`{"name":"Ops","users":[""]}`,
+		`{"name":"Ops","users":["U1","alice"]}`,
+		`{"name":"Ops","users":["<@U1>"]}`,
+		`{"name":`,
+	} {
+		r := newRequestTester(http.MethodPost, "/api", strings.NewReader(body))
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.Authenticate("1", "")
+
+		if assert.NoError(t, r.Handle(r.Server.handleAPIListsCreate)) {
+			assert.Equal(t, http.StatusBadRequest, r.Response.Code, "body: %s", body)
+		}
+	}
+}
+
+func TestHandleAPISuggestLists(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/api?term=ops", nil)
+	r.Authenticate("1", "")
+	r.Session.Destinations = testDestinations()
+	assert.NoError(t, r.Server.lists.Put("L1", &recipientList{
+		ID:    "L1",
+		Name:  "Ops update",
+		Users: []string{"U2", "U9", "U1"},
+	}))
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPISuggest)) {
+		var suggestions []*suggestion
+		if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &suggestions)) && assert.Len(t, suggestions, 1) {
+			assert.Equal(t, "list", suggestions[0].Type)
+			assert.Equal(t, "L1", suggestions[0].Value)
+
+			children := []string{}
+			for _, child := range suggestions[0].Children {
+				children = append(children, child.Value)
+			}
+			assert.Equal(t, []string{"U2", "U1"}, children)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+const openAPIVersion = "3.0.3"
+
+// apiOperation describes an /api endpoint for the OpenAPI document.
+// Request and response are zero values of the types the handler binds and returns.
+type apiOperation struct {
+	method  string
+	path    string
+	id      string
+	summary string
+
+	// scope makes operation also available under /api/v1 to tokens with that scope
+	scope string
+
+	query    []apiParam
+	request  interface{}
+	upload   string
+	status   int
+	response interface{}
+}
+
+type apiParam struct {
+	name        string
+	kind        string
+	description string
+}
+
+// apiOperations lists all /api endpoints, which must match routes registered in [New].
+var apiOperations = []apiOperation{
+	{
+		method: http.MethodGet, path: "/suggest", id: "suggest", summary: "Suggest recipients matching a search term",
+		scope: scopeSuggest,
+		query: []apiParam{
+			{"term", "string", "Search term, optionally with field filters such as title:engineer"},
+			{"limit", "integer", "Maximum number of suggestions"},
+			{"offset", "integer", "Number of suggestions to skip"},
+			{"include_disabled", "boolean", "Include disabled user groups"},
+		},
+		status: http.StatusOK, response: []*suggestion{},
+	},
+	{
+		method: http.MethodGet, path: "/audience", id: "audience", summary: "Preview users matching an audience expression",
+		scope: scopeSuggest,
+		query: []apiParam{
+			{"expression", "string", "Audience expression, such as group:oncall AND is:member"},
+		},
+		status: http.StatusOK, response: audienceResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/resolve", id: "resolve", summary: "Expand recipient IDs into users",
+		scope:   scopeSuggest,
+		request: resolveRequest{}, status: http.StatusOK, response: resolution{},
+	},
+	{
+		method: http.MethodPost, path: "/recipients/csv", id: "uploadRecipientsCSV", summary: "Match recipients from a CSV file",
+		upload: "file", status: http.StatusOK, response: csvUpload{},
+	},
+	{
+		method: http.MethodPost, path: "/send", id: "send", summary: "Send a message to recipients",
+		scope:   scopeSend,
+		request: sendRequest{}, status: http.StatusOK, response: sendResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/lists", id: "listLists", summary: "List saved recipient lists",
+		status: http.StatusOK, response: []*recipientList{},
+	},
+	{
+		method: http.MethodPost, path: "/lists", id: "createList", summary: "Save a recipient list",
+		request: listRequest{}, status: http.StatusCreated, response: recipientList{},
+	},
+	{
+		method: http.MethodGet, path: "/lists/:id", id: "getList", summary: "Get a saved recipient list",
+		status: http.StatusOK, response: recipientList{},
+	},
+	{
+		method: http.MethodPut, path: "/lists/:id", id: "updateList", summary: "Update a saved recipient list",
+		request: listRequest{}, status: http.StatusOK, response: recipientList{},
+	},
+	{
+		method: http.MethodDelete, path: "/lists/:id", id: "deleteList", summary: "Delete a saved recipient list",
+		status: http.StatusNoContent,
+	},
+	{
+		method: http.MethodGet, path: "/exclusions", id: "listExclusions", summary: "List users excluded from blasts",
+		status: http.StatusOK, response: []*exclusion{},
+	},
+	{
+		method: http.MethodPost, path: "/exclusions", id: "createExclusion", summary: "Exclude a user from blasts",
+		request: exclusionRequest{}, status: http.StatusCreated, response: exclusion{},
+	},
+	{
+		method: http.MethodDelete, path: "/exclusions/:user", id: "deleteExclusion", summary: "Remove a user's exclusion",
+		status: http.StatusNoContent,
+	},
+	{
+		method: http.MethodGet, path: "/blasts", id: "listBlasts", summary: "List sent blasts, most recent first",
+		scope:  scopeHistory,
+		status: http.StatusOK, response: []*blast{},
+	},
+	{
+		method: http.MethodGet, path: "/blasts/:id", id: "getBlast", summary: "Get a sent blast",
+		scope:  scopeHistory,
+		status: http.StatusOK, response: blast{},
+	},
+	{
+		method: http.MethodGet, path: "/blasts/:id/acks", id: "getBlastAcks", summary: "Report which recipients acknowledged a blast",
+		scope:  scopeHistory,
+		status: http.StatusOK, response: ackReport{},
+	},
+	{
+		method: http.MethodPost, path: "/blasts/:id/remind", id: "remindBlast", summary: "Remind recipients who haven't acknowledged a blast",
+		scope:   scopeSend,
+		request: remindRequest{}, status: http.StatusOK, response: sendResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/blasts/:id/poll", id: "getBlastPoll", summary: "Tally votes for a blast's poll",
+		scope:  scopeHistory,
+		status: http.StatusOK, response: pollResults{},
+	},
+	{
+		method: http.MethodGet, path: "/blasts/:id/replies", id: "listBlastReplies", summary: "List recipients' replies to a blast, oldest first",
+		scope:  scopeHistory,
+		status: http.StatusOK, response: []*blastReply{},
+	},
+	{
+		method: http.MethodDelete, path: "/blasts/:id/schedule", id: "cancelBlastSchedule", summary: "Cancel a scheduled blast's waves that haven't been sent",
+		scope:  scopeSend,
+		status: http.StatusOK, response: blast{},
+	},
+	{
+		method: http.MethodPost, path: "/blasts/:id/approve", id: "approveBlast", summary: "Approve a blast pending your approval",
+		scope:  scopeSend,
+		status: http.StatusOK, response: blast{},
+	},
+	{
+		method: http.MethodGet, path: "/webhooks", id: "listWebhooks", summary: "List webhooks subscribed to blast events",
+		status: http.StatusOK, response: []*webhookInfo{},
+	},
+	{
+		method: http.MethodPost, path: "/webhooks", id: "createWebhook", summary: "Subscribe a webhook to blast events, returning its secret once",
+		request: webhookRequest{}, status: http.StatusCreated, response: webhookInfo{},
+	},
+	{
+		method: http.MethodDelete, path: "/webhooks/:id", id: "deleteWebhook", summary: "Delete a webhook",
+		status: http.StatusNoContent,
+	},
+	{
+		method: http.MethodGet, path: "/webhooks/:id/deliveries", id: "listWebhookDeliveries", summary: "List a webhook's deliveries, most recent first",
+		status: http.StatusOK, response: []*webhookDelivery{},
+	},
+	{
+		method: http.MethodPost, path: "/webhooks/:id/deliveries/:delivery/redeliver", id: "redeliverWebhook", summary: "Deliver a payload again",
+		status: http.StatusAccepted, response: webhookDelivery{},
+	},
+	{
+		method: http.MethodGet, path: "/hooks", id: "listHooks", summary: "List incoming hooks that send blasts",
+		status: http.StatusOK, response: []*hookInfo{},
+	},
+	{
+		method: http.MethodPost, path: "/hooks", id: "createHook", summary: "Create an incoming hook, returning its secret once",
+		request: hookRequest{}, status: http.StatusCreated, response: hookInfo{},
+	},
+	{
+		method: http.MethodDelete, path: "/hooks/:id", id: "deleteHook", summary: "Delete an incoming hook",
+		status: http.StatusNoContent,
+	},
+	{
+		method: http.MethodGet, path: "/tokens", id: "listTokens", summary: "List your API tokens",
+		status: http.StatusOK, response: []*tokenInfo{},
+	},
+	{
+		method: http.MethodPost, path: "/tokens", id: "createToken", summary: "Create an API token, returning its value once",
+		request: tokenRequest{}, status: http.StatusCreated, response: tokenInfo{},
+	},
+	{
+		method: http.MethodDelete, path: "/tokens/:id", id: "revokeToken", summary: "Revoke an API token",
+		status: http.StatusNoContent,
+	},
+}
+
+// schemaNames overrides schema names derived from Go type names.
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(apiError{}): "Error",
+}
+
+// handleAPIOpenAPI handles GET /api/openapi.json.
+func (s *Server) handleAPIOpenAPI(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, s.openAPI)
+}
+
+// newOpenAPIDocument generates OpenAPI document from operations and their Go types.
+func newOpenAPIDocument(title string, operations []apiOperation) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    openAPIInfo{Title: title, Version: "1"},
+		Paths:   map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: map[string]*openAPISchema{},
+			SecuritySchemes: map[string]*openAPISecurityScheme{
+				"session": {Type: "apiKey", In: "cookie", Name: cookieSession},
+				"token":   {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+	errorSchema := doc.schema(reflect.TypeOf(apiError{}), false)
+
+	for _, op := range operations {
+		doc.addOperation("/api", op, "session", nil, errorSchema)
+		if op.scope != "" {
+			doc.addOperation("/api/v1", op, "token", []string{op.scope}, errorSchema)
+		}
+	}
+	return doc
+}
+
+func (d *openAPIDocument) addOperation(prefix string, op apiOperation, security string, scopes []string, errorSchema *openAPISchema) {
+	o := &openAPIOperation{
+		OperationID: op.id,
+		Summary:     op.summary,
+		Responses:   map[string]*openAPIResponse{},
+		Security:    []map[string][]string{{security: append([]string{}, scopes...)}},
+	}
+	if prefix != "/api" {
+		o.OperationID = strings.Trim(strings.ReplaceAll(prefix, "/", "_"), "_") + "_" + op.id
+		o.Tags = []string{"v1"}
+	}
+
+	segments := strings.Split(op.path, "/")
+	for i, segment := range segments {
+		if name, found := strings.CutPrefix(segment, ":"); found {
+			segments[i] = "{" + name + "}"
+			o.Parameters = append(o.Parameters, &openAPIParameter{
+				Name: name, In: "path", Required: true, Schema: &openAPISchema{Type: "string"},
+			})
+		}
+	}
+	for _, param := range op.query {
+		o.Parameters = append(o.Parameters, &openAPIParameter{
+			Name: param.name, In: "query", Description: param.description, Schema: &openAPISchema{Type: param.kind},
+		})
+	}
+
+	if op.request != nil {
+		o.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]*openAPIMediaType{
+				echo.MIMEApplicationJSON: {Schema: d.schema(reflect.TypeOf(op.request), true)},
+			},
+		}
+	} else if op.upload != "" {
+		o.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]*openAPIMediaType{
+				echo.MIMEMultipartForm: {Schema: &openAPISchema{
+					Type:       "object",
+					Properties: map[string]*openAPISchema{op.upload: {Type: "string", Format: "binary"}},
+					Required:   []string{op.upload},
+				}},
+			},
+		}
+	}
+
+	response := &openAPIResponse{Description: http.StatusText(op.status)}
+	if op.response != nil {
+		response.Content = map[string]*openAPIMediaType{
+			echo.MIMEApplicationJSON: {Schema: d.schema(reflect.TypeOf(op.response), false)},
+		}
+	}
+	o.Responses[strconv.Itoa(op.status)] = response
+	o.Responses["default"] = &openAPIResponse{
+		Description: "Error",
+		Content:     map[string]*openAPIMediaType{echo.MIMEApplicationJSON: {Schema: errorSchema}},
+	}
+
+	path := prefix + strings.Join(segments, "/")
+	if d.Paths[path] == nil {
+		d.Paths[path] = map[string]*openAPIOperation{}
+	}
+	d.Paths[path][strings.ToLower(op.method)] = o
+}
+
+// schema converts Go type into schema, adding named structs to components and referencing them.
+// Fields without omitempty are always encoded, so they are required in responses, but optional in requests.
+func (d *openAPIDocument) schema(t reflect.Type, request bool) *openAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t == reflect.TypeOf(json.RawMessage{}):
+		return &openAPISchema{Type: "object", AdditionalProperties: &openAPISchema{}}
+	case t.Kind() == reflect.Struct:
+		name := schemaName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+			// Placeholder prevents infinite recursion on self-referencing types
+			d.Components.Schemas[name] = schema
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name, omitEmpty := jsonField(field)
+				if name == "" {
+					continue
+				}
+				property := d.schema(field.Type, request)
+				if !omitEmpty && !request {
+					schema.Required = append(schema.Required, name)
+					switch field.Type.Kind() {
+					case reflect.Pointer, reflect.Slice, reflect.Map:
+						property = nullable(property)
+					}
+				}
+				schema.Properties[name] = property
+			}
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &openAPISchema{Type: "array", Items: d.schema(t.Elem(), request)}
+	case t.Kind() == reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: d.schema(t.Elem(), request)}
+	case t.Kind() == reflect.String:
+		return &openAPISchema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &openAPISchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	}
+	return &openAPISchema{}
+}
+
+// nullable marks schema as accepting null, wrapping references that can't have siblings.
+func nullable(schema *openAPISchema) *openAPISchema {
+	if schema.Ref != "" {
+		return &openAPISchema{AllOf: []*openAPISchema{schema}, Nullable: true}
+	}
+	schema.Nullable = true
+	return schema
+}
+
+// schemaName converts Go type name into exported schema name, such as "sendRequest" into "SendRequest".
+func schemaName(t reflect.Type) string {
+	if name, ok := schemaNames[t]; ok {
+		return name
+	}
+	runes := []rune(t.Name())
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// jsonField returns JSON name of exported struct field, empty if it isn't encoded.
+func jsonField(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+options+",", ",omitempty,")
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	AllOf                []*openAPISchema          `json:"allOf,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
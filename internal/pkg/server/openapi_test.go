@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleAPIOpenAPI(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/api/openapi.json", nil)
+	r.Server.echo.ServeHTTP(r.Response, r.Request)
+
+	assert.Equal(t, http.StatusOK, r.Response.Code)
+	var doc openAPIDocument
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &doc)) {
+		assert.Equal(t, openAPIVersion, doc.OpenAPI)
+		assert.Equal(t, "Blaster API", doc.Info.Title)
+		assert.Contains(t, doc.Components.Schemas, "SendRequest")
+		assert.Contains(t, doc.Components.Schemas, "Suggestion")
+		assert.Contains(t, doc.Components.Schemas, "Error")
+		assert.Equal(t, []map[string][]string{{"token": {scopeSend}}}, doc.Paths["/api/v1/send"]["post"].Security)
+	}
+}
+
+func TestOpenAPISchema(t *testing.T) {
+	doc := newOpenAPIDocument("Test", nil)
+	type child struct {
+		Name string `json:"name"`
+	}
+	type parent struct {
+		ID       string            `json:"id"`
+		Count    int               `json:"count,omitempty"`
+		Children []*child          `json:"children"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		At       time.Time         `json:"at"`
+		Ignored  string            `json:"-"`
+		hidden   string
+	}
+
+	assert.Equal(t, &openAPISchema{Ref: "#/components/schemas/Parent"}, doc.schema(reflect.TypeOf(parent{}), false))
+	assert.Equal(t, &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"id":       {Type: "string"},
+			"count":    {Type: "integer"},
+			"children": {Type: "array", Items: &openAPISchema{Ref: "#/components/schemas/Child"}, Nullable: true},
+			"labels":   {Type: "object", AdditionalProperties: &openAPISchema{Type: "string"}},
+			"at":       {Type: "string", Format: "date-time"},
+		},
+		Required: []string{"id", "children", "at"},
+	}, doc.Components.Schemas["Parent"])
+	assert.Equal(t, []string{"name"}, doc.Components.Schemas["Child"].Required)
+}
+
+// TestOpenAPIRoutes fails when a route is added to or removed from /api without updating apiOperations.
+func TestOpenAPIRoutes(t *testing.T) {
+	s := newRequestTester(http.MethodGet, "/", nil).Server
+	var doc openAPIDocument
+	if !assert.NoError(t, json.Unmarshal(s.openAPI, &doc)) {
+		return
+	}
+
+	paramReg := regexp.MustCompile(`:(\w+)`)
+	documented := []string{}
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	registered := []string{}
+	for _, route := range s.echo.Routes() {
+		if route.Method == echo.RouteNotFound || !strings.HasPrefix(route.Path, "/api/") || route.Path == "/api/openapi.json" {
+			continue
+		}
+		registered = append(registered, route.Method+" "+paramReg.ReplaceAllString(route.Path, "{$1}"))
+	}
+
+	assert.ElementsMatch(t, documented, registered)
+}
+
+// TestOpenAPIResponses fails when a handler responds with JSON that doesn't match its documented schema.
+func TestOpenAPIResponses(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	var doc openAPIDocument
+	if !assert.NoError(t, json.Unmarshal(server.openAPI, &doc)) {
+		return
+	}
+
+	assert.NoError(t, server.tokens.Put("0123456789abcdef01234567", &apiToken{
+		ID: "0123456789abcdef01234567", WorkspaceID: "T1", User: "U9", Name: "CI", Scopes: []string{scopeSend},
+	}))
+
+	for _, test := range []struct {
+		method  string
+		path    string
+		param   string
+		body    string
+		handler func(*Server) echo.HandlerFunc
+	}{
+		{http.MethodGet, "/api/suggest", "", "", func(s *Server) echo.HandlerFunc { return s.handleAPISuggest }},
+		{http.MethodGet, "/api/audience", "", "", func(s *Server) echo.HandlerFunc { return s.handleAPIAudience }},
+		{http.MethodPost, "/api/resolve", "", `{"ids":["S1","U3"]}`, func(s *Server) echo.HandlerFunc { return s.handleAPIResolve }},
+		{http.MethodPost, "/api/send", "", `{"users":["U1","U2"],"message":"Hello"}`, func(s *Server) echo.HandlerFunc { return s.handleAPISend }},
+		{http.MethodPost, "/api/lists", "", `{"name":"Ops","users":["U1"]}`, func(s *Server) echo.HandlerFunc { return s.handleAPIListsCreate }},
+		{http.MethodGet, "/api/lists", "", "", func(s *Server) echo.HandlerFunc { return s.handleAPIListsGet }},
+		{http.MethodGet, "/api/lists/{id}", "missing", "", func(s *Server) echo.HandlerFunc { return s.handleAPIListGet }},
+		{http.MethodPost, "/api/exclusions", "", `{"user":"U3","reason":"On leave"}`, func(s *Server) echo.HandlerFunc { return s.handleAPIExclusionsCreate }},
+		{http.MethodGet, "/api/exclusions", "", "", func(s *Server) echo.HandlerFunc { return s.handleAPIExclusionsGet }},
+		{http.MethodGet, "/api/blasts", "", "", func(s *Server) echo.HandlerFunc { return s.handleAPIBlastsGet }},
+		{http.MethodPost, "/api/tokens", "", `{"name":"CI","scopes":["send"]}`, func(s *Server) echo.HandlerFunc { return s.handleAPITokensCreate }},
+		{http.MethodGet, "/api/tokens", "", "", func(s *Server) echo.HandlerFunc { return s.handleAPITokensGet }},
+	} {
+		r := newRequestTester(test.method, test.path, strings.NewReader(test.body))
+		r.Server = server
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.Authenticate("1", "ACME")
+		r.Session.TeamID = "T1"
+		r.Session.User = "U9"
+		r.Session.Destinations = testDestinations()
+		r.Context.SetParamNames("id")
+		r.Context.SetParamValues(test.param)
+		if !assert.NoError(t, r.Handle(test.handler(server))) {
+			continue
+		}
+
+		operation := doc.Paths[test.path][strings.ToLower(test.method)]
+		if !assert.NotNil(t, operation, "%s %s", test.method, test.path) {
+			continue
+		}
+		response, ok := operation.Responses[strconv.Itoa(r.Response.Code)]
+		if !ok {
+			response = operation.Responses["default"]
+		}
+		var body interface{}
+		if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &body)) {
+			schema := response.Content[echo.MIMEApplicationJSON].Schema
+			for _, err := range validateSchema(&doc, schema, body, "$") {
+				t.Errorf("%s %s responded %d: %s", test.method, test.path, r.Response.Code, err)
+			}
+		}
+	}
+}
+
+// validateSchema checks JSON value against schema, returning all mismatches.
+func validateSchema(doc *openAPIDocument, schema *openAPISchema, value interface{}, path string) []string {
+	if schema.Ref != "" {
+		return validateSchema(doc, doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")], value, path)
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return []string{path + " is null"}
+	}
+	if len(schema.AllOf) > 0 {
+		errs := []string{}
+		for _, s := range schema.AllOf {
+			errs = append(errs, validateSchema(doc, s, value, path)...)
+		}
+		return errs
+	}
+
+	mismatch := []string{fmt.Sprintf("%s is %T, expected %s", path, value, schema.Type)}
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch
+		}
+		errs := []string{}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				errs = append(errs, path+"."+name+" is missing")
+			}
+		}
+		for name, v := range object {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property == nil {
+				errs = append(errs, path+"."+name+" is undocumented")
+				continue
+			}
+			errs = append(errs, validateSchema(doc, property, v, path+"."+name)...)
+		}
+		slices.Sort(errs)
+		return errs
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		errs := []string{}
+		for i, v := range array {
+			errs = append(errs, validateSchema(doc, schema.Items, v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+	case "string":
+		if _, ok := value.(string); !ok {
+			return mismatch
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return mismatch
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return mismatch
+		}
+	}
+	return nil
+}
+
+func TestValidateSchema(t *testing.T) {
+	doc := newOpenAPIDocument("Test", nil)
+	schema := doc.schema(reflect.TypeOf(recipientSkip{}), false)
+
+	assert.Empty(t, validateSchema(doc, schema, map[string]interface{}{"user": "U1", "reason": "Excluded"}, "$"))
+	assert.Equal(t, []string{"$.reason is missing", "$.user is float64, expected string"},
+		validateSchema(doc, schema, map[string]interface{}{"user": 1.0}, "$"))
+	assert.Equal(t, []string{"$.extra is undocumented"},
+		validateSchema(doc, schema, map[string]interface{}{"user": "U1", "reason": "", "extra": true}, "$"))
+}
@@ -3,25 +3,132 @@ package server
 import (
 	"net/http"
 
+	"github.com/gouline/blaster/internal/pkg/i18n"
+	"github.com/gouline/blaster/internal/pkg/slack"
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
 )
 
 // handleIndex handles /.
 func (s *Server) handleIndex(c echo.Context) error {
 	return c.Render(http.StatusOK, "index.html", s.baseData(c, map[string]interface{}{
-		"title": appName,
+		"title": s.branding(s.session(c).WorkspaceID()).AppName,
 	}))
 }
 
+// handleTokens handles /tokens, managing personal access tokens for the API.
+func (s *Server) handleTokens(c echo.Context) error {
+	session := s.session(c)
+	tokens := []*apiToken{}
+	if session.IsAuthenticated() {
+		var err error
+		tokens, err = s.userTokens(session)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.Render(http.StatusOK, "tokens.html", s.baseData(c, map[string]interface{}{
+		"title":  s.translator(c)("tokens.title"),
+		"scopes": tokenScopes,
+		"tokens": tokens,
+	}))
+}
+
+// handleBlasts handles /blasts, listing workspace's sent blasts.
+func (s *Server) handleBlasts(c echo.Context) error {
+	session := s.session(c)
+	blasts := []*blast{}
+	if session.IsAuthenticated() {
+		var err error
+		blasts, err = s.workspaceBlasts(session.WorkspaceID())
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.Render(http.StatusOK, "blasts.html", s.baseData(c, map[string]interface{}{
+		"title":  s.translator(c)("blasts.title"),
+		"blasts": blasts,
+	}))
+}
+
+// handleBlast handles /blasts/:id, showing blast's recipients, who acknowledged it, poll results and replies.
+func (s *Server) handleBlast(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return c.Redirect(http.StatusFound, "/blasts")
+	}
+
+	blast, err := s.blasts.Get(c.Param("id"))
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	} else if blast == nil || blast.WorkspaceID != session.WorkspaceID() {
+		return s.handleNotFound(c)
+	}
+
+	data := map[string]interface{}{
+		"title": s.translator(c)("blasts.blast_title"),
+		"blast": blast,
+		"names": s.userNames(session),
+	}
+	if blast.Acknowledge {
+		data["acks"] = newAckReport(blast)
+	}
+	if blast.Poll != nil {
+		data["poll"] = newPollResults(blast)
+	}
+	// Replies are private to the sender
+	if blast.CreatedBy == session.UserID() {
+		data["replies"], err = s.blastReplies(blast.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.Render(http.StatusOK, "blast.html", s.baseData(c, data))
+}
+
+// userNames maps workspace's user IDs to names for display, empty if they can't be loaded.
+func (s *Server) userNames(session slack.Session) map[string]string {
+	names := map[string]string{}
+	destinations, err := session.GetDestinations()
+	if err != nil {
+		s.config.Logger.Warn("failed to load user names", zap.Error(err))
+		return names
+	}
+	for _, dest := range destinations {
+		if dest.Type == "user" {
+			names[dest.ID] = dest.Name
+		}
+	}
+	return names
+}
+
 // handleNotFound handles 404 Not Found errors.
 func (s *Server) handleNotFound(c echo.Context) error {
+	t := s.translator(c)
 	return c.Render(http.StatusNotFound, "error.html", s.baseData(c, map[string]interface{}{
-		"title":   "404 Not Found",
-		"message": "Unfortunately, this page doesn't seem to exist. Are you sure about that URL?",
+		"title":   t("error.not_found_title"),
+		"message": t("error.not_found_message"),
 	}))
 }
 
 func (s *Server) baseData(c echo.Context, data map[string]interface{}) map[string]interface{} {
 	data["slack"] = s.session(c)
+	if _, ok := data["branding"]; !ok {
+		data["branding"] = s.branding(s.session(c).WorkspaceID())
+	}
+	data["lang"] = s.language(c).String()
 	return data
 }
+
+// language negotiates UI language from the Slack user's locale, then Accept-Language header.
+func (s *Server) language(c echo.Context) language.Tag {
+	return s.catalogue.Match(s.session(c).Locale(), c.Request().Header.Get("Accept-Language"))
+}
+
+// translator returns translator for the request's language.
+func (s *Server) translator(c echo.Context) i18n.Translator {
+	return s.catalogue.Translator(s.language(c))
+}
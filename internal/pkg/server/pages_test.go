@@ -1,9 +1,15 @@
 package server
 
 import (
+	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/gouline/blaster/internal/pkg/i18n"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,3 +40,136 @@ func TestHandleNotFound(t *testing.T) {
 		assert.Contains(t, r.Response.Body.String(), "<body>")
 	}
 }
+
+func TestHandleNotFoundTranslated(t *testing.T) {
+	for _, test := range []struct {
+		acceptLanguage string
+		locale         string
+		contains       []string
+	}{
+		{
+			contains: []string{`<html lang="en">`, "404 Not Found"},
+		},
+		{
+			acceptLanguage: "de-DE,de;q=0.9",
+			contains:       []string{`<html lang="de">`, "404 Nicht gefunden", "Autorisieren"},
+		},
+		{
+			acceptLanguage: "de-DE,de;q=0.9",
+			locale:         "ja-JP",
+			contains:       []string{`<html lang="ja">`, "404 ページが見つかりません"},
+		},
+	} {
+		r := newRequestTester(http.MethodGet, "/", nil)
+		r.Request.Header.Set("Accept-Language", test.acceptLanguage)
+		r.Session.UserLocale = test.locale
+
+		if assert.NoError(t, r.Server.handleNotFound(r.Context)) {
+			for _, s := range test.contains {
+				assert.Contains(t, r.Response.Body.String(), s)
+			}
+		}
+	}
+}
+
+func TestTemplateMessages(t *testing.T) {
+	catalogue, err := i18n.New()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	keyReg := regexp.MustCompile(`\{\{-?\s*(tn?)\s+"([^"]+)"`)
+	err = filepath.WalkDir("../../../templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range keyReg.FindAllStringSubmatch(string(content), -1) {
+			key := match[2]
+			if match[1] == "tn" {
+				key += ".other"
+			}
+			assert.NotEqual(t, key, catalogue.Translate(i18n.Fallback, key), "missing message %s in %s", key, path)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestHandleTokens(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/tokens", nil)
+	r.Authenticate("1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.User = "U1"
+	assert.NoError(t, r.Server.tokens.Put("1", &apiToken{ID: "1", WorkspaceID: "T1", User: "U1", Name: "Deploy bot", Scopes: []string{scopeSend}}))
+	assert.NoError(t, r.Server.tokens.Put("2", &apiToken{ID: "2", WorkspaceID: "T1", User: "U2", Name: "Someone else's"}))
+
+	if assert.NoError(t, r.Server.handleTokens(r.Context)) {
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+		assert.Contains(t, r.Response.Body.String(), "Deploy bot")
+		assert.NotContains(t, r.Response.Body.String(), "Someone else")
+		assert.Contains(t, r.Response.Body.String(), "Read sent announcements")
+	}
+}
+
+func TestHandleBlasts(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/blasts", nil)
+	r.Authenticate("1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.Destinations = testDestinations()
+	server := r.Server
+	now := time.Now()
+	assert.NoError(t, server.blasts.Put("1", &blast{ID: "1", WorkspaceID: "T1", CreatedBy: "U3", Message: "Fire drill", Status: blastStatusCompleted,
+		Sent: []string{"U1", "U2"}, Acknowledge: true, Acks: map[string]time.Time{"U1": now}, CreatedAt: now,
+		Poll: &poll{Options: []string{"Pizza", "Sushi"}, Votes: map[string]int{"U2": 1}}}))
+	assert.NoError(t, server.blasts.Put("2", &blast{ID: "2", WorkspaceID: "T2", Message: "Other workspace", CreatedAt: now}))
+	assert.NoError(t, server.blasts.Put("4", &blast{ID: "4", WorkspaceID: "T1", CreatedBy: "U3", Message: "Standup moved", Status: blastStatusScheduled,
+		Sent: []string{}, CreatedAt: now, PolicyOverride: &policyOverride{Reason: "Office closed tomorrow", Users: []string{"U1"}},
+		Schedule: &schedule{Date: "2030-03-01", Time: "09:00", Waves: []*blastWave{
+			{Timezone: "Europe/Berlin", SendAt: time.Date(2030, 3, 1, 8, 0, 0, 0, time.UTC), Users: []string{"U1"}, Status: waveStatusPending, Deferred: true},
+		}}}))
+	assert.NoError(t, server.blasts.Put("5", &blast{ID: "5", WorkspaceID: "T1", CreatedBy: "U3", Message: "*Reminder:* please acknowledge this message.",
+		Status: blastStatusCompleted, Sent: []string{"U2"}, CreatedAt: now, ReminderOf: "1"}))
+	assert.NoError(t, server.blasts.Put("6", &blast{ID: "6", WorkspaceID: "T1", CreatedBy: "U1", Message: "Office closed", Status: blastStatusPendingApproval,
+		Sent: []string{}, CreatedAt: now, Approval: &approval{Approver: "U3"},
+		Schedule: &schedule{Waves: []*blastWave{{SendAt: now, Users: []string{"U2"}, Status: waveStatusPending}}}}))
+	assert.NoError(t, server.replies.Put("D1/1.000", &blastReply{ID: "D1/1.000", BlastID: "1", User: "U1", Text: "Which floor?", Timestamp: "1.000"}))
+
+	if assert.NoError(t, server.handleBlasts(r.Context)) {
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+		assert.Contains(t, r.Response.Body.String(), "Fire drill")
+		assert.Contains(t, r.Response.Body.String(), "1/2")
+		assert.NotContains(t, r.Response.Body.String(), "Other workspace")
+	}
+
+	for _, test := range []struct {
+		id       string
+		status   int
+		contains []string
+	}{
+		{id: "1", status: http.StatusOK, contains: []string{"Fire drill", "Yuki Tanaka", "Jane Doe", "John Smith", "blast-remind", "Sushi", "1 vote", "width: 100%", "Which floor?"}},
+		{id: "2", status: http.StatusNotFound},
+		{id: "3", status: http.StatusNotFound},
+		{id: "4", status: http.StatusOK, contains: []string{"Scheduled", "blast-cancel", "Europe/Berlin", "1 Mar 2030 08:00", "deferred to working hours", "Office closed tomorrow"}},
+		{id: "5", status: http.StatusOK, contains: []string{"Reminder of", `href="/blasts/1"`}},
+		{id: "6", status: http.StatusOK, contains: []string{"Pending approval", "Approver", "blast-approve", "blast-cancel", "Once approved"}},
+	} {
+		r = newRequestTester(http.MethodGet, "/blasts/"+test.id, nil)
+		r.Server = server
+		r.Authenticate("1", "ACME")
+		r.Session.TeamID = "T1"
+		r.Session.User = "U3"
+		r.Session.Destinations = testDestinations()
+		r.Context.SetParamNames("id")
+		r.Context.SetParamValues(test.id)
+		if assert.NoError(t, server.handleBlast(r.Context)) {
+			assert.Equal(t, test.status, r.Response.Code, test.id)
+			for _, s := range test.contains {
+				assert.Contains(t, r.Response.Body.String(), s, test.id)
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// policyModeOff delivers blasts at any time
+	policyModeOff = "off"
+
+	// policyModeDefer holds messages to recipients outside working hours until their next working hours
+	policyModeDefer = "defer"
+
+	// policyModeWarn rejects blasts reaching recipients outside working hours, unless overridden with a reason
+	policyModeWarn = "warn"
+
+	// hoursLayout parses times in working hours
+	hoursLayout = "15:04"
+)
+
+// weekdays are abbreviations of working days, indexed by [time.Weekday].
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// defaultDeliveryPolicy is used for fields not set in configuration.
+var defaultDeliveryPolicy = DeliveryPolicy{
+	Mode:         policyModeOff,
+	WorkingHours: "09:00-17:00",
+	WorkingDays:  []string{"mon", "tue", "wed", "thu", "fri"},
+}
+
+// DeliveryPolicy keeps blasts within recipients' working hours and days, in their profile's timezone.
+// Empty fields inherit from configured or default policy.
+type DeliveryPolicy struct {
+	// Mode is "defer", "warn" or "off"
+	Mode string `json:"mode"`
+
+	// WorkingHours is a local time range, such as "09:00-17:00"
+	WorkingHours string `json:"working_hours"`
+
+	// WorkingDays are weekday abbreviations, such as "mon"
+	WorkingDays []string `json:"working_days"`
+}
+
+// merge returns copy of p with non-empty fields replaced from override.
+func (p DeliveryPolicy) merge(override DeliveryPolicy) DeliveryPolicy {
+	if override.Mode != "" {
+		p.Mode = override.Mode
+	}
+	if override.WorkingHours != "" {
+		p.WorkingHours = override.WorkingHours
+	}
+	if len(override.WorkingDays) > 0 {
+		p.WorkingDays = override.WorkingDays
+	}
+	return p
+}
+
+func (p DeliveryPolicy) validate() error {
+	switch p.Mode {
+	case "", policyModeOff, policyModeDefer, policyModeWarn:
+	default:
+		return fmt.Errorf("mode must be %q, %q or %q", policyModeDefer, policyModeWarn, policyModeOff)
+	}
+	if p.WorkingHours != "" {
+		if _, _, err := p.hours(); err != nil {
+			return err
+		}
+	}
+	for _, day := range p.WorkingDays {
+		if !slices.Contains(weekdays, day) {
+			return fmt.Errorf("invalid working day %q, expected one of %s", day, strings.Join(weekdays, ", "))
+		}
+	}
+	return nil
+}
+
+// hours parses working hours into minutes since midnight.
+func (p DeliveryPolicy) hours() (int, int, error) {
+	from, to, _ := strings.Cut(p.WorkingHours, "-")
+	start, err := time.Parse(hoursLayout, strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("working hours must be a range, such as 09:00-17:00")
+	}
+	end, err := time.Parse(hoursLayout, strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, fmt.Errorf("working hours must be a range, such as 09:00-17:00")
+	}
+	startMinutes, endMinutes := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if startMinutes >= endMinutes {
+		return 0, 0, fmt.Errorf("working hours must start before they end")
+	}
+	return startMinutes, endMinutes, nil
+}
+
+// enforced returns whether policy restricts when blasts are delivered.
+func (p DeliveryPolicy) enforced() bool {
+	return p.Mode == policyModeDefer || p.Mode == policyModeWarn
+}
+
+// allows returns whether t is within working hours on a working day in location.
+func (p DeliveryPolicy) allows(t time.Time, location *time.Location) bool {
+	start, end, _ := p.hours()
+	local := t.In(location)
+	minutes := local.Hour()*60 + local.Minute()
+	return slices.Contains(p.WorkingDays, weekdays[local.Weekday()]) && minutes >= start && minutes < end
+}
+
+// next returns the earliest time from t that's within working hours in location.
+func (p DeliveryPolicy) next(t time.Time, location *time.Location) time.Time {
+	if p.allows(t, location) {
+		return t
+	}
+	start, _, _ := p.hours()
+	local := t.In(location)
+	for i := 0; i <= len(weekdays); i++ {
+		day := local.AddDate(0, 0, i)
+		opens := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, location)
+		if opens.After(t) && slices.Contains(p.WorkingDays, weekdays[opens.Weekday()]) {
+			return opens.UTC()
+		}
+	}
+	return t
+}
+
+// deliveryPolicy returns policy for workspace, applying its overrides over configured policy.
+func (s *Server) deliveryPolicy(workspaceID string) DeliveryPolicy {
+	policy := defaultDeliveryPolicy.merge(s.config.DeliveryPolicy)
+	if override, ok := s.config.WorkspaceDeliveryPolicies[workspaceID]; ok && workspaceID != "" {
+		policy = policy.merge(override)
+	}
+	return policy
+}
+
+// applyDeliveryPolicy checks when recipients would receive blast against workspace's policy, returning
+// recipients to send to now, waves to send later and override to record. Recipients outside working hours
+// are deferred into waves at their next working hours in defer mode. In warn mode, they're rejected unless
+// the sender gives a reason, which sends to them anyway. Recipients without a timezone use the request's default.
+func (s *Server) applyDeliveryPolicy(workspaceID string, request *sendRequest, recipients []*resolvedUser, waves []*blastWave, now time.Time) ([]*resolvedUser, []*blastWave, *policyOverride, error) {
+	policy := s.deliveryPolicy(workspaceID)
+	if !policy.enforced() {
+		return recipients, waves, nil, nil
+	}
+	fallback := ""
+	if request.Schedule != nil {
+		fallback = request.Schedule.DefaultTimezone
+	}
+
+	outside := []string{}
+	for _, wave := range waves {
+		if _, location := userLocation(wave.Timezone, fallback); !policy.allows(wave.SendAt, location) {
+			outside = append(outside, wave.Users...)
+		}
+	}
+	for _, user := range recipients {
+		if _, location := userLocation(user.Timezone, fallback); !policy.allows(now, location) {
+			outside = append(outside, user.ID)
+		}
+	}
+	if len(outside) == 0 {
+		return recipients, waves, nil, nil
+	}
+
+	if policy.Mode == policyModeWarn {
+		if reason := strings.TrimSpace(request.OverrideReason); reason != "" {
+			return recipients, waves, &policyOverride{Reason: reason, Users: outside}, nil
+		}
+		return nil, nil, nil, errOutsideWorkingHours(len(outside))
+	}
+
+	for _, wave := range waves {
+		_, location := userLocation(wave.Timezone, fallback)
+		if next := policy.next(wave.SendAt, location); !next.Equal(wave.SendAt) {
+			wave.SendAt = next
+			wave.Deferred = true
+		}
+	}
+	inside := []*resolvedUser{}
+	deferred := map[string]*blastWave{}
+	for _, user := range recipients {
+		timezone, location := userLocation(user.Timezone, fallback)
+		if policy.allows(now, location) {
+			inside = append(inside, user)
+			continue
+		}
+		wave, ok := deferred[timezone]
+		if !ok {
+			wave = &blastWave{
+				Timezone: timezone,
+				SendAt:   policy.next(now, location),
+				Users:    []string{},
+				Status:   waveStatusPending,
+				Deferred: true,
+			}
+			deferred[timezone] = wave
+			waves = append(waves, wave)
+		}
+		wave.Users = append(wave.Users, user.ID)
+	}
+	sortWaves(waves)
+	return inside, waves, nil, nil
+}
+
+// policyOverride records why a blast was sent to recipients outside working hours.
+type policyOverride struct {
+	Reason string   `json:"reason"`
+	Users  []string `json:"users"`
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryPolicyValidate(t *testing.T) {
+	for _, test := range []struct {
+		policy        DeliveryPolicy
+		errorContains string
+	}{
+		{policy: DeliveryPolicy{}},
+		{policy: DeliveryPolicy{Mode: policyModeDefer, WorkingHours: "08:30-18:00", WorkingDays: []string{"sun", "mon"}}},
+		{policy: DeliveryPolicy{Mode: "block"}, errorContains: "mode must be"},
+		{policy: DeliveryPolicy{WorkingHours: "9-5"}, errorContains: "must be a range"},
+		{policy: DeliveryPolicy{WorkingHours: "17:00-09:00"}, errorContains: "start before"},
+		{policy: DeliveryPolicy{WorkingDays: []string{"monday"}}, errorContains: "invalid working day"},
+	} {
+		err := test.policy.validate()
+		if test.errorContains == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorContains(t, err, test.errorContains)
+		}
+	}
+}
+
+func TestDeliveryPolicyNext(t *testing.T) {
+	policy := defaultDeliveryPolicy.merge(DeliveryPolicy{Mode: policyModeDefer})
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	for _, test := range []struct {
+		t        time.Time
+		expected time.Time
+	}{
+		// Friday 2030-03-01 in working hours
+		{t: time.Date(2030, 3, 1, 10, 0, 0, 0, berlin), expected: time.Date(2030, 3, 1, 10, 0, 0, 0, berlin)},
+		// After hours on Friday and over the weekend
+		{t: time.Date(2030, 3, 1, 17, 0, 0, 0, berlin), expected: time.Date(2030, 3, 4, 9, 0, 0, 0, berlin)},
+		{t: time.Date(2030, 3, 3, 12, 0, 0, 0, berlin), expected: time.Date(2030, 3, 4, 9, 0, 0, 0, berlin)},
+		// Before hours on Tuesday
+		{t: time.Date(2030, 3, 5, 7, 0, 0, 0, berlin), expected: time.Date(2030, 3, 5, 9, 0, 0, 0, berlin)},
+	} {
+		assert.True(t, test.expected.Equal(policy.next(test.t, berlin)), "%s", test.t)
+	}
+}
+
+func TestApplyDeliveryPolicy(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	// Friday 17:30 in Berlin, 11:30 in New York and 01:30 on Saturday in Tokyo
+	now := time.Date(2030, 3, 1, 16, 30, 0, 0, time.UTC)
+	recipients := func() []*resolvedUser {
+		return []*resolvedUser{
+			{ID: "U1", Timezone: "Europe/Berlin"},
+			{ID: "U2", Timezone: "America/New_York"},
+			{ID: "U3"},
+			{ID: "U4", Timezone: "Asia/Tokyo"},
+		}
+	}
+
+	inside, waves, override, err := server.applyDeliveryPolicy("T1", &sendRequest{}, recipients(), nil, now)
+	if assert.NoError(t, err) {
+		assert.Len(t, inside, 4)
+		assert.Empty(t, waves)
+		assert.Nil(t, override)
+	}
+
+	server.config.WorkspaceDeliveryPolicies = map[string]DeliveryPolicy{"T1": {Mode: policyModeDefer}}
+	inside, waves, _, err = server.applyDeliveryPolicy("T1", &sendRequest{}, recipients(), nil, now)
+	if assert.NoError(t, err) && assert.Len(t, inside, 2) && assert.Len(t, waves, 2) {
+		assert.Equal(t, "U2", inside[0].ID)
+		assert.Equal(t, "U3", inside[1].ID)
+		assert.Equal(t, &blastWave{Timezone: "Asia/Tokyo", SendAt: time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC), Users: []string{"U4"}, Status: waveStatusPending, Deferred: true}, waves[0])
+		assert.Equal(t, &blastWave{Timezone: "Europe/Berlin", SendAt: time.Date(2030, 3, 4, 8, 0, 0, 0, time.UTC), Users: []string{"U1"}, Status: waveStatusPending, Deferred: true}, waves[1])
+	}
+
+	// Scheduled waves outside working hours are moved too
+	scheduled := []*blastWave{{Timezone: "Europe/Berlin", SendAt: time.Date(2030, 3, 2, 8, 0, 0, 0, time.UTC), Users: []string{"U1"}, Status: waveStatusPending}}
+	_, waves, _, err = server.applyDeliveryPolicy("T1", &sendRequest{}, nil, scheduled, now)
+	if assert.NoError(t, err) && assert.Len(t, waves, 1) {
+		assert.Equal(t, time.Date(2030, 3, 4, 8, 0, 0, 0, time.UTC), waves[0].SendAt)
+		assert.True(t, waves[0].Deferred)
+	}
+
+	// A reason doesn't override deferring
+	inside, waves, override, err = server.applyDeliveryPolicy("T1", &sendRequest{OverrideReason: "Outage"}, recipients(), nil, now)
+	if assert.NoError(t, err) {
+		assert.Len(t, inside, 2)
+		assert.Len(t, waves, 2)
+		assert.Nil(t, override)
+	}
+
+	// Recipients without a timezone use the request's default
+	inside, waves, _, err = server.applyDeliveryPolicy("T1", &sendRequest{Schedule: &scheduleRequest{DefaultTimezone: "Asia/Tokyo"}}, recipients(), nil, now)
+	if assert.NoError(t, err) && assert.Len(t, inside, 1) && assert.Len(t, waves, 2) {
+		assert.Equal(t, "U2", inside[0].ID)
+		assert.Equal(t, []string{"U3", "U4"}, waves[0].Users)
+	}
+
+	server.config.WorkspaceDeliveryPolicies = map[string]DeliveryPolicy{"T1": {Mode: policyModeWarn}}
+	_, _, _, err = server.applyDeliveryPolicy("T1", &sendRequest{OverrideReason: " "}, recipients(), nil, now)
+	assert.ErrorContains(t, err, "2 recipients are outside working hours")
+	inside, waves, override, err = server.applyDeliveryPolicy("T1", &sendRequest{OverrideReason: "Office closed tomorrow"}, recipients(), nil, now)
+	if assert.NoError(t, err) && assert.NotNil(t, override) {
+		assert.Len(t, inside, 4)
+		assert.Empty(t, waves)
+		assert.Equal(t, &policyOverride{Reason: "Office closed tomorrow", Users: []string{"U1", "U4"}}, override)
+	}
+}
+
+func TestSendDeliveryPolicy(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	mock := &mockSlackSession{ClientSession: &slack.ClientSession{Token: "xoxp-1", TeamID: "T1", User: "U9"}}
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+	assert.NoError(t, server.saveAuthorization(mock))
+
+	// Only the weekday three days from now is a working day, so recipients in UTC are outside working hours
+	workingDay := weekdays[time.Now().UTC().AddDate(0, 0, 3).Weekday()]
+	server.config.WorkspaceDeliveryPolicies = map[string]DeliveryPolicy{"T1": {Mode: policyModeWarn, WorkingDays: []string{workingDay}}}
+
+	request := func(body, id string, handler echo.HandlerFunc) *requestTester {
+		return serveAPI(t, server, mock, http.MethodPost, body, handler, "id", id)
+	}
+	send := func(body string) *requestTester {
+		return request(body, "", server.handleAPISend)
+	}
+
+	r := send(`{"users":["U1","U2"],"message":"Server maintenance"}`)
+	assert.Equal(t, http.StatusConflict, r.Response.Code)
+	assert.Equal(t, errorCodeOutsideHours, r.APIError().Code)
+	assert.Empty(t, mock.Posted)
+
+	r = send(`{"users":["U1","U2"],"message":"Server maintenance","override_reason":"Outage"}`)
+	var sent sendResponse
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) && assert.Len(t, sent.Sent, 2) {
+		overridden, err := server.blasts.Get(sent.Blast)
+		if assert.NoError(t, err) && assert.NotNil(t, overridden.PolicyOverride) {
+			assert.Equal(t, "Outage", overridden.PolicyOverride.Reason)
+		}
+	}
+
+	// Reminders follow the same policy
+	r = send(`{"users":["U1"],"message":"Fire drill","acknowledge":true,"override_reason":"Outage"}`)
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent))
+	mock.Posted = nil
+	r = request("", sent.Blast, server.handleAPIBlastRemind)
+	assert.Equal(t, http.StatusConflict, r.Response.Code)
+	assert.Empty(t, mock.Posted)
+	r = request(`{"override_reason":"Drill starts soon"}`, sent.Blast, server.handleAPIBlastRemind)
+	var reminded sendResponse
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &reminded)) && assert.Equal(t, []string{"U1"}, reminded.Sent) {
+		reminder, err := server.blasts.Get(reminded.Blast)
+		if assert.NoError(t, err) && assert.NotNil(t, reminder.PolicyOverride) {
+			assert.Equal(t, "Drill starts soon", reminder.PolicyOverride.Reason)
+		}
+	}
+
+	// Deferred recipients are sent to when their working hours start
+	mock.Posted = nil
+	server.config.WorkspaceDeliveryPolicies["T1"] = DeliveryPolicy{Mode: policyModeDefer, WorkingDays: []string{workingDay}}
+	r = send(`{"users":["U1"],"message":"Team lunch"}`)
+	if !assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) || !assert.Len(t, sent.Waves, 1) {
+		return
+	}
+	assert.Empty(t, sent.Sent)
+	assert.True(t, sent.Waves[0].Deferred)
+	assert.Equal(t, 9, sent.Waves[0].SendAt.Hour())
+	assert.Empty(t, mock.Posted)
+
+	server.dispatchWaves(sent.Waves[0].SendAt)
+	assert.Contains(t, mock.Posted["U1"], "Team lunch")
+	deferred, err := server.blasts.Get(sent.Blast)
+	if assert.NoError(t, err) {
+		assert.Equal(t, blastStatusCompleted, deferred.Status)
+		assert.Empty(t, deferred.Schedule.Date)
+	}
+}
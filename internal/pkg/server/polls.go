@@ -0,0 +1,168 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	// pollBlockID identifies the actions block holding the poll's buttons.
+	pollBlockID = "blast_poll"
+
+	pollMinOptions   = 2
+	pollMaxOptions   = 10
+	pollOptionLength = 75
+)
+
+// pollActions creates a button for each poll option, identified by its index after [actionBlastVote].
+func pollActions(blastID string, options []string) *block {
+	elements := make([]*blockElement, len(options))
+	for i, option := range options {
+		elements[i] = button(actionBlastVote+strconv.Itoa(i), option, blastID, "")
+	}
+	return actionsBlock(pollBlockID, elements...)
+}
+
+// handleActionBlastVote records recipient's vote, replacing an earlier one. When poll marks votes,
+// the buttons are replaced with the choice, otherwise it's confirmed privately and can be changed.
+func (s *Server) handleActionBlastVote(callback slack.InteractionCallback, actionID, id string) {
+	user := callback.User.ID
+	option, err := strconv.Atoi(strings.TrimPrefix(actionID, actionBlastVote))
+	if err != nil {
+		return
+	}
+
+	blast, err := s.blasts.Update(id, func(blast *blast) error {
+		if blast.WorkspaceID != callback.Team.ID || !slices.Contains(blast.Sent, user) ||
+			blast.Poll == nil || option < 0 || option >= len(blast.Poll.Options) {
+			return errNotFound()
+		}
+		if blast.Poll.Votes == nil {
+			blast.Poll.Votes = map[string]int{}
+		}
+		blast.Poll.Votes[user] = option
+		return nil
+	})
+	if err != nil {
+		s.config.Logger.Warn("failed to vote", zap.String("blast", id), zap.String("user", user), zap.Error(err))
+		if err := slack.Respond(callback.ResponseURL, ephemeral("Failed to vote, the announcement may have been deleted.")); err != nil {
+			s.config.Logger.Warn("failed to respond to interaction", zap.Error(err))
+		}
+		return
+	}
+
+	text := fmt.Sprintf("You voted *%s*", blast.Poll.Options[option])
+	if blast.Poll.MarkVoted {
+		s.respond(callback, replaceBlock(callback, pollBlockID, sectionBlock(":ballot_box_with_check: "+text)))
+		return
+	}
+	if err := slack.Respond(callback.ResponseURL, ephemeral(text+", vote again to change it.")); err != nil {
+		s.config.Logger.Warn("failed to respond to interaction", zap.Error(err))
+	}
+}
+
+// handleAPIBlastPollGet handles GET /api/blasts/:id/poll, tallying blast's poll votes.
+func (s *Server) handleAPIBlastPollGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	blast, err := s.blasts.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if blast == nil || blast.WorkspaceID != session.WorkspaceID() {
+		return errNotFound()
+	} else if blast.Poll == nil {
+		return errBadRequest("blast doesn't have a poll")
+	}
+
+	return c.JSON(http.StatusOK, newPollResults(blast))
+}
+
+// newPollResults tallies votes for each of blast's poll options.
+func newPollResults(blast *blast) *pollResults {
+	results := &pollResults{
+		Options: make([]*pollTally, len(blast.Poll.Options)),
+		Pending: []string{},
+	}
+	for i, option := range blast.Poll.Options {
+		results.Options[i] = &pollTally{Option: option, Users: []string{}}
+	}
+	for _, user := range blast.Sent {
+		option, ok := blast.Poll.Votes[user]
+		if !ok || option >= len(results.Options) {
+			results.Pending = append(results.Pending, user)
+			continue
+		}
+		results.Options[option].Votes++
+		results.Options[option].Users = append(results.Options[option].Users, user)
+		results.Voted++
+	}
+	for _, tally := range results.Options {
+		if results.Voted > 0 {
+			tally.Percent = tally.Votes * 100 / results.Voted
+		}
+	}
+	return results
+}
+
+// poll is a single-choice question asked with blast.
+type poll struct {
+	Options []string `json:"options"`
+
+	// MarkVoted replaces the buttons in a recipient's message with their vote
+	MarkVoted bool `json:"mark_voted,omitempty"`
+
+	// Votes maps users to the index of the option they voted for
+	Votes map[string]int `json:"votes,omitempty"`
+}
+
+type pollRequest struct {
+	Options   []string `json:"options"`
+	MarkVoted bool     `json:"mark_voted,omitempty"`
+}
+
+func (r *pollRequest) validate() error {
+	if r == nil {
+		return nil
+	}
+	if len(r.Options) < pollMinOptions || len(r.Options) > pollMaxOptions {
+		return fmt.Errorf("poll must have between %d and %d options", pollMinOptions, pollMaxOptions)
+	}
+	for i, option := range r.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return fmt.Errorf("empty poll option")
+		}
+		if utf8.RuneCountInString(option) > pollOptionLength {
+			return fmt.Errorf("poll option %q is longer than %d characters", option, pollOptionLength)
+		}
+		if slices.Contains(r.Options[:i], option) {
+			return fmt.Errorf("duplicate poll option %q", option)
+		}
+		r.Options[i] = option
+	}
+	return nil
+}
+
+type pollResults struct {
+	Options []*pollTally `json:"options"`
+	Voted   int          `json:"voted"`
+	Pending []string     `json:"pending"`
+}
+
+type pollTally struct {
+	Option  string   `json:"option"`
+	Votes   int      `json:"votes"`
+	Percent int      `json:"percent"`
+	Users   []string `json:"users"`
+}
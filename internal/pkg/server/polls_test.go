@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollRequestValidate(t *testing.T) {
+	for _, test := range []struct {
+		request       *pollRequest
+		errorContains string
+	}{
+		{request: nil},
+		{request: &pollRequest{Options: []string{" Yes ", "No"}}},
+		{request: &pollRequest{Options: []string{"Yes"}}, errorContains: "between 2 and 10"},
+		{request: &pollRequest{Options: strings.Split("abcdefghijk", "")}, errorContains: "between 2 and 10"},
+		{request: &pollRequest{Options: []string{"Yes", " "}}, errorContains: "empty poll option"},
+		{request: &pollRequest{Options: []string{"Yes", "Yes "}}, errorContains: "duplicate"},
+		{request: &pollRequest{Options: []string{"Yes", strings.Repeat("n", 76)}}, errorContains: "longer than 75"},
+	} {
+		err := test.request.validate()
+		if test.errorContains == "" {
+			assert.NoError(t, err)
+			if test.request != nil {
+				assert.Equal(t, []string{"Yes", "No"}, test.request.Options)
+			}
+		} else {
+			assert.ErrorContains(t, err, test.errorContains)
+		}
+	}
+}
+
+func TestBlastPoll(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	mock := newMockSession("T1", "U9")
+	request := func(method, body, id string, handler echo.HandlerFunc) *requestTester {
+		return serveAPI(t, server, mock, method, body, handler, "id", id)
+	}
+
+	r := request(http.MethodPost, `{"users":["U1"],"message":"Lunch?","poll":{"options":["Pizza"]}}`, "", server.handleAPISend)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+	assert.Empty(t, mock.Posted)
+
+	r = request(http.MethodPost, `{"users":["U1","U2","U3"],"message":"Lunch?","acknowledge":true,"poll":{"options":["Pizza","Sushi"],"mark_voted":true}}`, "", server.handleAPISend)
+	var sent sendResponse
+	if !assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) || !assert.Len(t, sent.Sent, 3) {
+		return
+	}
+	var blocks []*block
+	if assert.NoError(t, json.Unmarshal([]byte(mock.PostedBlocks["U1"]), &blocks)) && assert.Len(t, blocks, 3) {
+		assert.Equal(t, pollBlockID, blocks[1].BlockID)
+		if assert.Len(t, blocks[1].Elements, 2) {
+			assert.Equal(t, "Sushi", blocks[1].Elements[1].Text.Text)
+			assert.Equal(t, actionBlastVote+"1", blocks[1].Elements[1].ActionID)
+		}
+		assert.Equal(t, ackBlockID, blocks[2].BlockID)
+	}
+
+	responseURL, responses := newResponseURL()
+	defer responseURL.Close()
+
+	vote := func(user, actionID string) *commandResponse {
+		blocks := mock.PostedBlocks[user]
+		if blocks == "" {
+			blocks = "[]"
+		}
+		r := newInteractionTester(server, map[string]interface{}{
+			"type":         "block_actions",
+			"team":         map[string]string{"id": "T1"},
+			"user":         map[string]string{"id": user},
+			"response_url": responseURL.URL,
+			"message": map[string]interface{}{
+				"text":   mock.Posted[user],
+				"blocks": json.RawMessage(blocks),
+			},
+			"actions": []map[string]string{{"block_id": pollBlockID, "action_id": actionID, "value": sent.Blast}},
+		})
+		assert.NoError(t, server.handleSlackInteraction(r.Context))
+		return <-responses
+	}
+
+	response := vote("U1", actionBlastVote+"1")
+	assert.True(t, response.ReplaceOriginal)
+	if assert.Len(t, response.Blocks, 3) {
+		assert.Contains(t, response.Blocks[1].Text.Text, "You voted *Sushi*")
+		assert.Equal(t, ackBlockID, response.Blocks[2].BlockID)
+	}
+	vote("U2", actionBlastVote+"0")
+	vote("U3", actionBlastVote+"1")
+	vote("U3", actionBlastVote+"0")
+	assert.Contains(t, vote("U3", actionBlastVote+"5").Text, "Failed")
+	assert.Contains(t, vote("U4", actionBlastVote+"0").Text, "Failed")
+
+	r = request(http.MethodGet, "", sent.Blast, server.handleAPIBlastPollGet)
+	var results pollResults
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &results)) && assert.Len(t, results.Options, 2) {
+		assert.Equal(t, 3, results.Voted)
+		assert.Empty(t, results.Pending)
+		assert.Equal(t, &pollTally{Option: "Pizza", Votes: 2, Percent: 66, Users: []string{"U2", "U3"}}, results.Options[0])
+		assert.Equal(t, &pollTally{Option: "Sushi", Votes: 1, Percent: 33, Users: []string{"U1"}}, results.Options[1])
+	}
+
+	// Without marking votes, the message stays so that the vote can change
+	r = request(http.MethodPost, `{"users":["U1"],"message":"Dinner?","poll":{"options":["Yes","No"]}}`, "", server.handleAPISend)
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent))
+	response = vote("U1", actionBlastVote+"0")
+	assert.False(t, response.ReplaceOriginal)
+	assert.Contains(t, response.Text, "You voted *Yes*")
+
+	r = request(http.MethodGet, "", sent.Blast, server.handleAPIBlastAcksGet)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// channelIDReg matches ID of a public or private channel, such as "C0123456789".
+var channelIDReg = regexp.MustCompile(`^[CG][A-Z0-9]{2,}$`)
+
+// handleSlackEvent handles /slack/events, the Events API request URL.
+// Slack retries events that aren't acknowledged within seconds, so slow work is done in the background.
+func (s *Server) handleSlackEvent(c echo.Context) error {
+	event, err := slack.ParseEvent(c.Request(), s.config.SlackSigningSecret)
+	if err != nil {
+		s.config.Logger.Warn("rejected event", zap.Error(err))
+		return c.String(http.StatusUnauthorized, err.Error())
+	}
+
+	switch event.Type {
+	case slack.EventURLVerification:
+		return c.String(http.StatusOK, event.Challenge)
+	case slack.EventCallback:
+		if event.Message != nil {
+			if err := s.handleMessageEvent(event.TeamID, event.Message); err != nil {
+				return c.String(http.StatusInternalServerError, err.Error())
+			}
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// handleMessageEvent records recipient's direct message in a blast's thread as reply to it,
+// then forwards it if blast asked to.
+func (s *Server) handleMessageEvent(workspaceID string, message *slack.MessageEvent) error {
+	if message.ChannelType != "im" || message.SubType != "" || message.BotID != "" || message.User == "" {
+		return nil
+	}
+
+	blast, err := s.replyBlast(workspaceID, message)
+	if err != nil || blast == nil {
+		return err
+	}
+
+	// Retried events are recorded once
+	id := replyKey(message.Channel, message.TimeStamp)
+	if existing, err := s.replies.Get(id); err != nil || existing != nil {
+		return err
+	}
+	reply := &blastReply{
+		ID:        id,
+		BlastID:   blast.ID,
+		User:      message.User,
+		Text:      message.Text,
+		Timestamp: message.TimeStamp,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.replies.Put(reply.ID, reply); err != nil {
+		return err
+	}
+
+	if blast.ForwardChannel != "" {
+		s.dispatchWG.Add(1)
+		go func() {
+			defer s.dispatchWG.Done()
+			if err := s.forwardReply(blast.ID, reply); err != nil {
+				s.config.Logger.Warn("failed to forward reply", zap.String("blast", blast.ID), zap.String("reply", reply.ID), zap.Error(err))
+			}
+		}()
+	}
+	return nil
+}
+
+// replyBlast finds workspace's blast that message replies to in its thread, nil if it isn't one.
+// Messages outside a blast's thread are never replies, because the conversation can be the sender's
+// personal one with the recipient, even when blast isn't sent as them.
+func (s *Server) replyBlast(workspaceID string, message *slack.MessageEvent) (*blast, error) {
+	if message.ThreadTimeStamp == "" {
+		return nil, nil
+	}
+	post, err := s.posts.Get(replyKey(message.Channel, message.ThreadTimeStamp))
+	if err != nil || post == nil || post.User != message.User {
+		return nil, err
+	}
+
+	blast, err := s.blasts.Get(post.BlastID)
+	if err != nil || blast == nil || blast.WorkspaceID != workspaceID {
+		return nil, err
+	}
+	return blast, nil
+}
+
+// indexPost records blast's message posted to user, so that replies in its thread find it without listing history.
+// Replies to reminders count towards the blast they remind about.
+func (s *Server) indexPost(blast *blast, user string, posted *slack.PostedMessage) error {
+	post := &blastPost{BlastID: blast.ID, User: user, Timestamp: posted.Timestamp}
+	if blast.ReminderOf != "" {
+		post.BlastID = blast.ReminderOf
+	}
+	return s.posts.Put(replyKey(posted.Channel, posted.Timestamp), post)
+}
+
+// forwardReply posts reply in blast's thread in the forwarding channel, starting the thread with the first reply.
+// Forwarding is serialised, so that concurrent replies don't start separate threads.
+func (s *Server) forwardReply(blastID string, reply *blastReply) error {
+	s.forwardMu.Lock()
+	defer s.forwardMu.Unlock()
+
+	target, err := s.blasts.Get(blastID)
+	if err != nil || target == nil {
+		return err
+	}
+	session, err := s.userSession(target.WorkspaceID, target.CreatedBy)
+	if err != nil {
+		return err
+	} else if session == nil {
+		return fmt.Errorf("sender hasn't signed in")
+	}
+
+	if target.ForwardThread == "" {
+		posted, err := session.PostChannelMessage(target.ForwardChannel, slack.Message{
+			Text: fmt.Sprintf("Replies to the announcement sent on %s:\n%s", target.CreatedAt.Format("2 Jan 2006 15:04 MST"), quote(target.Message)),
+		})
+		if err != nil {
+			return err
+		}
+		target, err = s.blasts.Update(target.ID, func(blast *blast) error {
+			blast.ForwardThread = posted.Timestamp
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	posted, err := session.PostChannelMessage(target.ForwardChannel, slack.Message{
+		Text:            fmt.Sprintf("<@%s> replied:\n%s", reply.User, quote(reply.Text)),
+		ThreadTimestamp: target.ForwardThread,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.replies.Update(reply.ID, func(reply *blastReply) error {
+		reply.ForwardedTimestamp = posted.Timestamp
+		return nil
+	})
+	return err
+}
+
+// handleAPIBlastRepliesGet handles GET /api/blasts/:id/replies, listing recipients' replies to blast, oldest first.
+// Replies are private to the sender.
+func (s *Server) handleAPIBlastRepliesGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	blast, err := s.blasts.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if blast == nil || blast.WorkspaceID != session.WorkspaceID() || blast.CreatedBy != session.UserID() {
+		return errNotFound()
+	}
+
+	replies, err := s.blastReplies(blast.ID)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, replies)
+}
+
+// blastReplies lists replies to blast, oldest first.
+func (s *Server) blastReplies(blastID string) ([]*blastReply, error) {
+	replies, err := s.replies.List(func(reply *blastReply) bool {
+		return reply.BlastID == blastID
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(replies, func(i, j int) bool {
+		return timestampBefore(replies[i].Timestamp, replies[j].Timestamp)
+	})
+	return replies, nil
+}
+
+// timestampBefore compares Slack message timestamps, such as "1700000000.000100",
+// which are too precise to compare as floats.
+func timestampBefore(a, b string) bool {
+	aSeconds, aMicros, _ := strings.Cut(a, ".")
+	bSeconds, bMicros, _ := strings.Cut(b, ".")
+	as, _ := strconv.ParseInt(aSeconds, 10, 64)
+	bs, _ := strconv.ParseInt(bSeconds, 10, 64)
+	if as != bs {
+		return as < bs
+	}
+	am, _ := strconv.ParseInt(aMicros, 10, 64)
+	bm, _ := strconv.ParseInt(bMicros, 10, 64)
+	return am < bm
+}
+
+func replyKey(channel, timestamp string) string {
+	return channel + "/" + timestamp
+}
+
+// blastReply is a direct message from a recipient in blast's thread.
+type blastReply struct {
+	ID        string    `json:"id"`
+	BlastID   string    `json:"blast_id"`
+	User      string    `json:"user"`
+	Text      string    `json:"text"`
+	Timestamp string    `json:"ts"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// ForwardedTimestamp identifies reply forwarded to blast's forwarding channel
+	ForwardedTimestamp string `json:"forwarded_ts,omitempty"`
+}
+
+// blastPost identifies blast posted to a recipient, indexed by conversation and timestamp.
+type blastPost struct {
+	BlastID   string `json:"blast_id"`
+	User      string `json:"user"`
+	Timestamp string `json:"ts"`
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampBefore(t *testing.T) {
+	assert.True(t, timestampBefore("1700000000.000100", "1700000000.000200"))
+	assert.True(t, timestampBefore("1700000000.999999", "1700000001.000000"))
+	assert.True(t, timestampBefore("9.000", "10.000"))
+	assert.False(t, timestampBefore("1700000000.000200", "1700000000.000200"))
+	assert.False(t, timestampBefore("1700000001.000000", "1700000000.999999"))
+}
+
+func TestHandleSlackEvent(t *testing.T) {
+	body := `{"type":"url_verification","challenge":"c1"}`
+	r := newRequestTester(http.MethodPost, "/slack/events", strings.NewReader(body))
+	slack.SignRequest(r.Request, mockSigningSecret, body, time.Now())
+	if assert.NoError(t, r.Server.handleSlackEvent(r.Context)) {
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+		assert.Equal(t, "c1", r.Response.Body.String())
+	}
+
+	r = newRequestTester(http.MethodPost, "/slack/events", strings.NewReader(body))
+	if assert.NoError(t, r.Server.handleSlackEvent(r.Context)) {
+		assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+	}
+}
+
+func TestBlastReplies(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api/send", strings.NewReader(`{"users":["U1"],"message":"Hi","forward_replies":"#general"}`))
+	server := r.Server
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.User = "U9"
+	mock := r.Session
+	assert.NoError(t, r.Handle(server.handleAPISend))
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+	assert.NoError(t, server.saveAuthorization(mock))
+
+	send := func(body string) string {
+		r := newRequestTester(http.MethodPost, "/api/send", strings.NewReader(body))
+		r.Server = server
+		r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.Context.Set(cookieSession, mock)
+		assert.NoError(t, r.Handle(server.handleAPISend))
+		var response sendResponse
+		assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &response), r.Response.Body.String())
+		return response.Blast
+	}
+	first := send(`{"users":["U1","U2"],"message":"Fire drill at 3pm","forward_replies":"C0123"}`)
+	second := send(`{"users":["U1"],"message":"Fire drill moved to 4pm"}`)
+	blasts := map[string]*blast{}
+	for _, id := range []string{first, second} {
+		b, err := server.blasts.Get(id)
+		if !assert.NoError(t, err) || !assert.NotNil(t, b) {
+			return
+		}
+		blasts[id] = b
+	}
+
+	event := func(team string, message map[string]string) {
+		encoded, _ := json.Marshal(map[string]interface{}{
+			"type":    "event_callback",
+			"team_id": team,
+			"event":   message,
+		})
+		r := newRequestTester(http.MethodPost, "/slack/events", strings.NewReader(string(encoded)))
+		r.Server = server
+		slack.SignRequest(r.Request, mockSigningSecret, string(encoded), time.Now())
+		assert.NoError(t, server.handleSlackEvent(r.Context))
+		assert.Equal(t, http.StatusOK, r.Response.Code)
+		server.dispatchWG.Wait()
+	}
+	message := func(blastID, user, text, ts string, threaded bool) map[string]string {
+		posted := blasts[blastID].Messages[user]
+		threadTS := ""
+		if threaded {
+			threadTS = posted.Timestamp
+		}
+		return map[string]string{
+			"type": "message", "channel_type": "im", "channel": posted.Channel,
+			"user": user, "text": text, "ts": ts, "thread_ts": threadTS,
+		}
+	}
+
+	// In each blast's thread
+	event("T1", message(first, "U1", "Which floor?", "100.000", true))
+	event("T1", message(second, "U1", "Thanks for the update", "100.000100", true))
+	event("T1", message(first, "U2", "Got it", "101.000", true))
+
+	// Retried, outside a thread, from another workspace, by a bot and edits aren't replies
+	event("T1", message(first, "U2", "Got it", "101.000", true))
+	event("T1", message(first, "U2", "Are you free for lunch?", "101.000100", false))
+	event("T2", message(first, "U1", "Hello", "102.000", true))
+	botMessage := message(first, "U1", "Beep", "103.000", true)
+	botMessage["bot_id"] = "B1"
+	event("T1", botMessage)
+	edit := message(first, "U1", "Edited", "104.000", true)
+	edit["subtype"] = "message_changed"
+	event("T1", edit)
+
+	replies, err := server.blastReplies(first)
+	if assert.NoError(t, err) && assert.Len(t, replies, 2) {
+		assert.Equal(t, "Which floor?", replies[0].Text)
+		assert.Equal(t, "U2", replies[1].User)
+		assert.NotEmpty(t, replies[1].ForwardedTimestamp)
+	}
+	replies, err = server.blastReplies(second)
+	if assert.NoError(t, err) && assert.Len(t, replies, 1) {
+		assert.Equal(t, "Thanks for the update", replies[0].Text)
+		assert.Empty(t, replies[0].ForwardedTimestamp)
+	}
+
+	forwarded, err := server.blasts.Get(first)
+	if assert.NoError(t, err) && assert.NotEmpty(t, forwarded.ForwardThread) {
+		assert.Equal(t, forwarded.ForwardThread, mock.PostedThreads["C0123"])
+		assert.Equal(t, "<@U2> replied:\n> Got it", mock.Posted["C0123"])
+	}
+
+	// Replies are private to the sender
+	r = serveAPI(t, server, mock, http.MethodGet, "", server.handleAPIBlastRepliesGet, "id", first)
+	assert.Contains(t, r.Response.Body.String(), "Which floor?")
+	r = serveAPI(t, server, newMockSession("T1", "U1"), http.MethodGet, "", server.handleAPIBlastRepliesGet, "id", first)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+)
+
+// userIDReg matches Slack user IDs, including Enterprise Grid ones.
+var userIDReg = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// handleAPIResolve handles /api/resolve.
+func (s *Server) handleAPIResolve(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request resolveRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+
+	resolution, err := s.resolveRecipients(session, request.IDs)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	return c.JSON(http.StatusOK, resolution)
+}
+
+// resolveRecipients expands user, user group, channel and saved list IDs into unique active users.
+// Membership is retrieved live rather than from the destination cache, so the result is the exact audience.
+// User groups whose membership changed since they were cached, and therefore suggested, produce warnings.
+func (s *Server) resolveRecipients(session slack.Session, ids []string) (*resolution, error) {
+	resolver := &recipientResolver{
+		server:  s,
+		session: session,
+		visited: map[string]bool{},
+		result: &resolution{
+			Users:   []*resolvedUser{},
+			Skipped: []*recipientSkip{},
+		},
+	}
+	r := resolver.result
+
+	candidates := []string{}
+	seen := map[string]bool{}
+	for _, id := range uniqueStrings(ids) {
+		members, err := resolver.expand(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			if !seen[member] {
+				seen[member] = true
+				candidates = append(candidates, member)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return r, nil
+	}
+
+	active, err := session.GetActiveUsers(candidates)
+	if err != nil {
+		return nil, err
+	}
+	activeLookup := map[string]*slack.Destination{}
+	for _, user := range active {
+		activeLookup[user.ID] = user
+	}
+
+	for _, id := range candidates {
+		user, ok := activeLookup[id]
+		if !ok {
+			r.Skipped = append(r.Skipped, &recipientSkip{User: id, Reason: "Deactivated or bot"})
+			continue
+		}
+		r.Users = append(r.Users, &resolvedUser{
+			ID:       user.ID,
+			Label:    suggestionLabel(user.Name, user.DisplayName),
+			Timezone: user.Timezone,
+		})
+	}
+	r.Count = len(r.Users)
+
+	return r, nil
+}
+
+// recipientResolver holds state while expanding recipient IDs.
+type recipientResolver struct {
+	server  *Server
+	session slack.Session
+	visited map[string]bool
+	result  *resolution
+
+	// Lazily loaded from destination cache
+	groups map[string]*slack.Destination
+	users  map[string]bool
+
+	// Lazily loaded live, once for all user groups
+	groupMembers map[string][]string
+}
+
+// expand returns user IDs for a recipient ID, recording unrecognized IDs as skipped.
+// Saved lists may contain user groups, channels and other lists, each expanded at most once.
+func (r *recipientResolver) expand(id string) ([]string, error) {
+	if r.visited[id] {
+		return nil, nil
+	}
+	r.visited[id] = true
+
+	switch {
+	case strings.HasPrefix(id, listIDPrefix):
+		list, err := r.server.workspaceList(r.session, id)
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			break
+		}
+		members := []string{}
+		for _, member := range list.Users {
+			expanded, err := r.expand(member)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, expanded...)
+		}
+		return members, nil
+	case strings.HasPrefix(id, "U"), strings.HasPrefix(id, "W"):
+		return []string{id}, nil
+	case strings.HasPrefix(id, "S"):
+		members, err := r.userGroupMembers(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", id, err)
+		}
+		if err := r.checkMembership(id, members); err != nil {
+			return nil, err
+		}
+		return members, nil
+	case strings.HasPrefix(id, "C"), strings.HasPrefix(id, "G"):
+		members, err := r.session.GetChannelMembers(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand %s: %w", id, err)
+		}
+		return members, nil
+	}
+
+	r.result.Skipped = append(r.result.Skipped, &recipientSkip{User: id, Reason: "Unrecognized ID"})
+	return nil, nil
+}
+
+// userGroupMembers returns live members of a user group, retrieving all groups on first use.
+func (r *recipientResolver) userGroupMembers(id string) ([]string, error) {
+	if r.groupMembers == nil {
+		groupMembers, err := r.session.GetUserGroupMembers()
+		if err != nil {
+			return nil, err
+		}
+		r.groupMembers = groupMembers
+	}
+	members, ok := r.groupMembers[id]
+	if !ok {
+		return nil, fmt.Errorf("user group not found")
+	}
+	return members, nil
+}
+
+// checkMembership compares live user group members against cached ones and records a warning if they differ.
+// Members missing from the directory, such as bots, are ignored because they are never cached.
+func (r *recipientResolver) checkMembership(id string, live []string) error {
+	if r.groups == nil {
+		destinations, err := r.session.GetDestinations()
+		if err != nil {
+			return err
+		}
+		r.groups = map[string]*slack.Destination{}
+		r.users = map[string]bool{}
+		for _, dest := range destinations {
+			switch dest.Type {
+			case "usergroup":
+				r.groups[dest.ID] = dest
+			case "user":
+				r.users[dest.ID] = true
+			}
+		}
+	}
+
+	group, ok := r.groups[id]
+	if !ok {
+		return nil
+	}
+
+	cached := map[string]bool{}
+	for _, child := range group.Children {
+		cached[child.ID] = true
+	}
+	change := &membershipChange{
+		ID:      id,
+		Name:    group.Name,
+		Added:   []string{},
+		Removed: []string{},
+	}
+	current := map[string]bool{}
+	for _, member := range live {
+		current[member] = true
+		if r.users[member] && !cached[member] {
+			change.Added = append(change.Added, member)
+		}
+	}
+	for _, child := range group.Children {
+		if !current[child.ID] {
+			change.Removed = append(change.Removed, child.ID)
+		}
+	}
+
+	if len(change.Added) > 0 || len(change.Removed) > 0 {
+		r.result.Warnings = append(r.result.Warnings, change)
+	}
+	return nil
+}
+
+type resolveRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// resolution is the final audience after expansion and deduplication.
+type resolution struct {
+	Users    []*resolvedUser     `json:"users"`
+	Count    int                 `json:"count"`
+	Skipped  []*recipientSkip    `json:"skipped"`
+	Warnings []*membershipChange `json:"warnings,omitempty"`
+}
+
+// membershipChange describes how a user group changed since it was suggested.
+type membershipChange struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type resolvedUser struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+
+	// Timezone is from user's profile, such as "Europe/Berlin", used to schedule blasts in their local time
+	Timezone string `json:"tz,omitempty"`
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRecipients(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/api", nil)
+	r.Authenticate("1", "")
+	r.Session.TeamID = "T1"
+	r.Session.UserGroupMembers = map[string][]string{"S1": {"U2", "U3", "B1"}, "S2": {"U3", "U6"}}
+	r.Session.ChannelMembers = map[string][]string{"C1": {"U4", "U1"}}
+	r.Session.InactiveUsers = []string{"B1", "U5"}
+	assert.NoError(t, r.Server.lists.Put("L1", &recipientList{ID: "L1", WorkspaceID: "T1", Users: []string{"U5", "U6"}}))
+	assert.NoError(t, r.Server.lists.Put("L2", &recipientList{ID: "L2", WorkspaceID: "T2", Users: []string{"U7"}}))
+
+	resolution, err := r.Server.resolveRecipients(r.Session, []string{"U1", "S1", "C1", "L1", "L2", "X1", "U1", "", "S2"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	actualIDs := []string{}
+	for _, user := range resolution.Users {
+		actualIDs = append(actualIDs, user.ID)
+	}
+	assert.Equal(t, []string{"U1", "U2", "U3", "U4", "U6"}, actualIDs)
+	assert.Equal(t, 5, resolution.Count)
+	assert.Equal(t, []*recipientSkip{
+		{User: "L2", Reason: "Unrecognized ID"},
+		{User: "X1", Reason: "Unrecognized ID"},
+		{User: "B1", Reason: "Deactivated or bot"},
+		{User: "U5", Reason: "Deactivated or bot"},
+	}, resolution.Skipped)
+	assert.Equal(t, 1, r.Session.GetUserGroupMembersCalls, "user groups retrieved per group")
+
+	_, err = r.Server.resolveRecipients(r.Session, []string{"S9"})
+	assert.ErrorContains(t, err, "failed to expand S9: user group not found")
+}
+
+func TestResolveRecipientsMembershipChange(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/api", nil)
+	r.Authenticate("1", "")
+	r.Session.TeamID = "T1"
+	r.Session.Destinations = testDestinations()
+	r.Session.UserGroupMembers = map[string][]string{"S1": {"U2", "U3", "B1"}}
+	r.Session.InactiveUsers = []string{"B1"}
+	assert.NoError(t, r.Server.lists.Put("L1", &recipientList{ID: "L1", WorkspaceID: "T1", Users: []string{"S1", "L2"}}))
+	assert.NoError(t, r.Server.lists.Put("L2", &recipientList{ID: "L2", WorkspaceID: "T1", Users: []string{"L1", "U1"}}))
+
+	resolution, err := r.Server.resolveRecipients(r.Session, []string{"L1", "S1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 3, resolution.Count)
+	assert.Equal(t, []*membershipChange{
+		{ID: "S1", Name: "On-call", Added: []string{"U3"}, Removed: []string{"U1"}},
+	}, resolution.Warnings)
+}
+
+func TestResolveRecipientsError(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/api", nil)
+	r.Authenticate("1", "")
+	r.Session.GetMembersError = errors.New("simulated")
+
+	_, err := r.Server.resolveRecipients(r.Session, []string{"S1"})
+	assert.ErrorContains(t, err, "simulated")
+}
+
+func TestHandleAPIResolve(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader(`{"ids":["S1","U1"]}`))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Authenticate("1", "")
+	r.Session.UserGroupMembers = map[string][]string{"S1": {"U1", "U2"}}
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPIResolve)) && assert.Equal(t, http.StatusOK, r.Response.Code) {
+		var response resolution
+		if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &response)) {
+			assert.Equal(t, 2, response.Count)
+		}
+	}
+}
+
+func TestHandleAPIResolveUnauthenticated(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api", nil)
+
+	if assert.NoError(t, r.Handle(r.Server.handleAPIResolve)) {
+		assert.Equal(t, http.StatusUnauthorized, r.Response.Code)
+	}
+}
@@ -0,0 +1,439 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+	"time"
+
+	// Timezone database is embedded, as slim images don't have one to schedule in recipients' timezones
+	_ "time/tzdata"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	waveStatusPending  = "pending"
+	waveStatusSending  = "sending"
+	waveStatusSent     = "sent"
+	waveStatusCanceled = "canceled"
+	waveStatusFailed   = "failed"
+
+	// scheduleLayout parses schedule's local date and time
+	scheduleLayout = "2006-01-02 15:04"
+
+	// schedulerInterval is how often scheduled blasts are checked for waves that are due
+	schedulerInterval = 30 * time.Second
+)
+
+// errWaveClaimed stops sending a wave that's already sent or canceled.
+var errWaveClaimed = errors.New("wave already claimed")
+
+// scheduleBlast saves waves for blast to be sent in by the scheduler, along with outcome of recipients sent to already.
+// Request is nil when blast wasn't scheduled, but some recipients were deferred by workspace's policy.
+func (s *Server) scheduleBlast(c echo.Context, id string, request *scheduleRequest, waves []*blastWave, variables map[string]map[string]string, response *sendResponse) error {
+	// Only variables of scheduled recipients are kept until they're sent
+	kept := map[string]map[string]string{}
+	for _, wave := range waves {
+		for _, user := range wave.Users {
+			if v, ok := variables[user]; ok {
+				kept[user] = v
+			}
+		}
+	}
+
+	_, err := s.blasts.Update(id, func(blast *blast) error {
+		if blast.Status == blastStatusSending {
+			blast.Status = blastStatusScheduled
+		}
+		blast.Sent = response.Sent
+		blast.Messages = response.messages
+		blast.Skipped = response.Skipped
+		blast.Failed = response.Failed
+		blast.Schedule = &schedule{
+			Waves:     waves,
+			Variables: kept,
+			Origin:    c.Scheme() + "://" + c.Request().Host,
+		}
+		if request != nil {
+			blast.Schedule.Date = request.Date
+			blast.Schedule.Time = request.Time
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	response.Waves = waves
+	return nil
+}
+
+// startScheduler sends waves of scheduled blasts when they're due, including ones missed while the server was stopped.
+func (s *Server) startScheduler() {
+	go func() {
+		s.failInterruptedWaves(time.Now())
+		s.dispatchWaves(time.Now())
+
+		ticker := time.NewTicker(schedulerInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			s.dispatchWaves(now)
+		}
+	}()
+}
+
+// dispatchWaves sends every pending wave that's due by now.
+func (s *Server) dispatchWaves(now time.Time) {
+	blasts, err := s.blasts.List(func(blast *blast) bool {
+		return blast.Status == blastStatusScheduled && blast.Schedule != nil
+	})
+	if err != nil {
+		s.config.Logger.Error("failed to list scheduled blasts", zap.Error(err))
+		return
+	}
+	for _, blast := range blasts {
+		for i, wave := range blast.Schedule.Waves {
+			if wave.Status != waveStatusPending || wave.SendAt.After(now) {
+				continue
+			}
+			if err := s.dispatchWave(blast.ID, i); err != nil {
+				s.config.Logger.Error("failed to send wave", zap.String("blast", blast.ID), zap.String("timezone", wave.Timezone), zap.Error(err))
+			}
+		}
+	}
+}
+
+// dispatchWave sends blast's wave with the sender's saved authorization. The wave is claimed first,
+// so that it's sent once, and its recipients are checked against exclusions made since it was scheduled.
+func (s *Server) dispatchWave(id string, index int) error {
+	target, err := s.blasts.Update(id, func(blast *blast) error {
+		if blast.Status != blastStatusScheduled || blast.Schedule.Waves[index].Status != waveStatusPending {
+			return errWaveClaimed
+		}
+		now := time.Now().UTC()
+		blast.Schedule.Waves[index].Status = waveStatusSending
+		blast.Schedule.Waves[index].ClaimedAt = &now
+		return nil
+	})
+	if errors.Is(err, errWaveClaimed) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	wave := target.Schedule.Waves[index]
+
+	response := &sendResponse{
+		Blast:    target.ID,
+		Sent:     []string{},
+		messages: map[string]*slack.PostedMessage{},
+	}
+	if err := s.sendWave(target, wave, response); err != nil {
+		s.config.Logger.Warn("failed to send wave", zap.String("blast", target.ID), zap.String("timezone", wave.Timezone), zap.Error(err))
+		for _, user := range wave.Users {
+			response.Failed = append(response.Failed, newSendFailure(user, err))
+		}
+	}
+
+	_, err = s.completeWave(id, index, response)
+	return err
+}
+
+// failInterruptedWaves fails waves claimed before the server started, which were interrupted while sending.
+// They aren't sent again, since it's unknown which of their recipients were sent to already.
+func (s *Server) failInterruptedWaves(started time.Time) {
+	blasts, err := s.blasts.List(func(blast *blast) bool {
+		return blast.Status == blastStatusScheduled && blast.Schedule != nil
+	})
+	if err != nil {
+		s.config.Logger.Error("failed to list scheduled blasts", zap.Error(err))
+		return
+	}
+	for _, blast := range blasts {
+		for i, wave := range blast.Schedule.Waves {
+			if wave.Status != waveStatusSending || (wave.ClaimedAt != nil && !wave.ClaimedAt.Before(started)) {
+				continue
+			}
+			s.config.Logger.Warn("failing interrupted wave", zap.String("blast", blast.ID), zap.String("timezone", wave.Timezone))
+			if err := s.failWave(blast.ID, i); err != nil {
+				s.config.Logger.Error("failed to fail wave", zap.String("blast", blast.ID), zap.String("timezone", wave.Timezone), zap.Error(err))
+			}
+		}
+	}
+}
+
+// failWave records every recipient of blast's interrupted wave as failed, completing the blast after its last wave.
+func (s *Server) failWave(id string, index int) error {
+	finished := false
+	target, err := s.blasts.Update(id, func(blast *blast) error {
+		wave := blast.Schedule.Waves[index]
+		if wave.Status != waveStatusSending {
+			return errWaveClaimed
+		}
+		wave.Status = waveStatusFailed
+		for _, user := range wave.Users {
+			blast.Failed = append(blast.Failed, &sendFailure{
+				User:    user,
+				Code:    errorCodeInternal,
+				Message: "Sending was interrupted, so the message may not have been delivered",
+			})
+		}
+		finished = finishSchedule(blast, time.Now().UTC())
+		return nil
+	})
+	if errors.Is(err, errWaveClaimed) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if finished {
+		s.emitScheduleFinished(target)
+	}
+	return nil
+}
+
+// sendWave posts blast to wave's recipients that aren't excluded, returning an error if it couldn't start.
+func (s *Server) sendWave(blast *blast, wave *blastWave, response *sendResponse) error {
+	session, err := s.userSession(blast.WorkspaceID, blast.CreatedBy)
+	if err != nil {
+		return err
+	} else if session == nil {
+		return fmt.Errorf("sender hasn't signed in")
+	}
+
+	excluded, err := s.excludedUsers(blast.WorkspaceID)
+	if err != nil {
+		return err
+	}
+	users := []string{}
+	for _, user := range wave.Users {
+		if reason, ok := excluded[user]; ok {
+			response.Skipped = append(response.Skipped, &recipientSkip{User: user, Reason: reason})
+			continue
+		}
+		users = append(users, user)
+	}
+
+	// Opt-out links point to where the blast was scheduled from
+	request, err := http.NewRequest(http.MethodGet, blast.Schedule.Origin, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set(echo.HeaderXForwardedProto, request.URL.Scheme)
+	c := s.echo.NewContext(request, nil)
+
+	s.postBlast(c, session, blast, users, blast.Schedule.Variables, response)
+	return nil
+}
+
+// completeWave records outcome of sending blast's wave, completing the blast after its last wave.
+func (s *Server) completeWave(id string, index int, response *sendResponse) (*blast, error) {
+	finished := false
+	blast, err := s.blasts.Update(id, func(blast *blast) error {
+		now := time.Now().UTC()
+		wave := blast.Schedule.Waves[index]
+		wave.Status = waveStatusSent
+		wave.SentAt = &now
+
+		blast.Sent = append(blast.Sent, response.Sent...)
+		if blast.Messages == nil {
+			blast.Messages = map[string]*slack.PostedMessage{}
+		}
+		maps.Copy(blast.Messages, response.messages)
+		blast.Skipped = append(blast.Skipped, response.Skipped...)
+		blast.Failed = append(blast.Failed, response.Failed...)
+
+		finished = finishSchedule(blast, now)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if finished {
+		s.emitScheduleFinished(blast)
+	}
+	return blast, nil
+}
+
+// handleAPIBlastScheduleDelete handles DELETE /api/blasts/:id/schedule, canceling waves that haven't been sent yet.
+// Approvers can cancel blasts pending their approval, rejecting them.
+func (s *Server) handleAPIBlastScheduleDelete(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	target, err := s.blasts.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if target == nil || target.WorkspaceID != session.WorkspaceID() {
+		return errNotFound()
+	} else if target.CreatedBy != session.UserID() && !target.awaitsApprovalBy(session.UserID()) {
+		return errForbidden("Only the sender can cancel scheduled waves")
+	}
+
+	finished := false
+	target, err = s.blasts.Update(target.ID, func(blast *blast) error {
+		if blast.Status != blastStatusScheduled && blast.Status != blastStatusPendingApproval {
+			return errBadRequest("blast isn't scheduled")
+		}
+		for _, wave := range blast.Schedule.Waves {
+			if wave.Status == waveStatusPending {
+				wave.Status = waveStatusCanceled
+			}
+		}
+		finished = finishSchedule(blast, time.Now().UTC())
+		return nil
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+
+	if finished {
+		s.emitScheduleFinished(target)
+	}
+	return c.JSON(http.StatusOK, target)
+}
+
+// emitScheduleFinished emits the outcome of scheduled blast, unless it was canceled before any wave was sent.
+func (s *Server) emitScheduleFinished(blast *blast) {
+	switch blast.Status {
+	case blastStatusCompleted:
+		s.emitBlastEvent(eventBlastCompleted, blast)
+	case blastStatusFailed:
+		s.emitBlastEvent(eventBlastFailed, blast)
+	}
+}
+
+// finishSchedule completes blast once none of its waves are left to send, returning whether it did.
+// Blast is canceled if none of its waves were sent or failed, and failed if they didn't reach any recipient.
+func finishSchedule(blast *blast, now time.Time) bool {
+	sent := false
+	for _, wave := range blast.Schedule.Waves {
+		switch wave.Status {
+		case waveStatusPending, waveStatusSending:
+			return false
+		case waveStatusSent, waveStatusFailed:
+			sent = true
+		}
+	}
+
+	switch {
+	case !sent:
+		blast.Status = blastStatusCanceled
+	case len(blast.Sent) == 0 && len(blast.Failed) > 0:
+		blast.Status = blastStatusFailed
+	default:
+		blast.Status = blastStatusCompleted
+	}
+	blast.CompletedAt = &now
+	return true
+}
+
+type scheduleRequest struct {
+	// Date and Time are local to each recipient, such as "2024-03-01" and "09:00"
+	Date string `json:"date"`
+	Time string `json:"time"`
+
+	// DefaultTimezone applies to recipients without a valid timezone in their profile, UTC when empty
+	DefaultTimezone string `json:"default_timezone,omitempty"`
+}
+
+func (r *scheduleRequest) validate() error {
+	if r == nil {
+		return nil
+	}
+	if _, err := time.Parse(scheduleLayout, r.Date+" "+r.Time); err != nil {
+		return fmt.Errorf("invalid schedule, expected date as YYYY-MM-DD and time as HH:MM")
+	}
+	if r.DefaultTimezone != "" {
+		if _, err := time.LoadLocation(r.DefaultTimezone); err != nil {
+			return fmt.Errorf("invalid default timezone %q", r.DefaultTimezone)
+		}
+	}
+	return nil
+}
+
+// waves groups users by timezone into waves sent at the scheduled local time, earliest first.
+func (r *scheduleRequest) waves(users []*resolvedUser) []*blastWave {
+	fallback := r.DefaultTimezone
+	if fallback == "" {
+		fallback = "UTC"
+	}
+
+	waves := []*blastWave{}
+	lookup := map[string]*blastWave{}
+	for _, user := range users {
+		timezone, location := userLocation(user.Timezone, fallback)
+		wave, ok := lookup[timezone]
+		if !ok {
+			sendAt, _ := time.ParseInLocation(scheduleLayout, r.Date+" "+r.Time, location)
+			wave = &blastWave{
+				Timezone: timezone,
+				SendAt:   sendAt.UTC(),
+				Users:    []string{},
+				Status:   waveStatusPending,
+			}
+			lookup[timezone] = wave
+			waves = append(waves, wave)
+		}
+		wave.Users = append(wave.Users, user.ID)
+	}
+
+	sortWaves(waves)
+	return waves
+}
+
+// sortWaves sorts waves by when they're sent, earliest first.
+func sortWaves(waves []*blastWave) {
+	sort.SliceStable(waves, func(i, j int) bool {
+		if !waves[i].SendAt.Equal(waves[j].SendAt) {
+			return waves[i].SendAt.Before(waves[j].SendAt)
+		}
+		return waves[i].Timezone < waves[j].Timezone
+	})
+}
+
+// userLocation returns timezone and its location, or fallback's when timezone is empty or unknown.
+func userLocation(timezone, fallback string) (string, *time.Location) {
+	if location, err := time.LoadLocation(timezone); timezone != "" && err == nil {
+		return timezone, location
+	}
+	if location, err := time.LoadLocation(fallback); fallback != "" && err == nil {
+		return fallback, location
+	}
+	return "UTC", time.UTC
+}
+
+// schedule sends blast at the same local date and time for each recipient, or later when deferred by policy.
+type schedule struct {
+	Date  string       `json:"date,omitempty"`
+	Time  string       `json:"time,omitempty"`
+	Waves []*blastWave `json:"waves"`
+
+	// Variables are merged into the message for each recipient, like when sending immediately
+	Variables map[string]map[string]string `json:"variables,omitempty"`
+
+	// Origin is the scheme and host blast was scheduled on, for links in the message
+	Origin string `json:"origin"`
+}
+
+// blastWave is the group of recipients in the same timezone, sent to at once.
+type blastWave struct {
+	Timezone string     `json:"tz"`
+	SendAt   time.Time  `json:"send_at"`
+	Users    []string   `json:"users"`
+	Status   string     `json:"status"`
+	SentAt   *time.Time `json:"sent_at,omitempty"`
+
+	// ClaimedAt is when sending started, so that waves interrupted by a restart can be told apart
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+
+	// Deferred is set when wave was moved to its recipients' next working hours by workspace's policy
+	Deferred bool `json:"deferred,omitempty"`
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleRequestValidate(t *testing.T) {
+	for _, test := range []struct {
+		request       *scheduleRequest
+		errorContains string
+	}{
+		{request: nil},
+		{request: &scheduleRequest{Date: "2030-03-01", Time: "09:00"}},
+		{request: &scheduleRequest{Date: "2030-03-01", Time: "09:00", DefaultTimezone: "Asia/Tokyo"}},
+		{request: &scheduleRequest{Date: "1 Mar 2030", Time: "09:00"}, errorContains: "invalid schedule"},
+		{request: &scheduleRequest{Date: "2030-03-01", Time: "9am"}, errorContains: "invalid schedule"},
+		{request: &scheduleRequest{Date: "2030-03-01", Time: "09:00", DefaultTimezone: "Mars/Olympus"}, errorContains: "invalid default timezone"},
+	} {
+		err := test.request.validate()
+		if test.errorContains == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorContains(t, err, test.errorContains)
+		}
+	}
+}
+
+func TestScheduleRequestWaves(t *testing.T) {
+	request := &scheduleRequest{Date: "2030-03-01", Time: "09:00", DefaultTimezone: "Asia/Tokyo"}
+	waves := request.waves([]*resolvedUser{
+		{ID: "U1", Timezone: "Europe/Berlin"},
+		{ID: "U2", Timezone: "America/New_York"},
+		{ID: "U3"},
+		{ID: "U4", Timezone: "Mars/Olympus"},
+		{ID: "U5", Timezone: "Europe/Berlin"},
+	})
+	if assert.Len(t, waves, 3) {
+		assert.Equal(t, &blastWave{Timezone: "Asia/Tokyo", SendAt: time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC), Users: []string{"U3", "U4"}, Status: waveStatusPending}, waves[0])
+		assert.Equal(t, &blastWave{Timezone: "Europe/Berlin", SendAt: time.Date(2030, 3, 1, 8, 0, 0, 0, time.UTC), Users: []string{"U1", "U5"}, Status: waveStatusPending}, waves[1])
+		assert.Equal(t, &blastWave{Timezone: "America/New_York", SendAt: time.Date(2030, 3, 1, 14, 0, 0, 0, time.UTC), Users: []string{"U2"}, Status: waveStatusPending}, waves[2])
+	}
+
+	waves = (&scheduleRequest{Date: "2030-03-01", Time: "09:00"}).waves([]*resolvedUser{{ID: "U1"}})
+	if assert.Len(t, waves, 1) {
+		assert.Equal(t, "UTC", waves[0].Timezone)
+		assert.Equal(t, time.Date(2030, 3, 1, 9, 0, 0, 0, time.UTC), waves[0].SendAt)
+	}
+}
+
+func TestScheduledBlast(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	mock := &mockSlackSession{
+		ClientSession: &slack.ClientSession{Token: "xoxp-1", TeamID: "T1", User: "U9"},
+		Timezones:     map[string]string{"U1": "Europe/Berlin", "U2": "America/New_York"},
+	}
+	defer func(original func() slack.Session) { newSession = original }(newSession)
+	newSession = func() slack.Session { return mock }
+	assert.NoError(t, server.saveAuthorization(mock))
+
+	request := func(method, body, id string, handler echo.HandlerFunc) *requestTester {
+		return serveAPI(t, server, mock, method, body, handler, "id", id)
+	}
+	send := server.handleAPISend
+	cancel := server.handleAPIBlastScheduleDelete
+
+	r := request(http.MethodPost, `{"users":["U1"],"message":"Hi","schedule":{"date":"2020-03-01","time":"09:00"}}`, "", send)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+	assert.Contains(t, r.APIError().Message, "passed")
+
+	r = request(http.MethodPost, `{"recipients":[{"user":"U1","variables":{"name":"Jane"}}],"users":["U2","U3"],"message":"Hi {{name}}","acknowledge":true,"schedule":{"date":"2030-03-01","time":"09:00"}}`, "", send)
+	var sent sendResponse
+	if !assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent)) || !assert.Len(t, sent.Waves, 3) {
+		return
+	}
+	assert.Empty(t, sent.Sent)
+	assert.Empty(t, mock.Posted)
+	assert.Equal(t, []string{"Europe/Berlin", "UTC", "America/New_York"}, []string{sent.Waves[0].Timezone, sent.Waves[1].Timezone, sent.Waves[2].Timezone})
+
+	// Waves are sent once they're due, skipping users who opted out since
+	server.dispatchWaves(time.Date(2030, 3, 1, 8, 30, 0, 0, time.UTC))
+	server.dispatchWaves(time.Date(2030, 3, 1, 8, 31, 0, 0, time.UTC))
+	assert.Len(t, mock.Posted, 1)
+	assert.True(t, strings.HasPrefix(mock.Posted["U1"], "Hi Jane"))
+	assert.Contains(t, mock.Posted["U1"], "http://example.com/optout?token=")
+	assert.Contains(t, mock.PostedBlocks["U1"], actionBlastAck)
+
+	assert.NoError(t, server.optOut("T1", "U3"))
+	server.dispatchWaves(time.Date(2030, 3, 1, 9, 0, 0, 0, time.UTC))
+	assert.Len(t, mock.Posted, 1)
+
+	scheduled, err := server.blasts.Get(sent.Blast)
+	if assert.NoError(t, err) {
+		assert.Equal(t, blastStatusScheduled, scheduled.Status)
+		assert.Equal(t, []string{"U1"}, scheduled.Sent)
+		assert.Equal(t, "DU1", scheduled.Messages["U1"].Channel)
+		if assert.Len(t, scheduled.Skipped, 1) {
+			assert.Equal(t, "U3", scheduled.Skipped[0].User)
+		}
+		assert.Equal(t, waveStatusSent, scheduled.Schedule.Waves[1].Status)
+		assert.Equal(t, waveStatusPending, scheduled.Schedule.Waves[2].Status)
+	}
+
+	// Canceling stops the remaining waves and completes the blast, only for the sender
+	r = serveAPI(t, server, newMockSession("T1", "U1"), http.MethodDelete, "", cancel, "id", sent.Blast)
+	assert.Equal(t, http.StatusForbidden, r.Response.Code)
+	r = request(http.MethodDelete, "", sent.Blast, cancel)
+	assert.Equal(t, http.StatusOK, r.Response.Code)
+	server.dispatchWaves(time.Date(2030, 3, 2, 0, 0, 0, 0, time.UTC))
+	assert.Len(t, mock.Posted, 1)
+	scheduled, err = server.blasts.Get(sent.Blast)
+	if assert.NoError(t, err) {
+		assert.Equal(t, blastStatusCompleted, scheduled.Status)
+		assert.NotNil(t, scheduled.CompletedAt)
+		assert.Equal(t, waveStatusCanceled, scheduled.Schedule.Waves[2].Status)
+	}
+	r = request(http.MethodDelete, "", sent.Blast, cancel)
+	assert.Equal(t, http.StatusBadRequest, r.Response.Code)
+
+	// Canceled before any wave is sent
+	r = request(http.MethodPost, `{"users":["U2"],"message":"Later","schedule":{"date":"2030-03-01","time":"09:00"}}`, "", send)
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &sent))
+	r = request(http.MethodDelete, "", sent.Blast, cancel)
+	var canceled blast
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &canceled)) {
+		assert.Equal(t, blastStatusCanceled, canceled.Status)
+	}
+
+	r = request(http.MethodDelete, "", "missing", cancel)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+}
+
+func TestFailInterruptedWaves(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	started := time.Now()
+	claimed := started.Add(-time.Minute)
+	assert.NoError(t, server.blasts.Put("B1", &blast{
+		ID:          "B1",
+		WorkspaceID: "T1",
+		CreatedBy:   "U9",
+		Status:      blastStatusScheduled,
+		Sent:        []string{},
+		Schedule: &schedule{Waves: []*blastWave{
+			{Timezone: "UTC", Users: []string{"U1", "U2"}, Status: waveStatusSending, ClaimedAt: &claimed},
+			{Timezone: "Asia/Tokyo", Users: []string{"U3"}, Status: waveStatusCanceled},
+		}},
+	}))
+
+	server.failInterruptedWaves(started)
+
+	interrupted, err := server.blasts.Get("B1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, waveStatusFailed, interrupted.Schedule.Waves[0].Status)
+		assert.Equal(t, blastStatusFailed, interrupted.Status)
+		if assert.Len(t, interrupted.Failed, 2) {
+			assert.Equal(t, "U1", interrupted.Failed[0].User)
+			assert.Contains(t, interrupted.Failed[0].Message, "interrupted")
+		}
+	}
+}
@@ -1,20 +1,26 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"html/template"
+	"io/fs"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/gouline/blaster/internal/pkg/i18n"
+	"github.com/gouline/blaster/internal/pkg/overlay"
+	"github.com/gouline/blaster/internal/pkg/store"
 	"github.com/gouline/blaster/internal/pkg/templates"
+	"github.com/gouline/blaster/internal/pkg/webhook"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
 )
 
 const (
-	appName = "Blaster"
-
 	cookiePrefix  = "blaster_"
 	cookieSession = cookiePrefix + "session"
 )
@@ -29,16 +35,59 @@ type Config struct {
 	CertFile string
 	KeyFile  string
 
+	// Assets contains default "static" and "templates" directories, usually embedded
+	Assets fs.FS
+
+	// StaticRoot and TemplatesRoot are optional directories on disk overriding files in Assets
 	StaticRoot    string
 	TemplatesRoot string
 
-	SlackClientID     string
-	SlackClientSecret string
+	// StorePath is a file to persist data to, kept in memory when empty
+	StorePath string
+
+	SlackClientID      string
+	SlackClientSecret  string
+	SlackSigningSecret string
+
+	// GlobalExclusions lists user IDs that never receive blasts in any workspace
+	GlobalExclusions []string
+
+	// Branding applies to all workspaces, unless overridden in WorkspaceBranding by workspace ID
+	Branding          Branding
+	WorkspaceBranding map[string]Branding
+
+	// DeliveryPolicy applies to all workspaces, unless overridden in WorkspaceDeliveryPolicies by workspace ID
+	DeliveryPolicy            DeliveryPolicy
+	WorkspaceDeliveryPolicies map[string]DeliveryPolicy
 }
 
 type Server struct {
-	config Config
-	echo   *echo.Echo
+	config     Config
+	echo       *echo.Echo
+	catalogue  *i18n.Catalogue
+	store      *store.Store
+	lists      *store.Collection[recipientList]
+	exclusions *store.Collection[exclusion]
+	tokens     *store.Collection[apiToken]
+	blasts     *store.Collection[blast]
+	webhooks   *store.Collection[webhookSubscription]
+	deliveries *store.Collection[webhookDelivery]
+	hooks      *store.Collection[incomingHook]
+
+	authorizations *store.Collection[authorization]
+	drafts         *store.Collection[commandDraft]
+	replies        *store.Collection[blastReply]
+	posts          *store.Collection[blastPost]
+
+	webhookClient *webhook.Client
+	deliveriesWG  sync.WaitGroup
+
+	// dispatchWG tracks blasts sent and replies forwarded in the background after Slack requests
+	dispatchWG sync.WaitGroup
+	forwardMu  sync.Mutex
+
+	// openAPI is the encoded OpenAPI document, generated once on start
+	openAPI []byte
 }
 
 func New(config Config) (*Server, error) {
@@ -54,7 +103,47 @@ func New(config Config) (*Server, error) {
 
 	s.echo.Debug = config.Debug
 
+	if err := config.Branding.validate(); err != nil {
+		return s, fmt.Errorf("invalid branding: %w", err)
+	}
+	for workspaceID, branding := range config.WorkspaceBranding {
+		if err := branding.validate(); err != nil {
+			return s, fmt.Errorf("invalid branding for %s: %w", workspaceID, err)
+		}
+	}
+	if err := config.DeliveryPolicy.validate(); err != nil {
+		return s, fmt.Errorf("invalid delivery policy: %w", err)
+	}
+	for workspaceID, policy := range config.WorkspaceDeliveryPolicies {
+		if err := policy.validate(); err != nil {
+			return s, fmt.Errorf("invalid delivery policy for %s: %w", workspaceID, err)
+		}
+	}
+
+	s.store, err = store.New(config.StorePath)
+	if err != nil {
+		return nil, fmt.Errorf("store loading failed: %w", err)
+	}
+	s.lists = store.NewCollection[recipientList](s.store, "lists")
+	s.exclusions = store.NewCollection[exclusion](s.store, "exclusions")
+	s.tokens = store.NewCollection[apiToken](s.store, "tokens")
+	s.blasts = store.NewCollection[blast](s.store, "blasts")
+	s.webhooks = store.NewCollection[webhookSubscription](s.store, "webhooks")
+	s.deliveries = store.NewCollection[webhookDelivery](s.store, "webhook_deliveries")
+	s.hooks = store.NewCollection[incomingHook](s.store, "hooks")
+	s.authorizations = store.NewCollection[authorization](s.store, "authorizations")
+	s.drafts = store.NewCollection[commandDraft](s.store, "command_drafts")
+	s.replies = store.NewCollection[blastReply](s.store, "blast_replies")
+	s.posts = store.NewCollection[blastPost](s.store, "blast_posts")
+
+	s.webhookClient = webhook.NewClient()
+	if err := s.resumeDeliveries(); err != nil {
+		return nil, fmt.Errorf("webhook deliveries loading failed: %w", err)
+	}
+
+	s.echo.HTTPErrorHandler = s.handleHTTPError
 	s.echo.Use(middleware.Recover())
+	s.echo.Use(middleware.RequestID())
 	s.echo.Use(middleware.Gzip())
 	if s.echo.Debug {
 		s.echo.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
@@ -71,23 +160,49 @@ func New(config Config) (*Server, error) {
 	}
 
 	// Static
-	if f, err := os.Stat(config.StaticRoot); os.IsNotExist(err) {
-		return s, fmt.Errorf("static not found: %w", err)
-	} else if err == nil && !f.IsDir() {
-		return s, fmt.Errorf("static not directory")
+	staticFS, err := assetsFS(config.Assets, "static", config.StaticRoot)
+	if err != nil {
+		return s, fmt.Errorf("static %w", err)
 	}
-	s.echo.Static("/static", config.StaticRoot)
+	s.echo.StaticFS("/static", staticFS)
 
 	// Templates
+	s.catalogue, err = i18n.New()
+	if err != nil {
+		return nil, fmt.Errorf("catalogue loading failed: %w", err)
+	}
+	templatesFS, err := assetsFS(config.Assets, "templates", config.TemplatesRoot)
+	if err != nil {
+		return s, fmt.Errorf("templates %w", err)
+	}
 	s.echo.Renderer, err = templates.New(templates.Config{
-		Logger:     config.Logger,
-		RootPath:   config.TemplatesRoot,
-		LayoutFile: "layout.html",
+		Logger:      config.Logger,
+		FS:          templatesFS,
+		RootPath:    ".",
+		LayoutFile:  "layout.html",
+		LayoutsDir:  "layouts",
+		PartialsDir: "partials",
+		Funcs: template.FuncMap{
+			"t":  s.catalogue.Translator(i18n.Fallback),
+			"tn": s.catalogue.PluralTranslator(i18n.Fallback),
+		},
+		ContextFuncs: func(c echo.Context) template.FuncMap {
+			return template.FuncMap{
+				"t":  s.translator(c),
+				"tn": s.catalogue.PluralTranslator(s.language(c)),
+			}
+		},
+		Reload: config.Debug,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("templates parsing failed: %w", err)
 	}
 
+	s.openAPI, err = json.MarshalIndent(newOpenAPIDocument(s.branding("").AppName+" API", apiOperations), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI generation failed: %w", err)
+	}
+
 	// Slack auth
 	s.echo.Use(s.middlewareAuth)
 	authGroup := s.echo.Group("/auth")
@@ -96,12 +211,73 @@ func New(config Config) (*Server, error) {
 
 	// Pages
 	s.echo.GET("/", s.handleIndex)
+	s.echo.GET("/blasts", s.handleBlasts)
+	s.echo.GET("/blasts/:id", s.handleBlast)
+	s.echo.GET("/tokens", s.handleTokens)
+	s.echo.GET("/optout", s.handleOptOut)
+	s.echo.POST("/optout", s.handleOptOutConfirm)
 	s.echo.RouteNotFound("/*", s.handleNotFound)
 
 	// API
 	apiGroup := s.echo.Group("/api")
+	apiGroup.RouteNotFound("/*", func(c echo.Context) error { return errNotFound() })
+	apiGroup.GET("/openapi.json", s.handleAPIOpenAPI)
 	apiGroup.GET("/suggest", s.handleAPISuggest)
+	apiGroup.GET("/audience", s.handleAPIAudience)
+	apiGroup.POST("/resolve", s.handleAPIResolve)
+	apiGroup.POST("/recipients/csv", s.handleAPIRecipientsCSV)
 	apiGroup.POST("/send", s.handleAPISend)
+	apiGroup.GET("/lists", s.handleAPIListsGet)
+	apiGroup.POST("/lists", s.handleAPIListsCreate)
+	apiGroup.GET("/lists/:id", s.handleAPIListGet)
+	apiGroup.PUT("/lists/:id", s.handleAPIListUpdate)
+	apiGroup.DELETE("/lists/:id", s.handleAPIListDelete)
+	apiGroup.GET("/exclusions", s.handleAPIExclusionsGet)
+	apiGroup.POST("/exclusions", s.handleAPIExclusionsCreate)
+	apiGroup.DELETE("/exclusions/:user", s.handleAPIExclusionDelete)
+	apiGroup.GET("/blasts", s.handleAPIBlastsGet)
+	apiGroup.GET("/blasts/:id", s.handleAPIBlastGet)
+	apiGroup.GET("/blasts/:id/acks", s.handleAPIBlastAcksGet)
+	apiGroup.POST("/blasts/:id/remind", s.handleAPIBlastRemind)
+	apiGroup.GET("/blasts/:id/poll", s.handleAPIBlastPollGet)
+	apiGroup.GET("/blasts/:id/replies", s.handleAPIBlastRepliesGet)
+	apiGroup.DELETE("/blasts/:id/schedule", s.handleAPIBlastScheduleDelete)
+	apiGroup.POST("/blasts/:id/approve", s.handleAPIBlastApprove)
+	apiGroup.GET("/webhooks", s.handleAPIWebhooksGet)
+	apiGroup.POST("/webhooks", s.handleAPIWebhooksCreate)
+	apiGroup.DELETE("/webhooks/:id", s.handleAPIWebhookDelete)
+	apiGroup.GET("/webhooks/:id/deliveries", s.handleAPIWebhookDeliveriesGet)
+	apiGroup.POST("/webhooks/:id/deliveries/:delivery/redeliver", s.handleAPIWebhookRedeliver)
+	apiGroup.GET("/hooks", s.handleAPIHooksGet)
+	apiGroup.POST("/hooks", s.handleAPIHooksCreate)
+	apiGroup.DELETE("/hooks/:id", s.handleAPIHookDelete)
+	apiGroup.GET("/tokens", s.handleAPITokensGet)
+	apiGroup.POST("/tokens", s.handleAPITokensCreate)
+	apiGroup.DELETE("/tokens/:id", s.handleAPITokenDelete)
+
+	// Public API, authenticated by tokens
+	v1Group := apiGroup.Group("/v1", s.middlewareToken)
+	v1Group.GET("/suggest", s.handleAPISuggest, requireScope(scopeSuggest))
+	v1Group.GET("/audience", s.handleAPIAudience, requireScope(scopeSuggest))
+	v1Group.POST("/resolve", s.handleAPIResolve, requireScope(scopeSuggest))
+	v1Group.POST("/send", s.handleAPISend, requireScope(scopeSend))
+	v1Group.GET("/blasts", s.handleAPIBlastsGet, requireScope(scopeHistory))
+	v1Group.GET("/blasts/:id", s.handleAPIBlastGet, requireScope(scopeHistory))
+	v1Group.GET("/blasts/:id/acks", s.handleAPIBlastAcksGet, requireScope(scopeHistory))
+	v1Group.POST("/blasts/:id/remind", s.handleAPIBlastRemind, requireScope(scopeSend))
+	v1Group.GET("/blasts/:id/poll", s.handleAPIBlastPollGet, requireScope(scopeHistory))
+	v1Group.GET("/blasts/:id/replies", s.handleAPIBlastRepliesGet, requireScope(scopeHistory))
+	v1Group.DELETE("/blasts/:id/schedule", s.handleAPIBlastScheduleDelete, requireScope(scopeSend))
+	v1Group.POST("/blasts/:id/approve", s.handleAPIBlastApprove, requireScope(scopeSend))
+
+	// Slack callbacks
+	slackGroup := s.echo.Group("/slack")
+	slackGroup.POST("/commands", s.handleSlackCommand)
+	slackGroup.POST("/interactions", s.handleSlackInteraction)
+	slackGroup.POST("/events", s.handleSlackEvent)
+
+	// Incoming hooks, authenticated by signature
+	s.echo.POST("/hooks/:id", s.handleHook)
 
 	return s, nil
 }
@@ -114,6 +290,8 @@ func (s *Server) Start() error {
 		zap.String("certFile", s.config.CertFile),
 		zap.String("keyFile", s.config.KeyFile))
 
+	s.startScheduler()
+
 	addr := fmt.Sprintf("%s:%s", s.config.Host, s.config.Port)
 	if s.config.CertFile != "" && s.config.KeyFile != "" {
 		return s.echo.StartTLS(addr, s.config.CertFile, s.config.KeyFile)
@@ -121,6 +299,30 @@ func (s *Server) Start() error {
 	return s.echo.Start(addr)
 }
 
+// assetsFS returns dir within assets, overridden by files in overrideRoot on disk when it's set.
+func assetsFS(assets fs.FS, dir, overrideRoot string) (fs.FS, error) {
+	var base, override fs.FS
+	if assets != nil {
+		sub, err := fs.Sub(assets, dir)
+		if err != nil {
+			return nil, err
+		}
+		base = sub
+	}
+	if overrideRoot != "" {
+		if f, err := os.Stat(overrideRoot); os.IsNotExist(err) {
+			return nil, fmt.Errorf("not found: %w", err)
+		} else if err == nil && !f.IsDir() {
+			return nil, fmt.Errorf("not directory")
+		}
+		override = os.DirFS(overrideRoot)
+	}
+	if base == nil && override == nil {
+		return nil, fmt.Errorf("not found")
+	}
+	return overlay.New(override, base), nil
+}
+
 // redirectURI creates a stable URI for redirects.
 // Removes query parameters and trailing slashes.
 func redirectURI(c echo.Context, uri string) string {
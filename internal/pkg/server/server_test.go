@@ -1,12 +1,18 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/gouline/blaster/internal/pkg/slack"
 	"github.com/labstack/echo/v4"
@@ -15,8 +21,9 @@ import (
 )
 
 const (
-	mockClientID     = "dummy_id"
-	mockClientSecret = "dummy_secret"
+	mockClientID      = "dummy_id"
+	mockClientSecret  = "dummy_secret"
+	mockSigningSecret = "dummy_signing_secret"
 )
 
 type requestTester struct {
@@ -36,17 +43,35 @@ func (r *requestTester) Authenticate(token, team string) {
 	r.Context.Set(cookieSession, r.Session)
 }
 
+// Handle calls handler like echo does, passing returned error to the server's error handler.
+func (r *requestTester) Handle(handler echo.HandlerFunc) error {
+	if err := handler(r.Context); err != nil {
+		r.Server.echo.HTTPErrorHandler(err, r.Context)
+	}
+	return nil
+}
+
+// APIError decodes error envelope from response.
+func (r *requestTester) APIError() *apiError {
+	var e apiError
+	if err := json.Unmarshal(r.Response.Body.Bytes(), &e); err != nil {
+		return nil
+	}
+	return &e
+}
+
 func newRequestTester(method, target string, body io.Reader) *requestTester {
 	r := &requestTester{}
 
 	var err error
 	r.Server, err = New(Config{
-		Logger:            zap.Must(zap.NewDevelopment()),
-		Debug:             true,
-		SlackClientID:     mockClientID,
-		SlackClientSecret: mockClientSecret,
-		StaticRoot:        "../../../static",
-		TemplatesRoot:     "../../../templates",
+		Logger:             zap.Must(zap.NewDevelopment()),
+		Debug:              true,
+		SlackClientID:      mockClientID,
+		SlackClientSecret:  mockClientSecret,
+		SlackSigningSecret: mockSigningSecret,
+		StaticRoot:         "../../../static",
+		TemplatesRoot:      "../../../templates",
 	})
 	if err != nil {
 		fmt.Println(err)
@@ -61,12 +86,49 @@ func newRequestTester(method, target string, body io.Reader) *requestTester {
 	return r
 }
 
+// serveAPI handles JSON request with server's handler, signed in with session. Params alternate
+// path parameter names and values, such as "id", "b1".
+func serveAPI(t *testing.T, server *Server, session *mockSlackSession, method, body string, handler echo.HandlerFunc, params ...string) *requestTester {
+	r := newRequestTester(method, "/api", strings.NewReader(body))
+	r.Server = server
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Session = session
+	r.Context.Set(cookieSession, session)
+	names, values := []string{}, []string{}
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	r.Context.SetParamNames(names...)
+	r.Context.SetParamValues(values...)
+	assert.NoError(t, r.Handle(handler))
+	return r
+}
+
+// newMockSession creates session signed in to workspace as user.
+func newMockSession(workspace, user string) *mockSlackSession {
+	return &mockSlackSession{ClientSession: &slack.ClientSession{Token: "1", TeamID: workspace, User: user}}
+}
+
 type mockSlackSession struct {
 	*slack.ClientSession
-	AuthenticateError    error
-	AuthorizeURLError    error
-	GetDestinationsError error
-	PostMessageError     error
+	Destinations             []*slack.Destination
+	UserGroupMembers         map[string][]string
+	ChannelMembers           map[string][]string
+	InactiveUsers            []string
+	Admins                   []string
+	Timezones                map[string]string
+	UsersByEmail             map[string]*slack.Destination
+	LookupErrors             []error
+	Posted                   map[string]string
+	PostedBlocks             map[string]string
+	PostedThreads            map[string]string
+	AuthenticateError        error
+	AuthorizeURLError        error
+	GetDestinationsError     error
+	GetMembersError          error
+	GetUserGroupMembersCalls int
+	PostMessageError         error
 }
 
 func (s *mockSlackSession) Authenticate(clientID, clientSecret, redirectURI string, query url.Values) (bool, error) {
@@ -85,11 +147,129 @@ func (s *mockSlackSession) AuthorizeURL(clientID, redirectURI string) (string, e
 }
 
 func (s *mockSlackSession) GetDestinations() ([]*slack.Destination, error) {
-	return []*slack.Destination{}, s.GetDestinationsError
+	if s.Destinations == nil {
+		return []*slack.Destination{}, s.GetDestinationsError
+	}
+	return s.Destinations, s.GetDestinationsError
+}
+
+func (s *mockSlackSession) GetProfileFieldNames() ([]string, error) {
+	names := []string{}
+	for _, dest := range s.Destinations {
+		for name := range dest.Fields {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, s.GetDestinationsError
+}
+
+// WithProfiles returns destinations as is, since mock destinations have their profiles set already.
+func (s *mockSlackSession) WithProfiles(destinations []*slack.Destination) ([]*slack.Destination, error) {
+	return destinations, s.GetDestinationsError
+}
+
+func (s *mockSlackSession) GetUserGroupMembers() (map[string][]string, error) {
+	s.GetUserGroupMembersCalls++
+	return s.UserGroupMembers, s.GetMembersError
+}
+
+func (s *mockSlackSession) GetChannelMembers(channel string) ([]string, error) {
+	return s.ChannelMembers[channel], s.GetMembersError
 }
 
-func (s *mockSlackSession) PostMessage(user, message string, asUser bool) error {
-	return s.PostMessageError
+func (s *mockSlackSession) GetActiveUsers(users []string) ([]*slack.Destination, error) {
+	active := []*slack.Destination{}
+	for _, user := range users {
+		if !slices.Contains(s.InactiveUsers, user) {
+			active = append(active, &slack.Destination{
+				Type:     "user",
+				ID:       user,
+				Name:     "Name " + user,
+				Timezone: s.Timezones[user],
+				IsAdmin:  slices.Contains(s.Admins, user),
+			})
+		}
+	}
+	return active, nil
+}
+
+func (s *mockSlackSession) LookupUserByEmail(email string) (*slack.Destination, error) {
+	if len(s.LookupErrors) > 0 {
+		err := s.LookupErrors[0]
+		s.LookupErrors = s.LookupErrors[1:]
+		return nil, err
+	}
+	return s.UsersByEmail[email], nil
+}
+
+func (s *mockSlackSession) PostMessage(user string, message slack.Message) (*slack.PostedMessage, error) {
+	if s.PostMessageError != nil {
+		return nil, s.PostMessageError
+	}
+	if s.Posted == nil {
+		s.Posted = map[string]string{}
+		s.PostedBlocks = map[string]string{}
+		s.PostedThreads = map[string]string{}
+	}
+	s.Posted[user] = message.Text
+	s.PostedBlocks[user] = string(message.Blocks)
+	s.PostedThreads[user] = message.ThreadTimestamp
+	return &slack.PostedMessage{Channel: "D" + user, Timestamp: strconv.Itoa(len(s.Posted)) + ".000"}, nil
+}
+
+// PostChannelMessage records message like [mockSlackSession.PostMessage], keyed by channel.
+func (s *mockSlackSession) PostChannelMessage(channel string, message slack.Message) (*slack.PostedMessage, error) {
+	posted, err := s.PostMessage(channel, message)
+	if err != nil {
+		return nil, err
+	}
+	posted.Channel = channel
+	return posted, nil
+}
+
+// newInteractionTester creates request with interaction payload signed like Slack does.
+func newInteractionTester(server *Server, payload interface{}) *requestTester {
+	encoded, _ := json.Marshal(payload)
+	body := url.Values{"payload": {string(encoded)}}.Encode()
+	r := newRequestTester(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+	r.Server = server
+	r.Context = server.echo.NewContext(r.Request, r.Response)
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	slack.SignRequest(r.Request, mockSigningSecret, body, time.Now())
+	return r
+}
+
+// newResponseURL starts server receiving interaction responses into channel.
+func newResponseURL() (*httptest.Server, chan *commandResponse) {
+	responses := make(chan *commandResponse, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response commandResponse
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &response)
+		responses <- &response
+	}))
+	return server, responses
+}
+
+// testDestinations returns a small directory with one user group.
+func testDestinations() []*slack.Destination {
+	jane := &slack.Destination{Type: "user", ID: "U1", Name: "Jane Doe", Title: "Engineer"}
+	john := &slack.Destination{Type: "user", ID: "U2", Name: "John Smith", Title: "Designer"}
+	yuki := &slack.Destination{Type: "user", ID: "U3", Name: "Yuki Tanaka", Title: "Product Manager"}
+	return []*slack.Destination{
+		jane,
+		john,
+		yuki,
+		{
+			Type:        "usergroup",
+			ID:          "S1",
+			Name:        "On-call",
+			DisplayName: "oncall",
+			Children:    []*slack.Destination{jane, john},
+		},
+	}
 }
 
 func TestServerChecks(t *testing.T) {
@@ -125,7 +305,7 @@ func TestServerChecks(t *testing.T) {
 				StaticRoot:        "../../../static",
 				TemplatesRoot:     "../../templates",
 			},
-			errorContains: "templates parsing failed",
+			errorContains: "templates not found",
 		},
 		{
 			config: Config{
@@ -144,6 +324,52 @@ func TestServerChecks(t *testing.T) {
 
 }
 
+func TestServerAssets(t *testing.T) {
+	assets := fstest.MapFS{
+		"static/css/main.css":   {Data: []byte("body {}")},
+		"templates/layout.html": {Data: []byte(`{{template "content" .}}`)},
+		"templates/index.html":  {Data: []byte(`{{define "content"}}Embedded{{end}}`)},
+	}
+
+	s, err := New(Config{
+		Logger:            zap.Must(zap.NewDevelopment()),
+		SlackClientID:     mockClientID,
+		SlackClientSecret: mockClientSecret,
+		Assets:            assets,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	request := httptest.NewRequest(http.MethodGet, "/static/css/main.css", nil)
+	response := httptest.NewRecorder()
+	s.echo.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "body {}", response.Body.String())
+
+	s, err = New(Config{
+		Logger:            zap.Must(zap.NewDevelopment()),
+		SlackClientID:     mockClientID,
+		SlackClientSecret: mockClientSecret,
+		Assets:            assets,
+		StaticRoot:        "../../../static",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	request = httptest.NewRequest(http.MethodGet, "/static/css/main.css", nil)
+	response = httptest.NewRecorder()
+	s.echo.ServeHTTP(response, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.NotEqual(t, "body {}", response.Body.String())
+
+	_, err = New(Config{
+		Logger:            zap.Must(zap.NewDevelopment()),
+		SlackClientID:     mockClientID,
+		SlackClientSecret: mockClientSecret,
+	})
+	assert.ErrorContains(t, err, "static not found")
+}
+
 func TestRedirectURI(t *testing.T) {
 	for _, test := range []struct {
 		target   string
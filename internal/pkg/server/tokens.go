@@ -0,0 +1,299 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/gouline/blaster/internal/pkg/store"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// API token scopes, each granting access to a group of /api/v1 endpoints.
+const (
+	scopeSuggest = "suggest"
+	scopeSend    = "send"
+	scopeHistory = "history"
+)
+
+var tokenScopes = []string{scopeSuggest, scopeSend, scopeHistory}
+
+const (
+	// tokenPrefix makes tokens recognizable, such as by secret scanners
+	tokenPrefix = "blaster_"
+
+	// tokenIDLength is the length of the ID following tokenPrefix, as generated by [store.NewID]
+	tokenIDLength = 24
+
+	tokenDefaultExpiryDays = 30
+	tokenMaxExpiryDays     = 365
+
+	contextKeyToken = "api_token"
+)
+
+// handleAPITokensGet handles GET /api/tokens, listing the user's tokens in workspace.
+func (s *Server) handleAPITokensGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	tokens, err := s.userTokens(session)
+	if err != nil {
+		return errInternal(err)
+	}
+
+	infos := []*tokenInfo{}
+	for _, token := range tokens {
+		infos = append(infos, token.info())
+	}
+	return c.JSON(http.StatusOK, infos)
+}
+
+// handleAPITokensCreate handles POST /api/tokens, issuing a token acting as the current user with the Slack
+// authorization they last signed in with.
+// Token secret is only returned in this response.
+func (s *Server) handleAPITokensCreate(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request tokenRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if err := request.validate(); err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	// Authorization is saved on sign-in, but sessions from before it was recorded don't have one yet
+	if err := s.saveAuthorization(session); err != nil {
+		return errInternal(err)
+	}
+
+	secret, err := newTokenSecret()
+	if err != nil {
+		return errInternal(err)
+	}
+	now := time.Now().UTC()
+	token := &apiToken{
+		ID:          store.NewID(),
+		WorkspaceID: session.WorkspaceID(),
+		User:        session.UserID(),
+		Name:        request.Name,
+		Scopes:      request.Scopes,
+		CreatedAt:   now,
+		ExpiresAt:   now.AddDate(0, 0, request.ExpiresInDays),
+	}
+	value := tokenPrefix + token.ID + secret
+	token.Hash = hashToken(value)
+	if err := s.tokens.Put(token.ID, token); err != nil {
+		return errInternal(err)
+	}
+
+	info := token.info()
+	info.Token = value
+	return c.JSON(http.StatusCreated, info)
+}
+
+// handleAPITokenDelete handles DELETE /api/tokens/:id, revoking one of the user's tokens.
+func (s *Server) handleAPITokenDelete(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	token, err := s.tokens.Get(c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if token == nil || token.WorkspaceID != session.WorkspaceID() || token.User != session.UserID() {
+		return errNotFound()
+	}
+
+	if _, err := s.tokens.Delete(token.ID); err != nil {
+		return errInternal(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// userTokens returns tokens issued to session's user in workspace.
+func (s *Server) userTokens(session slack.Session) ([]*apiToken, error) {
+	return s.tokens.List(func(token *apiToken) bool {
+		return token.WorkspaceID == session.WorkspaceID() && token.User == session.UserID()
+	})
+}
+
+// middlewareToken authenticates /api/v1 requests with a bearer token, replacing the cookie session
+// with the Slack authorization the token's user last signed in with.
+func (s *Server) middlewareToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		value, found := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !found {
+			return errInvalidToken("Missing bearer token")
+		}
+
+		token, err := s.lookupToken(strings.TrimSpace(value))
+		if err != nil {
+			return errInternal(err)
+		} else if token == nil {
+			return errInvalidToken("Invalid token")
+		} else if !time.Now().Before(token.ExpiresAt) {
+			return errInvalidToken("Token expired")
+		}
+
+		session, err := s.userSession(token.WorkspaceID, token.User)
+		if err != nil {
+			return errInternal(err)
+		} else if session == nil {
+			return errInvalidToken("Token's user must sign in again")
+		}
+		c.Set(cookieSession, session)
+		c.Set(contextKeyToken, token)
+
+		if _, err := s.tokens.Update(token.ID, func(token *apiToken) error {
+			now := time.Now().UTC()
+			token.LastUsedAt = &now
+			return nil
+		}); err != nil {
+			s.config.Logger.Warn("failed to update token usage", zap.String("token", token.ID), zap.Error(err))
+		}
+
+		return next(c)
+	}
+}
+
+// requireScope creates middleware rejecting tokens without scope, must follow [Server.middlewareToken].
+func requireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := contextToken(c)
+			if token == nil || !slices.Contains(token.Scopes, scope) {
+				return errForbidden("Token is missing scope " + scope)
+			}
+			return next(c)
+		}
+	}
+}
+
+// contextToken returns token authenticating the request, nil for cookie sessions.
+func contextToken(c echo.Context) *apiToken {
+	token, _ := c.Get(contextKeyToken).(*apiToken)
+	return token
+}
+
+// lookupToken finds token by value, returning nil if there is no such token.
+func (s *Server) lookupToken(value string) (*apiToken, error) {
+	rest, found := strings.CutPrefix(value, tokenPrefix)
+	if !found || len(rest) <= tokenIDLength {
+		return nil, nil
+	}
+
+	token, err := s.tokens.Get(rest[:tokenIDLength])
+	if err != nil || token == nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashToken(value))) != 1 {
+		return nil, nil
+	}
+	return token, nil
+}
+
+// newTokenSecret generates random part of token value.
+func newTokenSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns token value hashed with SHA-256, so that token values can't be recovered from the store.
+func hashToken(value string) string {
+	h := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(h[:])
+}
+
+func errInvalidToken(message string) *apiError {
+	return newAPIError(http.StatusUnauthorized, errorCodeUnauthorized, message)
+}
+
+// apiToken is a personal access token for /api/v1, acting as its user with their saved [authorization].
+type apiToken struct {
+	ID          string   `json:"id"`
+	WorkspaceID string   `json:"workspace_id"`
+	User        string   `json:"user"`
+	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes"`
+
+	// Hash is SHA-256 of the token value, which is never stored
+	Hash string `json:"hash"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func (t *apiToken) info() *tokenInfo {
+	return &tokenInfo{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+// tokenInfo describes token to its owner, without secrets.
+type tokenInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// Token is the value to authenticate with, only set when token is created
+	Token string `json:"token,omitempty"`
+}
+
+type tokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func (r *tokenRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+
+	r.Scopes = uniqueStrings(r.Scopes)
+	if len(r.Scopes) == 0 {
+		return fmt.Errorf("missing scopes")
+	}
+	for _, scope := range r.Scopes {
+		if !slices.Contains(tokenScopes, scope) {
+			return fmt.Errorf("unknown scope %s", scope)
+		}
+	}
+
+	if r.ExpiresInDays == 0 {
+		r.ExpiresInDays = tokenDefaultExpiryDays
+	}
+	if r.ExpiresInDays < 1 || r.ExpiresInDays > tokenMaxExpiryDays {
+		return fmt.Errorf("expiry must be between 1 and %d days", tokenMaxExpiryDays)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenRequestValidate(t *testing.T) {
+	for _, test := range []struct {
+		request       tokenRequest
+		errorContains string
+	}{
+		{request: tokenRequest{Name: " CI ", Scopes: []string{"send", "send"}}},
+		{request: tokenRequest{Scopes: []string{"send"}}, errorContains: "missing name"},
+		{request: tokenRequest{Name: "CI"}, errorContains: "missing scopes"},
+		{request: tokenRequest{Name: "CI", Scopes: []string{"admin"}}, errorContains: "unknown scope admin"},
+		{request: tokenRequest{Name: "CI", Scopes: []string{"send"}, ExpiresInDays: 366}, errorContains: "expiry"},
+		{request: tokenRequest{Name: "CI", Scopes: []string{"send"}, ExpiresInDays: -1}, errorContains: "expiry"},
+	} {
+		err := test.request.validate()
+		if test.errorContains == "" {
+			if assert.NoError(t, err) {
+				assert.Equal(t, "CI", test.request.Name)
+				assert.Equal(t, []string{"send"}, test.request.Scopes)
+				assert.Equal(t, tokenDefaultExpiryDays, test.request.ExpiresInDays)
+			}
+		} else {
+			assert.ErrorContains(t, err, test.errorContains)
+		}
+	}
+}
+
+func TestHandleAPITokensLifecycle(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	request := func(method, body, id, user string, handler echo.HandlerFunc) *requestTester {
+		session := &mockSlackSession{ClientSession: &slack.ClientSession{Token: "xoxp-1", Team: "ACME", TeamID: "T1", User: user}}
+		return serveAPI(t, server, session, method, body, handler, "id", id)
+	}
+	call := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		response := httptest.NewRecorder()
+		server.echo.ServeHTTP(response, req)
+		return response
+	}
+
+	r := request(http.MethodPost, `{"name":"CI","scopes":["history"],"expires_in_days":7}`, "", "U1", server.handleAPITokensCreate)
+	if !assert.Equal(t, http.StatusCreated, r.Response.Code) {
+		return
+	}
+	var created tokenInfo
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.Token, tokenPrefix+created.ID))
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 7), created.ExpiresAt, time.Minute)
+
+	r = request(http.MethodGet, "", "", "U1", server.handleAPITokensGet)
+	var listed []*tokenInfo
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &listed)) && assert.Len(t, listed, 1) {
+		assert.Equal(t, created.ID, listed[0].ID)
+		assert.Empty(t, listed[0].Token)
+		assert.NotContains(t, r.Response.Body.String(), "xoxp-1")
+	}
+
+	r = request(http.MethodGet, "", "", "U2", server.handleAPITokensGet)
+	assert.Equal(t, "[]\n", r.Response.Body.String())
+
+	response := call(http.MethodGet, "/api/v1/blasts", created.Token)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = call(http.MethodGet, "/api/v1/suggest?term=jane", created.Token)
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.Contains(t, response.Body.String(), "scope suggest")
+
+	r = request(http.MethodDelete, "", created.ID, "U2", server.handleAPITokenDelete)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+
+	r = request(http.MethodDelete, "", created.ID, "U1", server.handleAPITokenDelete)
+	assert.Equal(t, http.StatusNoContent, r.Response.Code)
+
+	response = call(http.MethodGet, "/api/v1/blasts", created.Token)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestMiddlewareToken(t *testing.T) {
+	r := newRequestTester(http.MethodGet, "/api/v1", nil)
+	r.Authenticate("xoxp-1", "ACME")
+	r.Session.TeamID = "T1"
+	r.Session.User = "U1"
+	value := tokenPrefix + "0123456789abcdef01234567" + "secret"
+	token := &apiToken{
+		ID:          "0123456789abcdef01234567",
+		WorkspaceID: "T1",
+		User:        "U1",
+		Scopes:      []string{scopeSend},
+		Hash:        hashToken(value),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, r.Server.tokens.Put(token.ID, token))
+	assert.NoError(t, r.Server.saveAuthorization(r.Session))
+
+	handler := r.Server.middlewareToken(requireScope(scopeSend)(func(c echo.Context) error {
+		session := r.Server.session(c)
+		return c.String(http.StatusOK, session.WorkspaceID()+"/"+session.UserID())
+	}))
+
+	for _, test := range []struct {
+		header   string
+		expires  time.Time
+		status   int
+		expected string
+	}{
+		{header: "", status: http.StatusUnauthorized, expected: "Missing bearer token"},
+		{header: "Basic " + value, status: http.StatusUnauthorized, expected: "Missing bearer token"},
+		{header: "Bearer blaster_short", status: http.StatusUnauthorized, expected: "Invalid token"},
+		{header: "Bearer " + value + "x", status: http.StatusUnauthorized, expected: "Invalid token"},
+		{header: "Bearer " + value, expires: time.Now().Add(-time.Minute), status: http.StatusUnauthorized, expected: "Token expired"},
+		{header: "Bearer " + value, status: http.StatusOK, expected: "T1/U1"},
+	} {
+		if !test.expires.IsZero() {
+			expired := *token
+			expired.ExpiresAt = test.expires
+			assert.NoError(t, r.Server.tokens.Put(token.ID, &expired))
+		} else {
+			assert.NoError(t, r.Server.tokens.Put(token.ID, token))
+		}
+
+		tr := newRequestTester(http.MethodGet, "/api/v1", nil)
+		tr.Server = r.Server
+		tr.Context = r.Server.echo.NewContext(tr.Request, tr.Response)
+		tr.Request.Header.Set(echo.HeaderAuthorization, test.header)
+
+		if assert.NoError(t, tr.Handle(handler)) {
+			assert.Equal(t, test.status, tr.Response.Code, test.header)
+			assert.Contains(t, tr.Response.Body.String(), test.expected)
+		}
+	}
+
+	used, err := r.Server.tokens.Get(token.ID)
+	if assert.NoError(t, err) {
+		assert.NotNil(t, used.LastUsedAt)
+	}
+
+	// Tokens don't keep a Slack session of their own
+	_, err = r.Server.authorizations.Delete(exclusionKey("T1", "U1"))
+	assert.NoError(t, err)
+	tr := newRequestTester(http.MethodGet, "/api/v1", nil)
+	tr.Server = r.Server
+	tr.Context = r.Server.echo.NewContext(tr.Request, tr.Response)
+	tr.Request.Header.Set(echo.HeaderAuthorization, "Bearer "+value)
+	if assert.NoError(t, tr.Handle(handler)) {
+		assert.Equal(t, http.StatusUnauthorized, tr.Response.Code)
+		assert.Contains(t, tr.Response.Body.String(), "sign in again")
+	}
+}
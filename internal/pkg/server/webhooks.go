@@ -0,0 +1,414 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/slack"
+	"github.com/gouline/blaster/internal/pkg/store"
+	"github.com/gouline/blaster/internal/pkg/webhook"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Blast lifecycle events delivered to webhooks.
+const (
+	eventBlastCreated   = "blast.created"
+	eventBlastApproved  = "blast.approved"
+	eventBlastCompleted = "blast.completed"
+	eventBlastFailed    = "blast.failed"
+)
+
+var webhookEvents = []string{eventBlastCreated, eventBlastApproved, eventBlastCompleted, eventBlastFailed}
+
+// checkWebhookHost rejects webhook hosts that don't resolve to public addresses, replaced in tests.
+var checkWebhookHost = webhook.CheckHost
+
+const (
+	deliveryStatusPending   = "pending"
+	deliveryStatusSucceeded = "succeeded"
+	deliveryStatusFailed    = "failed"
+)
+
+// handleAPIWebhooksGet handles GET /api/webhooks.
+func (s *Server) handleAPIWebhooksGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	webhooks, err := s.webhooks.List(func(w *webhookSubscription) bool {
+		return w.WorkspaceID == session.WorkspaceID()
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+
+	infos := []*webhookInfo{}
+	for _, w := range webhooks {
+		infos = append(infos, w.info())
+	}
+	return c.JSON(http.StatusOK, infos)
+}
+
+// handleAPIWebhooksCreate handles POST /api/webhooks, returning the signing secret only in this response.
+func (s *Server) handleAPIWebhooksCreate(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	var request webhookRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	if err := request.validate(); err != nil {
+		return errBadRequest(err.Error())
+	}
+
+	secret, err := newTokenSecret()
+	if err != nil {
+		return errInternal(err)
+	}
+	w := &webhookSubscription{
+		ID:          store.NewID(),
+		WorkspaceID: session.WorkspaceID(),
+		URL:         request.URL,
+		Events:      request.Events,
+		Secret:      secret,
+		CreatedBy:   session.UserID(),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.webhooks.Put(w.ID, w); err != nil {
+		return errInternal(err)
+	}
+
+	info := w.info()
+	info.Secret = w.Secret
+	return c.JSON(http.StatusCreated, info)
+}
+
+// handleAPIWebhookDelete handles DELETE /api/webhooks/:id.
+func (s *Server) handleAPIWebhookDelete(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	w, err := s.workspaceWebhook(session, c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if w == nil {
+		return errNotFound()
+	}
+
+	if _, err := s.webhooks.Delete(w.ID); err != nil {
+		return errInternal(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// handleAPIWebhookDeliveriesGet handles GET /api/webhooks/:id/deliveries, listing the most recent first.
+func (s *Server) handleAPIWebhookDeliveriesGet(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	w, err := s.workspaceWebhook(session, c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if w == nil {
+		return errNotFound()
+	}
+
+	deliveries, err := s.deliveries.List(func(delivery *webhookDelivery) bool {
+		return delivery.WebhookID == w.ID
+	})
+	if err != nil {
+		return errInternal(err)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// handleAPIWebhookRedeliver handles POST /api/webhooks/:id/deliveries/:delivery/redeliver,
+// delivering the same payload again as a new delivery.
+func (s *Server) handleAPIWebhookRedeliver(c echo.Context) error {
+	session := s.session(c)
+	if !session.IsAuthenticated() {
+		return errUnauthorized()
+	}
+
+	w, err := s.workspaceWebhook(session, c.Param("id"))
+	if err != nil {
+		return errInternal(err)
+	} else if w == nil {
+		return errNotFound()
+	}
+	original, err := s.deliveries.Get(c.Param("delivery"))
+	if err != nil {
+		return errInternal(err)
+	} else if original == nil || original.WebhookID != w.ID {
+		return errNotFound()
+	}
+
+	delivery := &webhookDelivery{
+		ID:           store.NewID(),
+		WebhookID:    w.ID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		Status:       deliveryStatusPending,
+		Attempts:     []webhook.Attempt{},
+		RedeliveryOf: original.ID,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.deliveries.Put(delivery.ID, delivery); err != nil {
+		return errInternal(err)
+	}
+	s.deliver(w, delivery)
+
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+// workspaceWebhook returns webhook by ID, or nil if it's not found in session's workspace.
+func (s *Server) workspaceWebhook(session slack.Session, id string) (*webhookSubscription, error) {
+	w, err := s.webhooks.Get(id)
+	if err != nil || w == nil || w.WorkspaceID != session.WorkspaceID() {
+		return nil, err
+	}
+	return w, nil
+}
+
+// emitBlastEvent delivers event about blast to webhooks subscribed to it in blast's workspace.
+// Delivery is asynchronous and failures are only logged, so that webhooks never affect sending.
+func (s *Server) emitBlastEvent(event string, blast *blast) {
+	webhooks, err := s.webhooks.List(func(w *webhookSubscription) bool {
+		return w.WorkspaceID == blast.WorkspaceID && slices.Contains(w.Events, event)
+	})
+	if err != nil {
+		s.config.Logger.Error("failed to list webhooks", zap.String("event", event), zap.Error(err))
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(&webhookPayload{
+		ID:          store.NewID(),
+		Event:       event,
+		WorkspaceID: blast.WorkspaceID,
+		CreatedAt:   time.Now().UTC(),
+		Blast:       newWebhookBlast(blast),
+	})
+	if err != nil {
+		s.config.Logger.Error("failed to encode webhook payload", zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	for _, w := range webhooks {
+		delivery := &webhookDelivery{
+			ID:        store.NewID(),
+			WebhookID: w.ID,
+			Event:     event,
+			Payload:   payload,
+			Status:    deliveryStatusPending,
+			Attempts:  []webhook.Attempt{},
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := s.deliveries.Put(delivery.ID, delivery); err != nil {
+			s.config.Logger.Error("failed to save webhook delivery", zap.String("webhook", w.ID), zap.Error(err))
+			continue
+		}
+		s.deliver(w, delivery)
+	}
+}
+
+// resumeDeliveries restarts deliveries left pending when the server stopped.
+func (s *Server) resumeDeliveries() error {
+	deliveries, err := s.deliveries.List(func(delivery *webhookDelivery) bool {
+		return delivery.Status == deliveryStatusPending
+	})
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		w, err := s.webhooks.Get(delivery.WebhookID)
+		if err != nil {
+			return err
+		}
+		if w == nil {
+			s.finishDelivery(delivery.ID, false)
+			continue
+		}
+		s.deliver(w, delivery)
+	}
+	return nil
+}
+
+// deliver sends delivery in the background, recording every attempt.
+func (s *Server) deliver(w *webhookSubscription, delivery *webhookDelivery) {
+	s.deliveriesWG.Add(1)
+	go func() {
+		defer s.deliveriesWG.Done()
+
+		succeeded := s.webhookClient.Deliver(context.Background(), webhook.Request{
+			URL:      w.URL,
+			Secret:   w.Secret,
+			Event:    delivery.Event,
+			Delivery: delivery.ID,
+			Body:     delivery.Payload,
+		}, func(attempt webhook.Attempt) {
+			if _, err := s.deliveries.Update(delivery.ID, func(delivery *webhookDelivery) error {
+				delivery.Attempts = append(delivery.Attempts, attempt)
+				return nil
+			}); err != nil {
+				s.config.Logger.Error("failed to record webhook attempt", zap.String("delivery", delivery.ID), zap.Error(err))
+			}
+		})
+
+		s.finishDelivery(delivery.ID, succeeded)
+		if !succeeded {
+			s.config.Logger.Warn("webhook delivery failed", zap.String("webhook", w.ID), zap.String("delivery", delivery.ID))
+		}
+	}()
+}
+
+func (s *Server) finishDelivery(id string, succeeded bool) {
+	if _, err := s.deliveries.Update(id, func(delivery *webhookDelivery) error {
+		now := time.Now().UTC()
+		delivery.Status = deliveryStatusFailed
+		if succeeded {
+			delivery.Status = deliveryStatusSucceeded
+		}
+		delivery.CompletedAt = &now
+		return nil
+	}); err != nil {
+		s.config.Logger.Error("failed to finish webhook delivery", zap.String("delivery", id), zap.Error(err))
+	}
+}
+
+// webhookSubscription posts signed events to URL.
+type webhookSubscription struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Secret      string    `json:"secret"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (w *webhookSubscription) info() *webhookInfo {
+	return &webhookInfo{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedBy: w.CreatedBy,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// webhookInfo describes webhook without its secret.
+type webhookInfo struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Secret signs deliveries, only set when webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (r *webhookRequest) validate() error {
+	r.URL = strings.TrimSpace(r.URL)
+	u, err := url.Parse(r.URL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("url must be absolute HTTPS URL")
+	}
+	if err := checkWebhookHost(context.Background(), u.Hostname()); err != nil {
+		return fmt.Errorf("url must be public: %w", err)
+	}
+
+	r.Events = uniqueStrings(r.Events)
+	if len(r.Events) == 0 {
+		return fmt.Errorf("missing events")
+	}
+	for _, event := range r.Events {
+		if !slices.Contains(webhookEvents, event) {
+			return fmt.Errorf("unknown event %s", event)
+		}
+	}
+	return nil
+}
+
+// webhookPayload is the JSON body delivered for an event. ID identifies the event across redeliveries.
+type webhookPayload struct {
+	ID          string        `json:"id"`
+	Event       string        `json:"event"`
+	WorkspaceID string        `json:"workspace_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Blast       *webhookBlast `json:"blast"`
+}
+
+// webhookBlast summarizes blast for webhooks, leaving out recipients, their messages and responses.
+type webhookBlast struct {
+	ID          string     `json:"id"`
+	CreatedBy   string     `json:"created_by"`
+	Message     string     `json:"message"`
+	Status      string     `json:"status"`
+	Sent        int        `json:"sent"`
+	Skipped     int        `json:"skipped"`
+	Failed      int        `json:"failed"`
+	Approval    *approval  `json:"approval,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+func newWebhookBlast(blast *blast) *webhookBlast {
+	return &webhookBlast{
+		ID:          blast.ID,
+		CreatedBy:   blast.CreatedBy,
+		Message:     blast.Message,
+		Status:      blast.Status,
+		Sent:        len(blast.Sent),
+		Skipped:     len(blast.Skipped),
+		Failed:      len(blast.Failed),
+		Approval:    blast.Approval,
+		CreatedAt:   blast.CreatedAt,
+		CompletedAt: blast.CompletedAt,
+	}
+}
+
+// webhookDelivery is the log of delivering one payload to a webhook.
+type webhookDelivery struct {
+	ID        string            `json:"id"`
+	WebhookID string            `json:"webhook_id"`
+	Event     string            `json:"event"`
+	Payload   json.RawMessage   `json:"payload"`
+	Status    string            `json:"status"`
+	Attempts  []webhook.Attempt `json:"attempts"`
+
+	// RedeliveryOf is the ID of the delivery this one repeats
+	RedeliveryOf string `json:"redelivery_of,omitempty"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gouline/blaster/internal/pkg/webhook"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// allowWebhookHosts lets webhooks be delivered to test servers, restoring the host check when the test ends.
+func allowWebhookHosts(t *testing.T, server *Server, client *http.Client) {
+	original := checkWebhookHost
+	t.Cleanup(func() { checkWebhookHost = original })
+	checkWebhookHost = func(context.Context, string) error { return nil }
+	server.webhookClient.HTTP = client
+}
+
+func TestWebhookRequestValidate(t *testing.T) {
+	defer func(original func(context.Context, string) error) { checkWebhookHost = original }(checkWebhookHost)
+	resolved := map[string]string{"example.com": "93.184.216.34", "metadata.internal": "169.254.169.254"}
+	checkWebhookHost = func(ctx context.Context, host string) error {
+		if ip, ok := resolved[host]; ok {
+			host = ip
+		}
+		return webhook.CheckHost(ctx, host)
+	}
+
+	for _, test := range []struct {
+		request       webhookRequest
+		errorContains string
+	}{
+		{request: webhookRequest{URL: " https://example.com/hook ", Events: []string{eventBlastCompleted}}},
+		{request: webhookRequest{URL: "example.com/hook", Events: []string{eventBlastCompleted}}, errorContains: "url"},
+		{request: webhookRequest{URL: "ftp://example.com", Events: []string{eventBlastCompleted}}, errorContains: "url"},
+		{request: webhookRequest{URL: "http://example.com/hook", Events: []string{eventBlastCompleted}}, errorContains: "HTTPS"},
+		{request: webhookRequest{URL: "https://127.0.0.1:8080/hook", Events: []string{eventBlastCompleted}}, errorContains: "public"},
+		{request: webhookRequest{URL: "https://[::1]/hook", Events: []string{eventBlastCompleted}}, errorContains: "public"},
+		{request: webhookRequest{URL: "https://10.0.0.1/hook", Events: []string{eventBlastCompleted}}, errorContains: "public"},
+		{request: webhookRequest{URL: "https://metadata.internal/hook", Events: []string{eventBlastCompleted}}, errorContains: "public"},
+		{request: webhookRequest{URL: "https://example.com"}, errorContains: "missing events"},
+		{request: webhookRequest{URL: "https://example.com", Events: []string{"blast.deleted"}}, errorContains: "unknown event"},
+	} {
+		err := test.request.validate()
+		if test.errorContains == "" {
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/hook", test.request.URL)
+		} else {
+			assert.ErrorContains(t, err, test.errorContains)
+		}
+	}
+}
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	payloads []*webhookPayload
+	status   int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request, secret *string) {
+	body, _ := io.ReadAll(req.Body)
+	if err := webhook.Verify(*secret, req.Header.Get(webhook.HeaderSignature), req.Header.Get(webhook.HeaderTimestamp), body, time.Minute); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var payload webhookPayload
+	json.Unmarshal(body, &payload)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, &payload)
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+func TestWebhooksLifecycle(t *testing.T) {
+	server := newRequestTester(http.MethodGet, "/api", nil).Server
+	server.webhookClient.Backoff = nil
+
+	var secret string
+	receiver := &webhookReceiver{}
+	hook := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receiver.ServeHTTP(w, r, &secret)
+	}))
+	defer hook.Close()
+	allowWebhookHosts(t, server, hook.Client())
+
+	request := func(method, body, workspace string, handler echo.HandlerFunc, params ...string) *requestTester {
+		return serveAPI(t, server, newMockSession(workspace, "U9"), method, body, handler, params...)
+	}
+
+	r := request(http.MethodPost, `{"url":"`+hook.URL+`","events":["blast.created","blast.completed"]}`, "T1", server.handleAPIWebhooksCreate)
+	if !assert.Equal(t, http.StatusCreated, r.Response.Code) {
+		return
+	}
+	var created webhookInfo
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Secret)
+	secret = created.Secret
+
+	r = request(http.MethodGet, "", "T1", server.handleAPIWebhooksGet)
+	assert.NotContains(t, r.Response.Body.String(), secret)
+
+	r = request(http.MethodPost, `{"users":["U1"],"message":"Hello"}`, "T1", server.handleAPISend)
+	assert.Equal(t, http.StatusOK, r.Response.Code)
+	request(http.MethodPost, `{"users":["U1"],"message":"Elsewhere"}`, "T2", server.handleAPISend)
+	server.deliveriesWG.Wait()
+
+	events := []string{}
+	for _, payload := range receiver.payloads {
+		events = append(events, payload.Event)
+		assert.Equal(t, "T1", payload.WorkspaceID)
+		assert.Equal(t, "Hello", payload.Blast.Message)
+		if payload.Event == eventBlastCompleted {
+			assert.Equal(t, 1, payload.Blast.Sent)
+		}
+	}
+	assert.ElementsMatch(t, []string{eventBlastCreated, eventBlastCompleted}, events)
+
+	r = request(http.MethodGet, "", "T1", server.handleAPIWebhookDeliveriesGet, "id", created.ID)
+	var deliveries []*webhookDelivery
+	if assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &deliveries)) && assert.Len(t, deliveries, 2) {
+		assert.Equal(t, deliveryStatusSucceeded, deliveries[0].Status)
+		assert.Len(t, deliveries[0].Attempts, 1)
+		assert.Equal(t, http.StatusOK, deliveries[0].Attempts[0].StatusCode)
+	}
+
+	r = request(http.MethodGet, "", "T2", server.handleAPIWebhookDeliveriesGet, "id", created.ID)
+	assert.Equal(t, http.StatusNotFound, r.Response.Code)
+
+	receiver.status = http.StatusGone
+	r = request(http.MethodPost, "", "T1", server.handleAPIWebhookRedeliver, "id", created.ID, "delivery", deliveries[0].ID)
+	assert.Equal(t, http.StatusAccepted, r.Response.Code)
+	server.deliveriesWG.Wait()
+
+	if assert.Len(t, receiver.payloads, 3) {
+		assert.Contains(t, []string{receiver.payloads[0].ID, receiver.payloads[1].ID}, receiver.payloads[2].ID, "redelivery repeats event ID")
+	}
+	var redelivery webhookDelivery
+	assert.NoError(t, json.Unmarshal(r.Response.Body.Bytes(), &redelivery))
+	stored, err := server.deliveries.Get(redelivery.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, deliveries[0].ID, stored.RedeliveryOf)
+		assert.Equal(t, deliveryStatusFailed, stored.Status)
+	}
+
+	r = request(http.MethodDelete, "", "T1", server.handleAPIWebhookDelete, "id", created.ID)
+	assert.Equal(t, http.StatusNoContent, r.Response.Code)
+}
+
+func TestWebhooksBlastFailed(t *testing.T) {
+	r := newRequestTester(http.MethodPost, "/api", strings.NewReader(`{"users":["U1"],"message":"Hello"}`))
+	r.Request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	r.Authenticate("1", "")
+	r.Session.TeamID = "T1"
+	r.Session.PostMessageError = errors.New("boom")
+	r.Server.webhookClient.Backoff = nil
+
+	received := make(chan string, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received <- req.Header.Get(webhook.HeaderEvent)
+	}))
+	defer hook.Close()
+	allowWebhookHosts(t, r.Server, hook.Client())
+	assert.NoError(t, r.Server.webhooks.Put("w1", &webhookSubscription{
+		ID: "w1", WorkspaceID: "T1", URL: hook.URL, Events: []string{eventBlastCompleted, eventBlastFailed},
+	}))
+
+	assert.NoError(t, r.Handle(r.Server.handleAPISend))
+	r.Server.deliveriesWG.Wait()
+	close(received)
+
+	events := []string{}
+	for event := range received {
+		events = append(events, event)
+	}
+	assert.Equal(t, []string{eventBlastFailed}, events)
+
+	blasts, err := r.Server.blasts.List(nil)
+	if assert.NoError(t, err) && assert.Len(t, blasts, 1) {
+		assert.Equal(t, blastStatusFailed, blasts[0].Status)
+	}
+}
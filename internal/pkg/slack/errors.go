@@ -0,0 +1,44 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// retryableErrors are Slack API error codes caused by temporary conditions.
+var retryableErrors = map[string]bool{
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+	"ratelimited":         true,
+}
+
+// APIError describes a failed Slack API request.
+type APIError struct {
+	// Code is Slack's error code, such as "channel_not_found"
+	Code string
+
+	Retryable   bool
+	RateLimited bool
+	RetryAfter  time.Duration
+}
+
+// ParseError extracts Slack API error from err, returning nil if it didn't come from Slack.
+func ParseError(err error) *APIError {
+	var response slack.SlackErrorResponse
+	var rateLimited *slack.RateLimitedError
+	var status slack.StatusCodeError
+	switch {
+	case errors.As(err, &rateLimited):
+		return &APIError{Code: "ratelimited", Retryable: true, RateLimited: true, RetryAfter: rateLimited.RetryAfter}
+	case errors.As(err, &status):
+		return &APIError{Code: fmt.Sprintf("http_%d", status.Code), Retryable: status.Retryable()}
+	case errors.As(err, &response):
+		return &APIError{Code: response.Err, Retryable: retryableErrors[response.Err]}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected *APIError
+	}{
+		{
+			err:      errors.New("simulated"),
+			expected: nil,
+		},
+		{
+			err:      slack.SlackErrorResponse{Err: "channel_not_found"},
+			expected: &APIError{Code: "channel_not_found"},
+		},
+		{
+			err:      fmt.Errorf("failed to expand S1: %w", slack.SlackErrorResponse{Err: "internal_error"}),
+			expected: &APIError{Code: "internal_error", Retryable: true},
+		},
+		{
+			err:      &slack.RateLimitedError{RetryAfter: 30 * time.Second},
+			expected: &APIError{Code: "ratelimited", Retryable: true, RateLimited: true, RetryAfter: 30 * time.Second},
+		},
+		{
+			err:      slack.StatusCodeError{Code: 503, Status: "Service Unavailable"},
+			expected: &APIError{Code: "http_503", Retryable: true},
+		},
+		{
+			err:      slack.StatusCodeError{Code: 404, Status: "Not Found"},
+			expected: &APIError{Code: "http_404"},
+		},
+	} {
+		assert.Equal(t, test.expected, ParseError(test.err), "error: %s", test.err)
+	}
+}
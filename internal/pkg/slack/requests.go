@@ -0,0 +1,145 @@
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// SlashCommand is a slash command invocation sent by Slack.
+type SlashCommand = slack.SlashCommand
+
+// InteractionCallback is a user interaction sent by Slack, such as clicking a message button.
+type InteractionCallback = slack.InteractionCallback
+
+// MessageEvent is a message posted in a conversation the app is subscribed to.
+type MessageEvent = slackevents.MessageEvent
+
+// Event is an Events API request, either verifying the request URL with a challenge or
+// notifying of an event in a workspace. Message is only set for message events.
+type Event struct {
+	Type      string
+	Challenge string
+	TeamID    string
+	Message   *MessageEvent
+}
+
+// Events API request types.
+const (
+	EventURLVerification = slackevents.URLVerification
+	EventCallback        = slackevents.CallbackEvent
+)
+
+// responseClient posts responses to response URLs, which Slack expects within seconds.
+var responseClient = &http.Client{Timeout: 10 * time.Second}
+
+// VerifyRequest checks signature of a request sent by Slack against signing secret.
+// Request body is restored so that it can be read again.
+func VerifyRequest(r *http.Request, signingSecret string) error {
+	if signingSecret == "" {
+		return fmt.Errorf("missing signing secret")
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+	if err != nil {
+		return fmt.Errorf("invalid signature headers: %w", err)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if _, err := verifier.Write(body); err != nil {
+		return err
+	}
+	if err := verifier.Ensure(); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return nil
+}
+
+// ParseSlashCommand verifies and parses a slash command request.
+func ParseSlashCommand(r *http.Request, signingSecret string) (SlashCommand, error) {
+	if err := VerifyRequest(r, signingSecret); err != nil {
+		return SlashCommand{}, err
+	}
+	return slack.SlashCommandParse(r)
+}
+
+// ParseInteraction verifies and parses an interactivity request, which carries JSON in the payload form field.
+func ParseInteraction(r *http.Request, signingSecret string) (InteractionCallback, error) {
+	var callback InteractionCallback
+	if err := VerifyRequest(r, signingSecret); err != nil {
+		return callback, err
+	}
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &callback); err != nil {
+		return callback, fmt.Errorf("invalid payload: %w", err)
+	}
+	return callback, nil
+}
+
+// ParseEvent verifies and parses an Events API request.
+func ParseEvent(r *http.Request, signingSecret string) (*Event, error) {
+	if err := VerifyRequest(r, signingSecret); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	// Signature is verified instead of the deprecated verification token
+	parsed, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
+	if err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	event := &Event{Type: parsed.Type, TeamID: parsed.TeamID}
+	switch data := parsed.Data.(type) {
+	case *slackevents.EventsAPIURLVerificationEvent:
+		event.Challenge = data.Challenge
+	}
+	if message, ok := parsed.InnerEvent.Data.(*slackevents.MessageEvent); ok {
+		event.Message = message
+	}
+	return event, nil
+}
+
+// Respond posts JSON message to response URL of a slash command or interaction.
+func Respond(responseURL string, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	response, err := responseClient.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to respond: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to respond: HTTP %d", response.StatusCode)
+	}
+	return nil
+}
+
+// SignRequest sets signature headers as Slack would, mainly for testing request handlers.
+func SignRequest(r *http.Request, signingSecret, body string, timestamp time.Time) {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	h := hmac.New(sha256.New, []byte(signingSecret))
+	h.Write([]byte("v0:" + ts + ":" + body))
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(h.Sum(nil)))
+}
@@ -0,0 +1,149 @@
+package slack
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	body := "team_id=T1&user_id=U1&command=%2Fblast&text=hello"
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	SignRequest(r, "secret", body, time.Now())
+
+	command, err := ParseSlashCommand(r, "secret")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "T1", command.TeamID)
+		assert.Equal(t, "U1", command.UserID)
+		assert.Equal(t, "/blast", command.Command)
+		assert.Equal(t, "hello", command.Text)
+	}
+}
+
+func TestParseInteraction(t *testing.T) {
+	body := url.Values{"payload": {`{"type":"block_actions","team":{"id":"T1"},"user":{"id":"U1"},"response_url":"https://hooks.slack.com/x","actions":[{"block_id":"b1","action_id":"send","value":"d1"}]}`}}.Encode()
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	SignRequest(r, "secret", body, time.Now())
+
+	callback, err := ParseInteraction(r, "secret")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "T1", callback.Team.ID)
+		assert.Equal(t, "U1", callback.User.ID)
+		assert.Equal(t, "https://hooks.slack.com/x", callback.ResponseURL)
+		if assert.Len(t, callback.ActionCallback.BlockActions, 1) {
+			assert.Equal(t, "send", callback.ActionCallback.BlockActions[0].ActionID)
+			assert.Equal(t, "d1", callback.ActionCallback.BlockActions[0].Value)
+		}
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload=%7B"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	SignRequest(r, "secret", "payload=%7B", time.Now())
+	_, err = ParseInteraction(r, "secret")
+	assert.ErrorContains(t, err, "invalid payload")
+}
+
+func TestParseEvent(t *testing.T) {
+	for _, test := range []struct {
+		body          string
+		expected      *Event
+		errorContains string
+	}{
+		{
+			body:     `{"type":"url_verification","token":"x","challenge":"c1"}`,
+			expected: &Event{Type: EventURLVerification, Challenge: "c1"},
+		},
+		{
+			body: `{"type":"event_callback","team_id":"T1","event":{"type":"message","channel":"D1","channel_type":"im","user":"U1","text":"hi","ts":"2.000","thread_ts":"1.000"}}`,
+			expected: &Event{Type: EventCallback, TeamID: "T1", Message: &MessageEvent{
+				Type: "message", Channel: "D1", ChannelType: "im", User: "U1", Text: "hi", TimeStamp: "2.000", ThreadTimeStamp: "1.000",
+			}},
+		},
+		{
+			body:     `{"type":"event_callback","team_id":"T1","event":{"type":"app_home_opened","user":"U1"}}`,
+			expected: &Event{Type: EventCallback, TeamID: "T1"},
+		},
+		{body: `{"type":`, errorContains: "invalid event"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		SignRequest(r, "secret", test.body, time.Now())
+
+		event, err := ParseEvent(r, "secret")
+		if test.errorContains != "" {
+			assert.ErrorContains(t, err, test.errorContains)
+		} else if assert.NoError(t, err, test.body) {
+			assert.Equal(t, test.expected, event)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type":"url_verification"}`))
+	SignRequest(r, "other", `{"type":"url_verification"}`, time.Now())
+	_, err := ParseEvent(r, "secret")
+	assert.ErrorContains(t, err, "invalid signature")
+}
+
+func TestRespond(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		if received["text"] == "fail" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	assert.NoError(t, Respond(server.URL, map[string]string{"text": "hello"}))
+	assert.Equal(t, "hello", received["text"])
+	assert.ErrorContains(t, Respond(server.URL, map[string]string{"text": "fail"}), "HTTP 404")
+}
+
+func TestVerifyRequestErrors(t *testing.T) {
+	body := "team_id=T1"
+
+	for _, test := range []struct {
+		signingSecret string
+		sign          func(r *http.Request)
+		errorContains string
+	}{
+		{
+			signingSecret: "",
+			sign:          func(r *http.Request) {},
+			errorContains: "missing signing secret",
+		},
+		{
+			signingSecret: "secret",
+			sign:          func(r *http.Request) {},
+			errorContains: "invalid signature headers",
+		},
+		{
+			signingSecret: "secret",
+			sign: func(r *http.Request) {
+				SignRequest(r, "other", body, time.Now())
+			},
+			errorContains: "invalid signature",
+		},
+		{
+			signingSecret: "secret",
+			sign: func(r *http.Request) {
+				SignRequest(r, "secret", body, time.Now().Add(-time.Hour))
+			},
+			errorContains: "invalid signature headers",
+		},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		test.sign(r)
+		assert.ErrorContains(t, VerifyRequest(r, test.signingSecret), test.errorContains)
+	}
+}
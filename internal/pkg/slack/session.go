@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gouline/blaster/internal/pkg/scache"
+	"github.com/gouline/blaster/internal/pkg/search"
 	"github.com/slack-go/slack"
 )
 
@@ -17,30 +20,77 @@ var (
 	scopes = []string{
 		"team:read",
 		"users:read",
+		"users:read.email",
 		"usergroups:read",
+		"channels:read",
+		"groups:read",
 		"im:write",
+		"im:history",
 		"chat:write:bot",
 		"chat:write:user",
 	}
 
 	destinationCache = scache.New(5*time.Minute, 10*time.Minute)
+
+	// profileCache holds custom profile field labels per workspace and profiles per user, which are
+	// fetched one user at a time and change rarely
+	profileCache = scache.New(time.Hour, 2*time.Hour)
+
+	// apiURL is where Slack API methods are called, replaced in tests
+	apiURL = slack.APIURL
 )
 
 type Session interface {
 	Marshal() string
 	Unmarshal(data string)
 	TeamName() string
+	WorkspaceID() string
+	UserID() string
+	Locale() string
 	IsAuthenticated() bool
 	Reset()
 	Authenticate(clientID, clientSecret, redirectURI string, query url.Values) (bool, error)
 	AuthorizeURL(clientID, redirectURI string) (string, error)
 	GetDestinations() ([]*Destination, error)
-	PostMessage(user, message string, asUser bool) error
+	GetProfileFieldNames() ([]string, error)
+	WithProfiles(destinations []*Destination) ([]*Destination, error)
+	GetUserGroupMembers() (map[string][]string, error)
+	GetChannelMembers(channel string) ([]string, error)
+	GetActiveUsers(users []string) ([]*Destination, error)
+	LookupUserByEmail(email string) (*Destination, error)
+	PostMessage(user string, message Message) (*PostedMessage, error)
+	PostChannelMessage(channel string, message Message) (*PostedMessage, error)
+}
+
+// Message is a message to post, optionally laid out with Block Kit blocks.
+type Message struct {
+	// Text is the message, or only its notification fallback when there are blocks
+	Text string
+
+	// Blocks is a JSON array of Block Kit blocks
+	Blocks json.RawMessage
+
+	// AsUser sends as the authenticated user instead of the app's bot
+	AsUser bool
+
+	// ThreadTimestamp replies in the thread of an earlier message in the same channel
+	ThreadTimestamp string
+}
+
+// PostedMessage identifies a posted message, such as for matching replies to it.
+type PostedMessage struct {
+	Channel   string `json:"channel"`
+	Timestamp string `json:"ts"`
 }
 
 type ClientSession struct {
-	Token string `json:"token"`
-	Team  string `json:"team"`
+	Token  string `json:"token"`
+	Team   string `json:"team"`
+	TeamID string `json:"team_id"`
+	User   string `json:"user"`
+
+	// UserLocale is the authenticated user's Slack locale, such as "ja-JP"
+	UserLocale string `json:"locale,omitempty"`
 }
 
 func NewSession() Session {
@@ -63,6 +113,21 @@ func (s *ClientSession) TeamName() string {
 	return s.Team
 }
 
+// WorkspaceID returns ID of the authenticated Slack team.
+func (s *ClientSession) WorkspaceID() string {
+	return s.TeamID
+}
+
+// UserID returns ID of the authenticated Slack user.
+func (s *ClientSession) UserID() string {
+	return s.User
+}
+
+// Locale returns Slack locale of the authenticated user, empty if unknown.
+func (s *ClientSession) Locale() string {
+	return s.UserLocale
+}
+
 // IsAuthenticated returns true if sessions has a token.
 func (s *ClientSession) IsAuthenticated() bool {
 	return s.Token != ""
@@ -71,11 +136,14 @@ func (s *ClientSession) IsAuthenticated() bool {
 func (s *ClientSession) Reset() {
 	s.Token = ""
 	s.Team = ""
+	s.TeamID = ""
+	s.User = ""
+	s.UserLocale = ""
 }
 
 // client creates a new [slack.Client] from token.
 func (s *ClientSession) client() *slack.Client {
-	return slack.New(s.Token)
+	return slack.New(s.Token, slack.OptionAPIURL(apiURL))
 }
 
 // tokenHash returns token hashed with SHA-1.
@@ -97,6 +165,7 @@ func (s *ClientSession) Authenticate(clientID, clientSecret, redirectURI string,
 		return true, err
 	}
 	s.Token = response.AccessToken
+	s.User = response.UserID
 
 	teamInfo, err := s.client().GetTeamInfo()
 	if err != nil {
@@ -104,6 +173,12 @@ func (s *ClientSession) Authenticate(clientID, clientSecret, redirectURI string,
 		return true, err
 	}
 	s.Team = teamInfo.Name
+	s.TeamID = teamInfo.ID
+
+	// Locale only personalizes UI language, so failing to retrieve it doesn't fail authentication
+	if user, err := s.client().GetUserInfo(s.User); err == nil {
+		s.UserLocale = user.Locale
+	}
 
 	return true, nil
 }
@@ -130,9 +205,59 @@ type Destination struct {
 	DisplayName string
 	ID          string
 	Children    []*Destination
+
+	// Profile fields, only populated for users
+	Email    string
+	Title    string
+	Timezone string
+	Locale   string
+	IsGuest  bool
+	IsAdmin  bool
+
+	// Pronouns and Fields, custom profile fields keyed by normalized label, are only set by [Session.WithProfiles]
+	Pronouns string
+	Fields   map[string]string
+
+	// Disabled is only set for user groups that were disabled in Slack
+	Disabled bool
+}
+
+// Field returns value of a built-in or custom profile field by name.
+func (d *Destination) Field(name string) string {
+	switch name {
+	case "type":
+		return d.Type
+	case "email":
+		return d.Email
+	case "title":
+		return d.Title
+	case "tz":
+		return d.Timezone
+	case "locale":
+		return d.Locale
+	case "pronouns":
+		return d.Pronouns
+	}
+	return d.Fields[name]
+}
+
+// HasFlag reports whether destination has a flag, such as "guest", "member" or "admin".
+func (d *Destination) HasFlag(flag string) bool {
+	switch strings.ToLower(flag) {
+	case "guest":
+		return d.IsGuest
+	case "member":
+		return d.Type == "user" && !d.IsGuest
+	case "admin":
+		return d.IsAdmin
+	case "disabled":
+		return d.Disabled
+	}
+	return false
 }
 
 // GetDestinations retrieves a list of users and user groups that you can send messages to.
+// Disabled user groups are included and flagged, so that callers can offer them when explicitly requested.
 func (s *ClientSession) GetDestinations() ([]*Destination, error) {
 	cacheResponse := <-destinationCache.ResponseChan(s.tokenHash(), func(key string) (interface{}, error) {
 		userLookup := map[string]*Destination{}
@@ -147,18 +272,15 @@ func (s *ClientSession) GetDestinations() ([]*Destination, error) {
 				continue
 			}
 
-			d := &Destination{
-				Type:        "user",
-				Name:        user.Profile.RealName,
-				DisplayName: user.Profile.DisplayName,
-				ID:          user.ID,
-			}
-
+			d := userDestination(user)
 			destinations = append(destinations, d)
 			userLookup[user.ID] = d
 		}
 
-		usergroups, err := s.client().GetUserGroups(slack.GetUserGroupsOptionIncludeUsers(true))
+		usergroups, err := s.client().GetUserGroups(
+			slack.GetUserGroupsOptionIncludeUsers(true),
+			slack.GetUserGroupsOptionIncludeDisabled(true),
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get user groups: %w", err)
 		}
@@ -180,9 +302,11 @@ func (s *ClientSession) GetDestinations() ([]*Destination, error) {
 
 			destinations = append(destinations, &Destination{
 				Type:        "usergroup",
+				ID:          usergroup.ID,
 				Name:        usergroup.Name,
 				DisplayName: usergroup.Handle,
 				Children:    children,
+				Disabled:    usergroup.DateDelete != 0,
 			})
 		}
 
@@ -194,9 +318,216 @@ func (s *ClientSession) GetDestinations() ([]*Destination, error) {
 	return cacheResponse.Value.([]*Destination), nil
 }
 
-// SendMessage sends text message to a user by ID.
-// Depending on asUser, message will be sent as your authenticated user or as the app's bot.
-func (s *ClientSession) PostMessage(user, message string, asUser bool) error {
+// userDestination converts Slack user into destination.
+func userDestination(user slack.User) *Destination {
+	d := &Destination{
+		Type:        "user",
+		Name:        user.Profile.RealName,
+		DisplayName: user.Profile.DisplayName,
+		ID:          user.ID,
+		Email:       user.Profile.Email,
+		Title:       user.Profile.Title,
+		Timezone:    user.TZ,
+		Locale:      user.Locale,
+		IsGuest:     user.IsRestricted || user.IsUltraRestricted,
+		IsAdmin:     user.IsAdmin || user.IsOwner,
+	}
+	return d
+}
+
+// GetUserGroupMembers retrieves current members of all user groups by ID in one call, bypassing cache.
+// Disabled user groups are included, because usergroups.users.list can't be asked for them through the client.
+func (s *ClientSession) GetUserGroupMembers() (map[string][]string, error) {
+	usergroups, err := s.client().GetUserGroups(
+		slack.GetUserGroupsOptionIncludeUsers(true),
+		slack.GetUserGroupsOptionIncludeDisabled(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user group members: %w", err)
+	}
+	members := map[string][]string{}
+	for _, group := range usergroups {
+		members[group.ID] = group.Users
+	}
+	return members, nil
+}
+
+// GetChannelMembers retrieves current members of a channel, bypassing cache.
+func (s *ClientSession) GetChannelMembers(channel string) ([]string, error) {
+	members := []string{}
+	params := &slack.GetUsersInConversationParameters{ChannelID: channel, Limit: 1000}
+	for {
+		page, cursor, err := s.client().GetUsersInConversation(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get channel members: %w", err)
+		}
+		members = append(members, page...)
+		if cursor == "" {
+			return members, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// usersInfoBatchSize limits how many users are requested in one call.
+const usersInfoBatchSize = 30
+
+// GetActiveUsers retrieves current profiles for users, omitting deactivated users and bots.
+func (s *ClientSession) GetActiveUsers(users []string) ([]*Destination, error) {
+	active := []*Destination{}
+	for start := 0; start < len(users); start += usersInfoBatchSize {
+		end := min(start+usersInfoBatchSize, len(users))
+		infos, err := s.client().GetUsersInfo(users[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get users: %w", err)
+		}
+		for _, user := range *infos {
+			if user.Deleted || user.IsBot {
+				continue
+			}
+			active = append(active, userDestination(user))
+		}
+	}
+	return active, nil
+}
+
+// LookupUserByEmail finds active user by email, returning nil if there is no such user.
+func (s *ClientSession) LookupUserByEmail(email string) (*Destination, error) {
+	user, err := s.client().GetUserByEmail(email)
+	if err != nil {
+		if err.Error() == "users_not_found" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if user.Deleted || user.IsBot {
+		return nil, nil
+	}
+	return userDestination(*user), nil
+}
+
+// GetProfileFieldNames retrieves normalized labels of workspace's custom profile fields, which are
+// the keys of [Destination.Fields].
+func (s *ClientSession) GetProfileFieldNames() ([]string, error) {
+	labels, err := s.profileFieldLabels()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WithProfiles returns destinations with copies of users that have pronouns and custom profile fields set.
+// users.list doesn't return either, so each user's profile is fetched with users.profile.get and cached,
+// which takes a while for large workspaces the first time.
+func (s *ClientSession) WithProfiles(destinations []*Destination) ([]*Destination, error) {
+	labels, err := s.profileFieldLabels()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Destination, 0, len(destinations))
+	for _, dest := range destinations {
+		if dest.Type != "user" {
+			result = append(result, dest)
+			continue
+		}
+		cacheResponse := <-profileCache.ResponseChan(s.TeamID+"/"+dest.ID, func(key string) (interface{}, error) {
+			return s.getUserProfile(dest.ID)
+		})
+		if cacheResponse.Error != nil {
+			return nil, cacheResponse.Error
+		}
+		profile := cacheResponse.Value.(*userProfile)
+		user := *dest
+		user.Pronouns = profile.Pronouns
+		user.Fields = profileFields(profile.Fields, labels)
+		result = append(result, &user)
+	}
+	return result, nil
+}
+
+// profileFieldLabels retrieves workspace's custom profile field labels by field ID with team.profile.get.
+func (s *ClientSession) profileFieldLabels() (map[string]string, error) {
+	cacheResponse := <-profileCache.ResponseChan(s.TeamID, func(key string) (interface{}, error) {
+		profile, err := s.client().GetTeamProfile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get profile fields: %w", err)
+		}
+		labels := map[string]string{}
+		for _, field := range profile.Fields {
+			labels[field.ID] = strings.ReplaceAll(search.Fold(field.Label), " ", "_")
+		}
+		return labels, nil
+	})
+	if cacheResponse.Error != nil {
+		return nil, cacheResponse.Error
+	}
+	return cacheResponse.Value.(map[string]string), nil
+}
+
+// getUserProfile retrieves user's profile with users.profile.get. The client library doesn't decode pronouns,
+// so the method is called directly.
+func (s *ClientSession) getUserProfile(user string) (*userProfile, error) {
+	response, err := http.PostForm(apiURL+"users.profile.get", url.Values{"token": {s.Token}, "user": {user}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+		return nil, fmt.Errorf("failed to get user profile: %w", &slack.RateLimitedError{RetryAfter: time.Duration(retryAfter) * time.Second})
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get user profile: %w", slack.StatusCodeError{Code: response.StatusCode, Status: response.Status})
+	}
+
+	var body struct {
+		slack.SlackResponse
+		Profile userProfile `json:"profile"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+	if err := body.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+	return &body.Profile, nil
+}
+
+// profileFields converts custom profile field values into a map keyed by their normalized label.
+// Fields without a known label are keyed by their lowercase ID.
+func profileFields(values map[string]userProfileField, labels map[string]string) map[string]string {
+	m := map[string]string{}
+	for id, field := range values {
+		key := labels[id]
+		if key == "" {
+			key = strings.ToLower(id)
+		}
+		value := field.Value
+		if field.Alt != "" {
+			value = field.Alt
+		}
+		m[key] = value
+	}
+	return m
+}
+
+// userProfile is the part of users.profile.get response that users.list lacks.
+type userProfile struct {
+	Pronouns string                      `json:"pronouns"`
+	Fields   map[string]userProfileField `json:"fields"`
+}
+
+type userProfileField struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+}
+
+// PostMessage sends message to a user by ID in their direct message channel.
+func (s *ClientSession) PostMessage(user string, message Message) (*PostedMessage, error) {
 	client := s.client()
 
 	// Open/get channel by user ID
@@ -204,14 +535,31 @@ func (s *ClientSession) PostMessage(user, message string, asUser bool) error {
 		Users: []string{user},
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return s.PostChannelMessage(channel.ID, message)
+}
 
-	// Post message to opened channel
-	_, _, err = client.PostMessage(
-		channel.ID,
-		slack.MsgOptionText(message, false),
-		slack.MsgOptionAsUser(asUser),
-	)
-	return err
+// PostChannelMessage sends message to a conversation by ID, which the authenticated user must be in.
+func (s *ClientSession) PostChannelMessage(channel string, message Message) (*PostedMessage, error) {
+	options := []slack.MsgOption{
+		slack.MsgOptionText(message.Text, false),
+		slack.MsgOptionAsUser(message.AsUser),
+	}
+	if len(message.Blocks) > 0 {
+		var blocks slack.Blocks
+		if err := json.Unmarshal(message.Blocks, &blocks); err != nil {
+			return nil, fmt.Errorf("invalid blocks: %w", err)
+		}
+		options = append(options, slack.MsgOptionBlocks(blocks.BlockSet...))
+	}
+	if message.ThreadTimestamp != "" {
+		options = append(options, slack.MsgOptionTS(message.ThreadTimestamp))
+	}
+
+	channelID, timestamp, err := s.client().PostMessage(channel, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &PostedMessage{Channel: channelID, Timestamp: timestamp}, nil
 }
@@ -1,6 +1,10 @@
 package slack
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,12 +43,15 @@ func TestHashToken(t *testing.T) {
 }
 
 func TestMarshalUnmarshalNormal(t *testing.T) {
-	original := &ClientSession{Token: "123", Team: "abc"}
+	original := &ClientSession{Token: "123", Team: "abc", TeamID: "T1", User: "U1", UserLocale: "ja-JP"}
 	data := original.Marshal()
 	recreated := &ClientSession{}
 	recreated.Unmarshal(data)
 	assert.Equal(t, original, recreated)
 	assert.Equal(t, original.TeamName(), recreated.TeamName())
+	assert.Equal(t, original.WorkspaceID(), recreated.WorkspaceID())
+	assert.Equal(t, original.UserID(), recreated.UserID())
+	assert.Equal(t, original.Locale(), recreated.Locale())
 	assert.Equal(t, original.IsAuthenticated(), recreated.IsAuthenticated())
 }
 
@@ -57,3 +64,56 @@ func TestMarshalEmpty(t *testing.T) {
 	assert.Equal(t, original.TeamName(), recreated.TeamName())
 	assert.Equal(t, original.IsAuthenticated(), recreated.IsAuthenticated())
 }
+
+func TestProfileFields(t *testing.T) {
+	actual := profileFields(map[string]userProfileField{
+		"Xf01": {Value: "2020-01-01"},
+		"Xf02": {Value: "U123", Alt: "Jane Doe"},
+		"Xf03": {Value: "Platform"},
+	}, map[string]string{
+		"Xf01": "start_date",
+		"Xf02": "manager",
+	})
+	assert.Equal(t, map[string]string{
+		"start_date": "2020-01-01",
+		"manager":    "Jane Doe",
+		"xf03":       "Platform",
+	}, actual)
+}
+
+func TestWithProfiles(t *testing.T) {
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		calls[method]++
+		switch method {
+		case "team.profile.get":
+			fmt.Fprint(w, `{"ok":true,"profile":{"fields":[{"id":"Xf01","label":"Department"}]}}`)
+		case "users.profile.get":
+			assert.Equal(t, "U1", r.FormValue("user"))
+			fmt.Fprint(w, `{"ok":true,"profile":{"pronouns":"they/them","fields":{"Xf01":{"value":"Platform","alt":""}}}}`)
+		default:
+			t.Errorf("unexpected method %s", method)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { apiURL = url }(apiURL)
+	apiURL = server.URL + "/"
+
+	session := &ClientSession{TeamID: "TPROFILES", Token: "1"}
+	user := &Destination{Type: "user", ID: "U1", Name: "alice"}
+	channel := &Destination{Type: "channel", ID: "C1", Name: "general"}
+	for i := 0; i < 2; i++ {
+		destinations, err := session.WithProfiles([]*Destination{user, channel})
+		assert.NoError(t, err)
+		assert.Equal(t, "they/them", destinations[0].Pronouns)
+		assert.Equal(t, map[string]string{"department": "Platform"}, destinations[0].Fields)
+		assert.Same(t, channel, destinations[1])
+	}
+	assert.Empty(t, user.Fields, "cached destination modified")
+	assert.Equal(t, map[string]int{"team.profile.get": 1, "users.profile.get": 1}, calls, "profiles not cached")
+
+	names, err := session.GetProfileFieldNames()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"department"}, names)
+}
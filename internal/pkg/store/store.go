@@ -0,0 +1,184 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned when updating a key that doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Store keeps named collections of JSON documents in memory and
+// optionally persists them to a file after every change.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	data map[string]map[string]json.RawMessage
+}
+
+// New creates a store, loading existing data from path if it exists.
+// Empty path keeps data in memory only.
+func New(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: map[string]map[string]json.RawMessage{},
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, fmt.Errorf("failed to read store: %w", err)
+	}
+	if err := json.Unmarshal(bytes, &s.data); err != nil {
+		return s, fmt.Errorf("failed to parse store: %w", err)
+	}
+	return s, nil
+}
+
+// save writes data to file, replacing it atomically. Must be called with mu held.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	bytes, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, bytes, 0o600); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// NewID generates a random identifier for stored documents.
+func NewID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate ID: %s", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Collection provides typed access to documents in a named collection.
+type Collection[T any] struct {
+	store *Store
+	name  string
+}
+
+// NewCollection creates a collection of documents of type T.
+func NewCollection[T any](store *Store, name string) *Collection[T] {
+	return &Collection[T]{
+		store: store,
+		name:  name,
+	}
+}
+
+// Get retrieves document by key, returning nil if not found.
+func (c *Collection[T]) Get(key string) (*T, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	return c.get(key)
+}
+
+func (c *Collection[T]) get(key string) (*T, error) {
+	raw, ok := c.store.data[c.name][key]
+	if !ok {
+		return nil, nil
+	}
+	value := new(T)
+	if err := json.Unmarshal(raw, value); err != nil {
+		return nil, fmt.Errorf("failed to decode %s/%s: %w", c.name, key, err)
+	}
+	return value, nil
+}
+
+// Put creates or replaces document by key.
+func (c *Collection[T]) Put(key string, value *T) error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	return c.put(key, value)
+}
+
+func (c *Collection[T]) put(key string, value *T) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", c.name, key, err)
+	}
+	if c.store.data[c.name] == nil {
+		c.store.data[c.name] = map[string]json.RawMessage{}
+	}
+	c.store.data[c.name][key] = raw
+	return c.store.save()
+}
+
+// Update atomically modifies an existing document by key.
+// Returns [ErrNotFound] if there is no such document.
+func (c *Collection[T]) Update(key string, update func(value *T) error) (*T, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	value, err := c.get(key)
+	if err != nil {
+		return nil, err
+	} else if value == nil {
+		return nil, ErrNotFound
+	}
+	if err := update(value); err != nil {
+		return nil, err
+	}
+	return value, c.put(key, value)
+}
+
+// Delete removes document by key, returning false if it didn't exist.
+func (c *Collection[T]) Delete(key string) (bool, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	if _, ok := c.store.data[c.name][key]; !ok {
+		return false, nil
+	}
+	delete(c.store.data[c.name], key)
+	return true, c.store.save()
+}
+
+// List returns all documents matching filter, ordered by key. Nil filter matches everything.
+func (c *Collection[T]) List(filter func(value *T) bool) ([]*T, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	keys := make([]string, 0, len(c.store.data[c.name]))
+	for key := range c.store.data[c.name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := []*T{}
+	for _, key := range keys {
+		value, err := c.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if filter == nil || filter(value) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
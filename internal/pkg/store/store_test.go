@@ -0,0 +1,100 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDocument struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestCollection(t *testing.T) {
+	s, err := New("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := NewCollection[testDocument](s, "docs")
+
+	value, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	assert.NoError(t, c.Put("b", &testDocument{Name: "b"}))
+	assert.NoError(t, c.Put("a", &testDocument{Name: "a", Count: 1}))
+
+	value, err = c.Get("a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &testDocument{Name: "a", Count: 1}, value)
+	}
+
+	values, err := c.List(nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*testDocument{{Name: "a", Count: 1}, {Name: "b"}}, values)
+	}
+
+	values, err = c.List(func(value *testDocument) bool { return value.Count > 0 })
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*testDocument{{Name: "a", Count: 1}}, values)
+	}
+
+	value, err = c.Update("a", func(value *testDocument) error {
+		value.Count++
+		return nil
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, value.Count)
+	}
+
+	_, err = c.Update("c", func(value *testDocument) error { return nil })
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = c.Update("a", func(value *testDocument) error { return errors.New("simulated") })
+	assert.ErrorContains(t, err, "simulated")
+
+	deleted, err := c.Delete("a")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = c.Delete("a")
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "store.json")
+
+	s, err := New(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, NewCollection[testDocument](s, "docs").Put("a", &testDocument{Name: "a"}))
+
+	s, err = New(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	value, err := NewCollection[testDocument](s, "docs").Get("a")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &testDocument{Name: "a"}, value)
+	}
+}
+
+func TestNewMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err := New(path)
+	assert.ErrorContains(t, err, "failed to parse store")
+}
+
+func TestNewID(t *testing.T) {
+	a, b := NewID(), NewID()
+	assert.Len(t, a, 24)
+	assert.NotEqual(t, a, b)
+}
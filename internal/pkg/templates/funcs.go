@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// DateLayout is the default layout for formatting dates in templates.
+const DateLayout = "2 Jan 2006 15:04"
+
+// Funcs returns functions available in all templates.
+//
+//   - date formats time with optional layout: {{date .created}}, {{.created | date "2 Jan"}}
+//   - pluralize prefixes count to singular or plural noun: {{pluralize .count "recipient"}}
+//   - truncate shortens text to maximum runes with ellipsis: {{.message | truncate 80}}
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"date":      formatDate,
+		"pluralize": pluralize,
+		"truncate":  truncate,
+	}
+}
+
+// formatDate formats time with optional layout preceding it, empty for zero or nil time.
+func formatDate(args ...interface{}) (string, error) {
+	layout := DateLayout
+	if len(args) == 2 {
+		var ok bool
+		if layout, ok = args[0].(string); !ok {
+			return "", fmt.Errorf("date layout must be string, got %T", args[0])
+		}
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		return "", fmt.Errorf("date expects time and optional layout")
+	}
+
+	var t time.Time
+	switch value := args[0].(type) {
+	case time.Time:
+		t = value
+	case *time.Time:
+		if value != nil {
+			t = *value
+		}
+	case nil:
+	default:
+		return "", fmt.Errorf("date expects time, got %T", value)
+	}
+	if t.IsZero() {
+		return "", nil
+	}
+	return t.Format(layout), nil
+}
+
+// pluralize formats count followed by singular or plural noun, which defaults to singular with "s".
+func pluralize(count int, singular string, plural ...string) string {
+	noun := singular
+	if count != 1 {
+		if len(plural) > 0 {
+			noun = plural[0]
+		} else {
+			noun = singular + "s"
+		}
+	}
+	return fmt.Sprintf("%d %s", count, noun)
+}
+
+// truncate shortens s to at most length runes, ending with an ellipsis when shortened.
+func truncate(length int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	if length <= 1 {
+		return string(runes[:max(length, 0)])
+	}
+	return strings.TrimRight(string(runes[:length-1]), " ") + "…"
+}
@@ -0,0 +1,58 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	for _, test := range []struct {
+		args          []interface{}
+		expected      string
+		errorContains string
+	}{
+		{args: []interface{}{date}, expected: "5 Mar 2024 14:30"},
+		{args: []interface{}{"2006-01-02", date}, expected: "2024-03-05"},
+		{args: []interface{}{&date}, expected: "5 Mar 2024 14:30"},
+		{args: []interface{}{time.Time{}}, expected: ""},
+		{args: []interface{}{nil}, expected: ""},
+		{args: []interface{}{}, errorContains: "expects time"},
+		{args: []interface{}{1, date}, errorContains: "layout must be string"},
+		{args: []interface{}{"today"}, errorContains: "got string"},
+	} {
+		actual, err := formatDate(test.args...)
+		if test.errorContains != "" {
+			assert.ErrorContains(t, err, test.errorContains, "args: %v", test.args)
+		} else if assert.NoError(t, err) {
+			assert.Equal(t, test.expected, actual, "args: %v", test.args)
+		}
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	assert.Equal(t, "0 recipients", pluralize(0, "recipient"))
+	assert.Equal(t, "1 recipient", pluralize(1, "recipient"))
+	assert.Equal(t, "3 recipients", pluralize(3, "recipient"))
+	assert.Equal(t, "2 replies", pluralize(2, "reply", "replies"))
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		length   int
+		s        string
+		expected string
+	}{
+		{length: 10, s: "short", expected: "short"},
+		{length: 5, s: "exact", expected: "exact"},
+		{length: 8, s: "hello world", expected: "hello w…"},
+		{length: 7, s: "hello world", expected: "hello…"},
+		{length: 4, s: "こんにちは", expected: "こんに…"},
+		{length: 1, s: "hello", expected: "h"},
+		{length: 0, s: "hello", expected: ""},
+	} {
+		assert.Equal(t, test.expected, truncate(test.length, test.s), "s: %s", test.s)
+	}
+}
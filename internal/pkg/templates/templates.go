@@ -1,27 +1,88 @@
 package templates
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
+// layoutDirectiveReg matches comment at the start of a page selecting its layout,
+// such as "{{/* layout: layouts/plain.html */}}".
+var layoutDirectiveReg = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*layout:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// errorTemplate renders parse errors in place of broken templates when reloading.
+var errorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+
+<head>
+    <title>Template error</title>
+</head>
+
+<body>
+    <h1>Template error: {{.Name}}</h1>
+    <pre>{{.Error}}</pre>
+</body>
+
+</html>
+`))
+
 type Config struct {
 	Logger *zap.Logger
 
+	// FS is the filesystem containing RootPath. When nil, RootPath is a directory on disk.
+	FS fs.FS
+
 	RootPath   string
 	LayoutFile string
+
+	// LayoutsDir contains alternative layouts, relative to RootPath. Pages select one
+	// with a comment at the start, such as "{{/* layout: layouts/plain.html */}}".
+	LayoutsDir string
+
+	// PartialsDir contains templates parsed with every page, relative to RootPath.
+	PartialsDir string
+
+	// Funcs are available in all templates, in addition to [Funcs].
+	Funcs template.FuncMap
+
+	// ContextFuncs replaces functions in Funcs for each render with ones depending on request,
+	// such as translations in the user's language. Functions must be declared in Funcs.
+	ContextFuncs func(c echo.Context) template.FuncMap
+
+	// Reload re-parses templates changed since the last render and shows parse errors
+	// instead of failing, intended for development. Otherwise templates are parsed once.
+	Reload bool
 }
 
 type Templates struct {
-	config    Config
-	templates map[string]*template.Template
+	config     Config
+	fs         fs.FS
+	rootPath   string
+	layoutPath string
+	funcs      template.FuncMap
+
+	mu        sync.Mutex
+	templates map[string]*page
+	errors    map[string]error
+	modTimes  map[string]time.Time
+}
+
+// page is a parsed page template, executed through its layout.
+type page struct {
+	template *template.Template
+	layout   string
 }
 
 // NewRenderer creates new renderer and parses templates directory recursively
@@ -29,49 +90,205 @@ type Templates struct {
 func New(config Config) (*Templates, error) {
 	t := &Templates{
 		config:    config,
-		templates: map[string]*template.Template{},
+		fs:        config.FS,
+		rootPath:  path.Clean(config.RootPath),
+		funcs:     Funcs(),
+		templates: map[string]*page{},
+		errors:    map[string]error{},
+		modTimes:  map[string]time.Time{},
+	}
+	if t.fs == nil {
+		t.fs = os.DirFS(config.RootPath)
+		t.rootPath = "."
 	}
+	t.layoutPath = path.Join(t.rootPath, config.LayoutFile)
+	maps.Copy(t.funcs, config.Funcs)
 
-	if f, err := os.Stat(config.RootPath); os.IsNotExist(err) {
+	if f, err := fs.Stat(t.fs, t.rootPath); errors.Is(err, fs.ErrNotExist) {
 		return t, fmt.Errorf("root not found: %w", err)
-	} else if err == nil && !f.IsDir() {
+	} else if err != nil || !f.IsDir() {
 		return t, fmt.Errorf("root not directory")
 	}
 
-	layoutPath := config.RootPath + "/" + config.LayoutFile
-	if f, err := os.Stat(layoutPath); os.IsNotExist(err) {
+	if f, err := fs.Stat(t.fs, t.layoutPath); errors.Is(err, fs.ErrNotExist) {
 		return t, fmt.Errorf("layout not found: %w", err)
 	} else if err == nil && f.IsDir() {
 		return t, fmt.Errorf("layout is directory")
 	}
 
-	err := filepath.WalkDir(config.RootPath, func(path string, d fs.DirEntry, err error) error {
-		name := d.Name()
+	if err := t.load(); err != nil {
+		return t, err
+	}
 
-		if d.IsDir() || name == config.LayoutFile {
-			return nil
-		} else if filepath.Ext(path) != filepath.Ext(layoutPath) {
-			t.config.Logger.Info("discarding template", zap.String("path", path))
+	return t, nil
+}
+
+// load parses pages that changed since the previous load, or all of them if any layout or partial changed.
+// Parse errors are recorded for [Templates.Render] when reloading, otherwise returned.
+func (t *Templates) load() error {
+	modTimes := map[string]time.Time{}
+	pages := map[string]string{}
+	partials := []string{}
+	sharedChanged := false
+
+	err := fs.WalkDir(t.fs, t.rootPath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
 			return nil
 		}
 
-		t.templates[name] = template.Must(template.ParseFiles(layoutPath, path))
-		t.config.Logger.Info("compiled template", zap.String("name", name), zap.String("path", path))
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		modTime, known := t.modTimes[file]
+		modTimes[file] = info.ModTime()
+
+		if path.Ext(file) != path.Ext(t.layoutPath) {
+			if !known {
+				t.config.Logger.Info("discarding template", zap.String("path", file))
+			}
+			return nil
+		}
 
+		name := t.name(file)
+		switch {
+		case file == t.layoutPath, t.inDir(name, t.config.LayoutsDir):
+			sharedChanged = sharedChanged || !known || !modTime.Equal(info.ModTime())
+		case t.inDir(name, t.config.PartialsDir):
+			sharedChanged = sharedChanged || !known || !modTime.Equal(info.ModTime())
+			partials = append(partials, file)
+		default:
+			pages[name] = file
+		}
 		return nil
 	})
 	if err != nil {
-		return t, fmt.Errorf("failed to walk root: %w", err)
+		return fmt.Errorf("failed to walk root: %w", err)
 	}
 
-	return t, nil
+	// Removed layouts and partials affect pages that used them
+	for file := range t.modTimes {
+		if _, ok := modTimes[file]; !ok && file != t.layoutPath {
+			sharedChanged = true
+		}
+	}
+
+	for name, file := range pages {
+		if modTime, known := t.modTimes[file]; known && !sharedChanged && modTime.Equal(modTimes[file]) {
+			continue
+		}
+
+		page, err := t.parse(file, partials)
+		if err != nil {
+			if !t.config.Reload {
+				return fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+			delete(t.templates, name)
+			t.errors[name] = err
+			t.config.Logger.Error("failed to parse template", zap.String("path", file), zap.Error(err))
+			continue
+		}
+
+		t.templates[name] = page
+		delete(t.errors, name)
+		t.config.Logger.Info("compiled template", zap.String("name", name), zap.String("path", file))
+	}
+
+	for name := range t.templates {
+		if _, ok := pages[name]; !ok {
+			delete(t.templates, name)
+		}
+	}
+	for name := range t.errors {
+		if _, ok := pages[name]; !ok {
+			delete(t.errors, name)
+		}
+	}
+	t.modTimes = modTimes
+
+	return nil
+}
+
+// parse parses page together with its layout and partials.
+func (t *Templates) parse(file string, partials []string) (*page, error) {
+	content, err := fs.ReadFile(t.fs, file)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := t.layoutPath
+	if match := layoutDirectiveReg.FindSubmatch(content); match != nil {
+		layout = path.Join(t.rootPath, string(match[1]))
+	}
+
+	tmpl := template.New(layout).Funcs(t.funcs)
+	for _, name := range append([]string{layout}, partials...) {
+		partial, err := fs.ReadFile(t.fs, name)
+		if err != nil {
+			return nil, err
+		}
+		if name == layout {
+			_, err = tmpl.Parse(string(partial))
+		} else {
+			_, err = tmpl.New(name).Parse(string(partial))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tmpl.New(file).Parse(string(content)); err != nil {
+		return nil, err
+	}
+
+	return &page{template: tmpl, layout: layout}, nil
+}
+
+// name returns template name for file, which is its path relative to root.
+func (t *Templates) name(file string) string {
+	if t.rootPath == "." {
+		return file
+	}
+	return strings.TrimPrefix(file, t.rootPath+"/")
+}
+
+// inDir checks whether name is within dir, if it's set.
+func (t *Templates) inDir(name, dir string) bool {
+	return dir != "" && strings.HasPrefix(name, path.Clean(dir)+"/")
 }
 
 // Render implements [echo.Renderer] interface.
 func (t *Templates) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
-	tmpl, ok := t.templates[name]
+	if t.config.Reload {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if err := t.load(); err != nil {
+			return err
+		}
+		if err, ok := t.errors[name]; ok {
+			return errorTemplate.Execute(w, map[string]interface{}{
+				"Name":  name,
+				"Error": err.Error(),
+			})
+		}
+	}
+
+	page, ok := t.templates[name]
 	if !ok {
 		return fmt.Errorf("template '%s' not found", name)
 	}
-	return tmpl.Execute(w, data)
+
+	tmpl := page.template
+	if t.config.ContextFuncs != nil {
+		// Parsed templates can only be cloned before execution, so they're never executed directly
+		var err error
+		if tmpl, err = tmpl.Clone(); err != nil {
+			return err
+		}
+		tmpl.Funcs(t.config.ContextFuncs(c))
+	}
+	return tmpl.ExecuteTemplate(w, page.layout, data)
 }
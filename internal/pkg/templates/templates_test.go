@@ -1,9 +1,15 @@
 package templates
 
 import (
+	"bytes"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -12,9 +18,11 @@ import (
 
 func TestNew(t *testing.T) {
 	templates, err := New(Config{
-		Logger:     zap.Must(zap.NewDevelopment()),
-		RootPath:   "examples",
-		LayoutFile: "layout.html",
+		Logger:      zap.Must(zap.NewDevelopment()),
+		RootPath:    "examples",
+		LayoutFile:  "layout.html",
+		LayoutsDir:  "layouts",
+		PartialsDir: "partials",
 	})
 	if !assert.NoError(t, err) {
 		return
@@ -23,6 +31,7 @@ func TestNew(t *testing.T) {
 	for _, test := range []struct {
 		name     string
 		expected bool
+		contains []string
 	}{
 		{
 			name:     "README.md",
@@ -31,10 +40,29 @@ func TestNew(t *testing.T) {
 		{
 			name:     "about.html",
 			expected: true,
+			contains: []string{"About", "Footer"},
 		},
 		{
 			name:     "home.html",
 			expected: true,
+			contains: []string{"Home", "Footer"},
+		},
+		{
+			name:     "blog/home.html",
+			expected: true,
+			contains: []string{"Blog home", `class="plain"`},
+		},
+		{
+			name:     "layout.html",
+			expected: false,
+		},
+		{
+			name:     "layouts/plain.html",
+			expected: false,
+		},
+		{
+			name:     "partials/footer.html",
+			expected: false,
 		},
 	} {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -43,6 +71,9 @@ func TestNew(t *testing.T) {
 
 		if err := templates.Render(rec, test.name, map[string]interface{}{}, c); test.expected {
 			assert.NoError(t, err, "template: %s", test.name)
+			for _, s := range test.contains {
+				assert.Contains(t, rec.Body.String(), s, "template: %s", test.name)
+			}
 		} else {
 			assert.Error(t, err, "template: %s", test.name)
 		}
@@ -81,3 +112,152 @@ func TestNewChecks(t *testing.T) {
 	})
 	assert.ErrorContains(t, err, "layout is directory")
 }
+
+func TestReload(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string, modTime time.Time) {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	now := time.Now()
+	write("layout.html", `<main>{{template "content" .}}</main>`, now)
+	write("home.html", `{{define "content"}}Home{{end}}`, now)
+
+	templates, err := New(Config{
+		Logger:      zap.Must(zap.NewDevelopment()),
+		RootPath:    root,
+		LayoutFile:  "layout.html",
+		PartialsDir: "partials",
+		Reload:      true,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	var buf bytes.Buffer
+	render := func(name string) (string, error) {
+		buf.Reset()
+		err := templates.Render(&buf, name, map[string]interface{}{}, c)
+		return buf.String(), err
+	}
+
+	output, err := render("home.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<main>Home</main>", output)
+
+	write("home.html", `{{define "content"}}Welcome{{end}}`, now.Add(time.Second))
+	output, err = render("home.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<main>Welcome</main>", output)
+
+	write("layout.html", `<div>{{template "content" .}}</div>`, now.Add(time.Second))
+	write("about.html", `{{define "content"}}About{{end}}`, now)
+	output, err = render("about.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<div>About</div>", output)
+	output, err = render("home.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<div>Welcome</div>", output)
+
+	write("home.html", `{{define "content"}}{{.missing</div>{{end}}`, now.Add(2*time.Second))
+	output, err = render("home.html")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Template error: home.html")
+
+	assert.NoError(t, os.Remove(filepath.Join(root, "about.html")))
+	_, err = render("about.html")
+	assert.ErrorContains(t, err, "not found")
+
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "partials"), 0o755))
+	write("partials/greeting.html", `{{define "greeting"}}Hello{{end}}`, now)
+	write("home.html", `{{define "content"}}{{template "greeting"}}{{end}}`, now.Add(3*time.Second))
+	output, err = render("home.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<div>Hello</div>", output)
+
+	write("partials/greeting.html", `{{define "greeting"}}Hi{{end}}`, now.Add(time.Second))
+	output, err = render("home.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "<div>Hi</div>", output)
+}
+
+func TestNewParseError(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "layout.html"), []byte(`{{template "content" .}}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "home.html"), []byte(`{{define "content"}}{{end`), 0o644))
+
+	_, err := New(Config{
+		Logger:     zap.Must(zap.NewDevelopment()),
+		RootPath:   root,
+		LayoutFile: "layout.html",
+	})
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestNewFS(t *testing.T) {
+	templates, err := New(Config{
+		Logger: zap.Must(zap.NewDevelopment()),
+		FS: fstest.MapFS{
+			"templates/layout.html": {Data: []byte(`<main>{{template "content" .}}</main>`)},
+			"templates/home.html":   {Data: []byte(`{{define "content"}}Home{{end}}`)},
+		},
+		RootPath:   "templates",
+		LayoutFile: "layout.html",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var buf bytes.Buffer
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if assert.NoError(t, templates.Render(&buf, "home.html", nil, c)) {
+		assert.Equal(t, "<main>Home</main>", buf.String())
+	}
+}
+
+func TestContextFuncs(t *testing.T) {
+	templates, err := New(Config{
+		Logger: zap.Must(zap.NewDevelopment()),
+		FS: fstest.MapFS{
+			"layout.html": {Data: []byte(`{{template "content" .}}`)},
+			"home.html":   {Data: []byte(`{{define "content"}}{{greet}}{{end}}`)},
+		},
+		RootPath:   ".",
+		LayoutFile: "layout.html",
+		Funcs: template.FuncMap{
+			"greet": func() string { return "Hello" },
+		},
+		ContextFuncs: func(c echo.Context) template.FuncMap {
+			greeting := "Hello"
+			if c.Request().Header.Get("Accept-Language") == "de" {
+				greeting = "Hallo"
+			}
+			return template.FuncMap{
+				"greet": func() string { return greeting },
+			}
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, test := range []struct {
+		language string
+		expected string
+	}{
+		{language: "de", expected: "Hallo"},
+		{language: "en", expected: "Hello"},
+		{language: "de", expected: "Hallo"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", test.language)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		var buf bytes.Buffer
+		if assert.NoError(t, templates.Render(&buf, "home.html", nil, c)) {
+			assert.Equal(t, test.expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package webhook signs, verifies and delivers webhook requests.
+//
+// Requests carry a Unix timestamp header and a signature header with the HMAC-SHA256
+// of "timestamp.body" keyed by a shared secret, formatted as "sha256=<hex>".
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Headers set on delivered requests.
+const (
+	HeaderEvent     = "X-Blaster-Event"
+	HeaderDelivery  = "X-Blaster-Delivery"
+	HeaderTimestamp = "X-Blaster-Timestamp"
+	HeaderSignature = "X-Blaster-Signature"
+)
+
+// signaturePrefix identifies the signature algorithm.
+const signaturePrefix = "sha256="
+
+// DefaultBackoff is the delay before each retry of a failed delivery.
+var DefaultBackoff = []time.Duration{
+	10 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+}
+
+// Sign returns signature of body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.", timestamp)
+	h.Write(body)
+	return signaturePrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify checks signature of body against secret, rejecting timestamps further than tolerance from now.
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp")
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("timestamp outside tolerance")
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) || !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// Request is a webhook to deliver.
+type Request struct {
+	URL      string
+	Secret   string
+	Event    string
+	Delivery string
+	Body     []byte
+}
+
+// Attempt records the outcome of one delivery attempt.
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Succeeded reports whether receiver accepted the request.
+func (a *Attempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// retryable reports whether a failed attempt may succeed later.
+// Client errors other than rate limiting won't change by retrying.
+func (a *Attempt) retryable() bool {
+	return a.StatusCode == 0 || a.StatusCode == http.StatusTooManyRequests || a.StatusCode >= 500
+}
+
+// Client delivers webhooks, retrying failures with backoff.
+type Client struct {
+	HTTP    *http.Client
+	Backoff []time.Duration
+}
+
+// NewClient creates client with a request timeout and [DefaultBackoff]. It only connects to public addresses,
+// checked when connecting so that hosts can't resolve elsewhere after their URL was accepted, and doesn't follow redirects.
+func NewClient() *Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return fmt.Errorf("%s isn't a public address", host)
+			}
+			return nil
+		},
+	}
+	return &Client{
+		HTTP: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Backoff: DefaultBackoff,
+	}
+}
+
+// CheckHost resolves host, returning an error unless all its addresses are public.
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("can't resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return fmt.Errorf("%s resolves to %s, which isn't a public address", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// PublicIP reports whether ip is routable on the internet, rather than loopback, private, link-local or otherwise reserved.
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip))
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, private to providers and often used by cloud networks.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Deliver sends request until it succeeds, fails permanently or retries run out,
+// calling record after every attempt. Returns true if delivery succeeded.
+func (c *Client) Deliver(ctx context.Context, request Request, record func(Attempt)) bool {
+	for retry := 0; ; retry++ {
+		attempt := c.attempt(ctx, request)
+		record(attempt)
+		if attempt.Succeeded() {
+			return true
+		}
+		if !attempt.retryable() || retry >= len(c.Backoff) {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(c.Backoff[retry]):
+		}
+	}
+}
+
+// attempt sends request once, signed at the current time.
+func (c *Client) attempt(ctx context.Context, request Request) (attempt Attempt) {
+	start := time.Now()
+	attempt.At = start.UTC()
+	defer func() {
+		attempt.DurationMS = time.Since(start).Milliseconds()
+	}()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := start.Unix()
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("User-Agent", "Blaster-Webhook")
+	r.Header.Set(HeaderEvent, request.Event)
+	r.Header.Set(HeaderDelivery, request.Delivery)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(HeaderSignature, Sign(request.Secret, timestamp, request.Body))
+
+	response, err := c.HTTP.Do(r)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+
+	attempt.StatusCode = response.StatusCode
+	return attempt
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"blast.completed"}`)
+	now := time.Now().Unix()
+	signature := Sign("secret", now, body)
+	timestamp := strconv.FormatInt(now, 10)
+
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	assert.NoError(t, Verify("secret", signature, timestamp, body, time.Minute))
+	assert.ErrorContains(t, Verify("other", signature, timestamp, body, time.Minute), "invalid signature")
+	assert.ErrorContains(t, Verify("secret", signature, timestamp, []byte("{}"), time.Minute), "invalid signature")
+	assert.ErrorContains(t, Verify("secret", signature, "x", body, time.Minute), "invalid timestamp")
+
+	old := now - 600
+	assert.ErrorContains(t, Verify("secret", Sign("secret", old, body), strconv.FormatInt(old, 10), body, time.Minute), "outside tolerance")
+}
+
+func TestClientDeliver(t *testing.T) {
+	for _, test := range []struct {
+		statuses  []int
+		succeeded bool
+		attempts  int
+	}{
+		{statuses: []int{http.StatusOK}, succeeded: true, attempts: 1},
+		{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusNoContent}, succeeded: true, attempts: 3},
+		{statuses: []int{http.StatusGone}, succeeded: false, attempts: 1},
+		{statuses: []int{500, 500, 500, 500}, succeeded: false, attempts: 3},
+	} {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, "blast.completed", r.Header.Get(HeaderEvent))
+			assert.Equal(t, "d1", r.Header.Get(HeaderDelivery))
+			assert.NoError(t, Verify("secret", r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body, time.Minute))
+			w.WriteHeader(test.statuses[calls])
+			calls++
+		}))
+
+		client := &Client{HTTP: server.Client(), Backoff: []time.Duration{0, 0}}
+		attempts := []Attempt{}
+		succeeded := client.Deliver(context.Background(), Request{
+			URL:      server.URL,
+			Secret:   "secret",
+			Event:    "blast.completed",
+			Delivery: "d1",
+			Body:     []byte(`{}`),
+		}, func(a Attempt) {
+			attempts = append(attempts, a)
+		})
+		server.Close()
+
+		assert.Equal(t, test.succeeded, succeeded, test.statuses)
+		assert.Len(t, attempts, test.attempts, test.statuses)
+		assert.Equal(t, test.statuses[len(attempts)-1], attempts[len(attempts)-1].StatusCode)
+	}
+}
+
+func TestClientDeliverUnreachable(t *testing.T) {
+	client := &Client{HTTP: http.DefaultClient}
+	var attempt Attempt
+	assert.False(t, client.Deliver(context.Background(), Request{URL: "http://127.0.0.1:1"}, func(a Attempt) {
+		attempt = a
+	}))
+	assert.NotEmpty(t, attempt.Error)
+	assert.False(t, attempt.Succeeded())
+}
+
+func TestPublicIP(t *testing.T) {
+	for _, test := range []struct {
+		ip       string
+		expected bool
+	}{
+		{ip: "93.184.216.34", expected: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "100.100.100.200"},
+		{ip: "0.0.0.0"},
+		{ip: "::ffff:127.0.0.1"},
+	} {
+		assert.Equal(t, test.expected, PublicIP(net.ParseIP(test.ip)), test.ip)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	assert.NoError(t, CheckHost(context.Background(), "93.184.216.34"))
+	assert.ErrorContains(t, CheckHost(context.Background(), "169.254.169.254"), "isn't a public address")
+	assert.ErrorContains(t, CheckHost(context.Background(), "::1"), "isn't a public address")
+}
+
+func TestClientPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Backoff = nil
+	var attempt Attempt
+	assert.False(t, client.Deliver(context.Background(), Request{URL: server.URL}, func(a Attempt) {
+		attempt = a
+	}))
+	assert.Contains(t, attempt.Error, "isn't a public address")
+}
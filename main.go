@@ -1,17 +1,34 @@
 package main
 
 import (
+	"embed"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/gouline/blaster/internal/pkg/cli"
 	"github.com/gouline/blaster/internal/pkg/server"
 	zaplogfmt "github.com/sykesm/zap-logfmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// assets contains default static files and templates, so the binary runs from any directory.
+//
+//go:embed static templates
+var assets embed.FS
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	serve()
+}
+
+// serve starts the web server configured from environment.
+func serve() {
 	debug := os.Getenv("DEBUG") == "1"
 
 	var logger *zap.Logger
@@ -30,17 +47,44 @@ func main() {
 	}
 	defer logger.Sync()
 
+	workspaceBranding, err := readWorkspaceFile[server.Branding](os.Getenv("BRANDING_FILE"))
+	if err != nil {
+		panic(fmt.Sprintf("failed to read branding: %s", err))
+	}
+	workspacePolicies, err := readWorkspaceFile[server.DeliveryPolicy](os.Getenv("DELIVERY_POLICY_FILE"))
+	if err != nil {
+		panic(fmt.Sprintf("failed to read delivery policies: %s", err))
+	}
+
 	s, err := server.New(server.Config{
-		Logger:            logger,
-		Debug:             debug,
-		Host:              os.Getenv("HOST"),
-		Port:              os.Getenv("PORT"),
-		CertFile:          os.Getenv("CERT_FILE"),
-		KeyFile:           os.Getenv("KEY_FILE"),
-		StaticRoot:        "static",
-		TemplatesRoot:     "templates",
-		SlackClientID:     os.Getenv("SLACK_CLIENT_ID"),
-		SlackClientSecret: os.Getenv("SLACK_CLIENT_SECRET"),
+		Logger:             logger,
+		Debug:              debug,
+		Host:               os.Getenv("HOST"),
+		Port:               os.Getenv("PORT"),
+		CertFile:           os.Getenv("CERT_FILE"),
+		KeyFile:            os.Getenv("KEY_FILE"),
+		Assets:             assets,
+		StaticRoot:         os.Getenv("STATIC_ROOT"),
+		TemplatesRoot:      os.Getenv("TEMPLATES_ROOT"),
+		StorePath:          os.Getenv("STORE_FILE"),
+		SlackClientID:      os.Getenv("SLACK_CLIENT_ID"),
+		SlackClientSecret:  os.Getenv("SLACK_CLIENT_SECRET"),
+		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+		GlobalExclusions:   splitList(os.Getenv("GLOBAL_EXCLUSIONS")),
+		Branding: server.Branding{
+			AppName:      os.Getenv("BRAND_NAME"),
+			LogoURL:      os.Getenv("BRAND_LOGO_URL"),
+			PrimaryColor: os.Getenv("BRAND_PRIMARY_COLOR"),
+			FooterText:   os.Getenv("BRAND_FOOTER_TEXT"),
+			SupportURL:   os.Getenv("BRAND_SUPPORT_URL"),
+		},
+		WorkspaceBranding: workspaceBranding,
+		DeliveryPolicy: server.DeliveryPolicy{
+			Mode:         os.Getenv("DELIVERY_POLICY"),
+			WorkingHours: os.Getenv("WORKING_HOURS"),
+			WorkingDays:  splitList(os.Getenv("WORKING_DAYS")),
+		},
+		WorkspaceDeliveryPolicies: workspacePolicies,
 	})
 	if err != nil {
 		panic(fmt.Sprintf("failed to create server: %s", err))
@@ -48,3 +92,30 @@ func main() {
 	err = s.Start()
 	logger.Fatal("server stopped", zap.Error(err))
 }
+
+// readWorkspaceFile reads JSON file of overrides keyed by workspace ID, if path isn't empty.
+func readWorkspaceFile[T any](path string) (map[string]T, error) {
+	overrides := map[string]T{}
+	if path == "" {
+		return overrides, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// splitList splits comma-separated environment variable, skipping empty values.
+func splitList(s string) []string {
+	values := []string{}
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
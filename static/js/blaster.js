@@ -1,24 +1,14 @@
 var blaster = {
-    messageState: {
-        queue: [],
-        count: 0
+    maxAttempts: 3,
+
+    sendState: {
+        request: null,
+        attempts: 0
     },
 
     recipientsField: {
+        // User groups and lists are kept as tokens and sent by ID, so that the server expands their current members
         onCreateToken: function(tf, e) {
-            if (e.attrs.type === "usergroup") {
-                e.preventDefault();
-                
-                var field = $(tf);
-                blaster.getTokenfieldInput(field).val("");
-    
-                $.each(e.attrs.children, function(index, child) {
-                    field.tokenfield("createToken", child);
-                });
-    
-                return;
-            }
-            
             if (e.attrs.value === e.attrs.label) {
                 var tokens = e.attrs.value.split("|");
                 if (tokens.length == 2) {
@@ -44,6 +34,8 @@ var blaster = {
         onCreatedToken: function(tf, e) {
             if (!e.attrs.type) {
                 $(e.relatedTarget).addClass("invalid");
+            } else if (e.attrs.disabled) {
+                $(e.relatedTarget).addClass("disabled-group");
             }
 
             blaster.checkSubmitState();
@@ -54,6 +46,15 @@ var blaster = {
         },
     },
 
+    suggest: function(request, response) {
+        $.getJSON("/api/suggest", {
+            term: request.term,
+            include_disabled: $("#include-disabled-check").is(":checked")
+        }, response).fail(function() {
+            response([]);
+        });
+    },
+
     checkSubmitState: function() {
         var missingUsers = $("#recipients-field").tokenfield("getTokens").length == 0;
         var missingMessage = $("#message-field").val().length == 0;
@@ -89,55 +90,138 @@ var blaster = {
 
         blaster.setFormEnabled(false);
 
-        blaster.messageState.queue = [];
-        blaster.messageState.count = users.length;
-        for (user of users) {
-            blaster.messageState.queue.push({
-                user: user, 
-                message: message,
-                as_user: asUser
-            });
+        blaster.sendState.request = {
+            users: users,
+            message: message,
+            as_user: asUser,
+            acknowledge: $("#acknowledge-check").is(":checked")
+        };
+        var forwardReplies = $("#forward-replies-field").val().trim();
+        if (forwardReplies.length > 0) {
+            blaster.sendState.request.forward_replies = forwardReplies;
         }
-
-        blaster.setProgressEnabled(true)
-        blaster.nextMessage();
-    },
-
-    nextMessage: function() {
-        var remaining = blaster.messageState.queue.length;
-
-        blaster.setProgressValue(blaster.messageState.count - remaining, blaster.messageState.count);
-
-        if (remaining == 0) {
-            blaster.resetForm(true);
-            return;
+        if ($("#poll-check").is(":checked")) {
+            blaster.sendState.request.poll = {
+                options: $.grep($("#poll-options-field").val().split("\n"), function(option) {
+                    return option.trim().length > 0;
+                }),
+                mark_voted: $("#poll-mark-voted-check").is(":checked")
+            };
         }
+        if ($("#schedule-check").is(":checked")) {
+            blaster.sendState.request.schedule = {
+                date: $("#schedule-date-field").val(),
+                time: $("#schedule-time-field").val(),
+                default_timezone: Intl.DateTimeFormat().resolvedOptions().timeZone
+            };
+        }
+        blaster.sendState.attempts = 0;
 
-        var message = blaster.messageState.queue.pop();
+        $("#blast-link").hide();
+        $("#blast-scheduled").hide();
+        $("#blast-deferred").hide();
+        $("#blast-warnings").hide();
+        blaster.setProgressEnabled(true);
+        blaster.setProgressValue(0, users.length);
+        blaster.submitMessage();
+    },
 
+    submitMessage: function() {
         $.ajax({
             type: "POST",
             url: "/api/send",
-            data: JSON.stringify(message),
+            data: JSON.stringify(blaster.sendState.request),
             contentType: "application/json; charset=utf-8",
             dataType: "json",
             success: function(data) {
-                blaster.nextMessage();
+                var total = data.sent.length + (data.failed || []).length;
+                blaster.setProgressValue(data.sent.length, total);
+                if (data.waves) {
+                    var deferred = $.grep(data.waves, function(wave) {
+                        return wave.deferred;
+                    });
+                    if (data.sent.length == 0) {
+                        $("#progress").hide();
+                    }
+                    $(deferred.length > 0 ? "#blast-deferred" : "#blast-scheduled").show();
+                }
+                $("#blast-link").show().find("a").attr("href", "/blasts/" + encodeURIComponent(data.blast));
+                if (data.warnings) {
+                    blaster.showWarnings(data.warnings);
+                }
+
+                if (data.failed) {
+                    alert("Error sending message to " + data.failed.length + " recipients: " + blaster.formatError(data.failed[0]));
+                }
+                blaster.resetForm(true);
             },
-            error: function(data) {
-                alert("Error sending message:\n" + JSON.stringify(data, null, 2));
+            error: function(xhr) {
+                var error = blaster.parseError(xhr);
+
+                // Workspace's policy lets the sender reach recipients outside working hours with a reason
+                if (error.code == "outside_working_hours") {
+                    var reason = prompt(error.message + "\n\n" + $("#send-form").data("override-prompt"));
+                    if (reason && reason.trim().length > 0) {
+                        blaster.sendState.request.override_reason = reason;
+                        blaster.sendState.attempts = 0;
+                        blaster.submitMessage();
+                        return;
+                    }
+                    blaster.resetForm(false);
+                    return;
+                }
+
+                // Nothing was sent when the request fails, so it's safe to retry
+                blaster.sendState.attempts++;
+                if (error.retryable && blaster.sendState.attempts < blaster.maxAttempts) {
+                    var delay = parseInt(xhr.getResponseHeader("Retry-After")) || blaster.sendState.attempts;
+                    setTimeout(blaster.submitMessage, delay * 1000);
+                    return;
+                }
+
+                alert("Error sending message: " + blaster.formatError(error));
                 blaster.resetForm(false);
             }
         });
     },
 
+    // showWarnings lists user groups whose members changed since they were suggested
+    showWarnings: function(warnings) {
+        var list = $("#blast-warnings ul").empty();
+        $.each(warnings, function(i, warning) {
+            list.append($("<li>").text(warning.name + ": +" + warning.added.length + " / -" + warning.removed.length));
+        });
+        $("#blast-warnings").show();
+    },
+
+    parseError: function(xhr) {
+        return xhr.responseJSON || {
+            code: "network_error",
+            message: xhr.statusText || "Request failed",
+            retryable: xhr.status == 0
+        };
+    },
+
+    formatError: function(error) {
+        var text = error.message;
+        if (error.slack_error) {
+            text += " (" + error.slack_error + ")";
+        }
+        if (error.request_id) {
+            text += "\nRequest ID: " + error.request_id;
+        }
+        return text;
+    },
+
     resetForm: function(success) {
-        blaster.messageState.queue = [];
-        blaster.messageState.count = 0;
+        blaster.sendState.request = null;
+        blaster.sendState.attempts = 0;
 
         if (success) {
             $("#recipients-field").tokenfield('setTokens', []);
             $("#message-field").val("");
+            $("#poll-options-field").val("");
+            $("#forward-replies-field").val("");
         } else {
             blaster.setProgressEnabled(false);
         }
@@ -152,7 +236,12 @@ var blaster = {
 
     setFormEnabled: function(enabled) {
         $("#recipients-field").tokenfield(enabled ? 'enable' : 'disable');
+        $("#include-disabled-check").prop("disabled", !enabled);
         $("#message-field").prop("disabled", !enabled);
+        $("#poll-options-field").prop("disabled", !enabled);
+        $("#forward-replies-field").prop("disabled", !enabled);
+        $("#schedule-date-field").prop("disabled", !enabled);
+        $("#schedule-time-field").prop("disabled", !enabled);
         $("#submit-button").prop("disabled", !enabled);
     },
 
@@ -161,7 +250,7 @@ var blaster = {
     },
 
     setProgressValue: function(current, total) {
-        var percentage = current / total * 100;
+        var percentage = total > 0 ? current / total * 100 : 100;
         $("#progress-bar")
             .text(percentage < 100 ? current + "/" + total : "Complete!")
             .css("width", percentage + "%")
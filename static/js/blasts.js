@@ -0,0 +1,123 @@
+var blasts = {
+    pollInterval: 10000,
+
+    refreshPoll: function(results) {
+        $.ajax({
+            type: "GET",
+            url: "/api/blasts/" + encodeURIComponent(results.data("id")) + "/poll",
+            dataType: "json",
+            success: function(data) {
+                $("#poll-voted").text(data.voted + "/" + (data.voted + data.pending.length));
+                $.each(data.options, function(i, tally) {
+                    results.find(".poll-votes[data-option=" + i + "]").text(blasts.votes(results, tally.votes));
+                    results.find(".poll-bar[data-option=" + i + "]").css("width", tally.percent + "%");
+                });
+            },
+            complete: function() {
+                setTimeout(function() {
+                    blasts.refreshPoll(results);
+                }, blasts.pollInterval);
+            }
+        });
+    },
+
+    // votes formats count with the plural form of the page's language, from messages in data attributes
+    votes: function(results, count) {
+        var form = new Intl.PluralRules(document.documentElement.lang).select(count);
+        var message = results.data("votes-" + form) || results.data("votes-other");
+        return message.replace("%d", count);
+    },
+
+    remind: function(button, reason) {
+        if (reason === undefined && !confirm(button.data("confirm"))) {
+            return;
+        }
+
+        button.prop("disabled", true);
+        $.ajax({
+            type: "POST",
+            url: "/api/blasts/" + encodeURIComponent(button.data("id")) + "/remind",
+            contentType: "application/json",
+            data: JSON.stringify({override_reason: reason || ""}),
+            dataType: "json",
+            success: function() {
+                location.reload();
+            },
+            error: function(xhr) {
+                var error = blaster.parseError(xhr);
+                button.prop("disabled", false);
+
+                // Workspace's policy lets the sender remind recipients outside working hours with a reason
+                if (error.code == "outside_working_hours") {
+                    reason = prompt(error.message + "\n\n" + button.data("override-prompt"));
+                    if (reason && reason.trim().length > 0) {
+                        blasts.remind(button, reason);
+                    }
+                    return;
+                }
+                alert(button.data("error") + ": " + blaster.formatError(error));
+            }
+        });
+    },
+
+    approve: function(button) {
+        if (!confirm(button.data("confirm"))) {
+            return;
+        }
+
+        button.prop("disabled", true);
+        $.ajax({
+            type: "POST",
+            url: "/api/blasts/" + encodeURIComponent(button.data("id")) + "/approve",
+            dataType: "json",
+            success: function() {
+                location.reload();
+            },
+            error: function(xhr) {
+                alert(button.data("error") + ": " + blaster.formatError(blaster.parseError(xhr)));
+                button.prop("disabled", false);
+            }
+        });
+    },
+
+    cancel: function(button) {
+        if (!confirm(button.data("confirm"))) {
+            return;
+        }
+
+        button.prop("disabled", true);
+        $.ajax({
+            type: "DELETE",
+            url: "/api/blasts/" + encodeURIComponent(button.data("id")) + "/schedule",
+            dataType: "json",
+            success: function() {
+                location.reload();
+            },
+            error: function(xhr) {
+                alert(button.data("error") + ": " + blaster.formatError(blaster.parseError(xhr)));
+                button.prop("disabled", false);
+            }
+        });
+    }
+};
+
+$(function() {
+    $("#blast-remind").click(function() {
+        blasts.remind($(this));
+    });
+
+    $("#blast-approve").click(function() {
+        blasts.approve($(this));
+    });
+
+    $("#blast-cancel").click(function() {
+        blasts.cancel($(this));
+    });
+
+    var results = $("#poll-results");
+    if (results.length > 0) {
+        setTimeout(function() {
+            blasts.refreshPoll(results);
+        }, blasts.pollInterval);
+    }
+});
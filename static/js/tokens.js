@@ -0,0 +1,55 @@
+var tokens = {
+    create: function() {
+        var scopes = $("input[name=scope]:checked").map(function() {
+            return $(this).val();
+        }).get();
+
+        $.ajax({
+            type: "POST",
+            url: "/api/tokens",
+            data: JSON.stringify({
+                name: $("#token-name-field").val(),
+                scopes: scopes,
+                expires_in_days: parseInt($("#token-expiry-field").val())
+            }),
+            contentType: "application/json; charset=utf-8",
+            dataType: "json",
+            success: function(data) {
+                $("#token-form").hide();
+                $("#token-value").text(data.token);
+                $("#token-created").show();
+            },
+            error: function(xhr) {
+                alert("Error creating token: " + blaster.formatError(blaster.parseError(xhr)));
+            }
+        });
+    },
+
+    revoke: function(button) {
+        if (!confirm(button.data("confirm"))) {
+            return;
+        }
+
+        $.ajax({
+            type: "DELETE",
+            url: "/api/tokens/" + encodeURIComponent(button.data("id")),
+            success: function() {
+                button.closest("tr").remove();
+            },
+            error: function(xhr) {
+                alert("Error revoking token: " + blaster.formatError(blaster.parseError(xhr)));
+            }
+        });
+    }
+};
+
+$(function() {
+    $("#token-form").submit(function(e) {
+        e.preventDefault();
+        tokens.create();
+    });
+
+    $(".token-revoke").click(function() {
+        tokens.revoke($(this));
+    });
+});